@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"debug/buildinfo"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/fileutil"
+	"github.com/nao1215/gup/internal/goutil"
+)
+
+const (
+	// defaultBackupKeep is the number of backups kept per binary.
+	defaultBackupKeep = 3
+	// fileModeExecutable is the permission of binaries restored from backups.
+	fileModeExecutable os.FileMode = 0o755
+	// backupTimeLayout is the layout of the time suffix of backup file names.
+	backupTimeLayout = "20060102T150405.000000000Z"
+)
+
+// backupDirPath returns the directory of binary backups.
+// An empty string disables backups.
+var backupDirPath = config.BackupDirPath //nolint:gochecknoglobals // swapped in tests
+
+// backupStore keeps copies of binaries replaced by 'gup update' so that
+// 'gup rollback' can restore them. Backups are stored as
+// <dir>/<binary name>/<escaped version>@<time>, so that a rebuild of the same
+// version (e.g. a toolchain-only update) does not overwrite an older backup.
+// The file modification time records when the backup was taken.
+type backupStore struct {
+	dir    string
+	keep   int
+	maxAge time.Duration
+	now    func() time.Time
+}
+
+// binaryBackup is a single backup of a binary.
+type binaryBackup struct {
+	name      string
+	version   string
+	path      string
+	createdAt time.Time
+}
+
+func newBackupStore(dir string, keep int, maxAge time.Duration) *backupStore {
+	return &backupStore{dir: dir, keep: keep, maxAge: maxAge, now: time.Now}
+}
+
+// openBackupStore returns the backup store, or nil when keep or
+// the backup directory disables backups.
+func openBackupStore(keep int, maxAge time.Duration) *backupStore {
+	if keep <= 0 {
+		return nil
+	}
+	dir := backupDirPath()
+	if dir == "" {
+		return nil
+	}
+	return newBackupStore(dir, keep, maxAge)
+}
+
+// backup copies the binary at binPath into the store and prunes old backups.
+// It does nothing when binPath does not exist.
+func (s *backupStore) backup(binPath string) error {
+	if err := s.save(binPath); err != nil {
+		return err
+	}
+	return s.prune(filepath.Base(binPath))
+}
+
+// save copies the binary at binPath into the store without pruning.
+// It does nothing when binPath does not exist.
+func (s *backupStore) save(binPath string) error {
+	if !fileutil.IsFile(binPath) {
+		return nil
+	}
+	info, err := buildinfo.ReadFile(binPath)
+	if err != nil {
+		return fmt.Errorf("can't read build info of %s: %w", binPath, err)
+	}
+
+	name := filepath.Base(binPath)
+	dir := filepath.Join(s.dir, name)
+	if err := os.MkdirAll(dir, fileutil.FileModeCreatingDir); err != nil {
+		return fmt.Errorf("%s: %w", "can not make backup directory", err)
+	}
+
+	ver := info.Main.Version
+	if ver == "" {
+		ver = "(devel)"
+	}
+	now := s.now()
+	dst := filepath.Join(dir, url.PathEscape(ver)+"@"+now.UTC().Format(backupTimeLayout))
+	if err := copyFileAtomic(binPath, dst, fileutil.FileModeCreatingFile); err != nil {
+		return fmt.Errorf("can't back up %s: %w", binPath, err)
+	}
+	if err := os.Chtimes(dst, now, now); err != nil {
+		return fmt.Errorf("can't back up %s: %w", binPath, err)
+	}
+	return nil
+}
+
+// list returns the backups of the binary, newest first.
+func (s *backupStore) list(name string) ([]binaryBackup, error) {
+	dir := filepath.Join(s.dir, name)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []binaryBackup{}, nil
+		}
+		return nil, fmt.Errorf("can't read backups of %s: %w", name, err)
+	}
+
+	backups := make([]binaryBackup, 0, len(entries))
+	for _, e := range entries {
+		// Skip temporary files of interrupted backups.
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		ver, err := url.PathUnescape(backupVersion(e.Name()))
+		if err != nil {
+			continue
+		}
+		stat, err := e.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, binaryBackup{
+			name:      name,
+			version:   ver,
+			path:      filepath.Join(dir, e.Name()),
+			createdAt: stat.ModTime(),
+		})
+	}
+
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backups[i].createdAt.After(backups[j].createdAt)
+	})
+	return backups, nil
+}
+
+// backupVersion returns the escaped version part of a backup file name.
+// Backups taken before the time suffix was added are named by the version only.
+func backupVersion(fileName string) string {
+	i := strings.LastIndex(fileName, "@")
+	if i < 0 {
+		return fileName
+	}
+	if _, err := time.Parse(backupTimeLayout, fileName[i+1:]); err != nil {
+		return fileName
+	}
+	return fileName[:i]
+}
+
+// prune removes backups of the binary beyond the keep count or older than maxAge.
+func (s *backupStore) prune(name string) error {
+	backups, err := s.list(name)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for i, b := range backups {
+		tooMany := s.keep > 0 && i >= s.keep
+		tooOld := s.maxAge > 0 && s.now().Sub(b.createdAt) > s.maxAge
+		if !tooMany && !tooOld {
+			continue
+		}
+		if err := os.Remove(b.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("can't remove backup %s: %w", b.path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// restore replaces the binary at dst with the backup.
+func (s *backupStore) restore(b binaryBackup, dst string) error {
+	if err := copyFileAtomic(b.path, dst, fileModeExecutable); err != nil {
+		return fmt.Errorf("can't restore %s %s: %w", b.name, b.version, err)
+	}
+	return nil
+}
+
+// backupBeforeUpdate backs up the installed binary of pkg. Errors are returned
+// for reporting only; a failed backup does not stop the update.
+func (s *backupStore) backupBeforeUpdate(pkg goutil.Package) error {
+	goBin, err := goutil.GoBin()
+	if err != nil {
+		return fmt.Errorf("can't find installed binaries: %w", err)
+	}
+	return s.backup(filepath.Join(goBin, pkg.Name))
+}
+
+// copyFileAtomic copies src to dst through a temporary file in the destination
+// directory, so that dst is never left half written.
+func copyFileAtomic(src, dst string, perm os.FileMode) (err error) {
+	//nolint:gosec // src is a binary under $GOBIN or the backup directory.
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close() //nolint:errcheck // read-only file
+
+	out, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := out.Name()
+	defer func() {
+		if err != nil {
+			_ = out.Close()
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err = io.Copy(out, in); err != nil {
+		return err
+	}
+	if err = out.Chmod(perm); err != nil {
+		return err
+	}
+	if err = out.Sync(); err != nil {
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	return fileutil.RenameWithReplace(tmpPath, dst)
+}
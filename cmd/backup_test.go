@@ -0,0 +1,232 @@
+//nolint:paralleltest // tests mutate global function variables for stubbing
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nao1215/gup/internal/goutil"
+)
+
+func Test_backupStore_backup(t *testing.T) {
+	gobin := t.TempDir()
+	binPath := filepath.Join(gobin, "gal")
+	helper_CopyFile(t, filepath.Join("testdata", "check_success", "gal"), binPath)
+
+	store := newBackupStore(t.TempDir(), 2, 0)
+	if err := store.backup(binPath); err != nil {
+		t.Fatalf("backupStore.backup() error = %v", err)
+	}
+
+	backups, err := store.list("gal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 || backups[0].version != "v1.1.1" {
+		t.Fatalf("backupStore.list() = %+v, want one backup of v1.1.1", backups)
+	}
+
+	want, err := os.ReadFile(binPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(backups[0].path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(want, got) {
+		t.Error("backup content differs from the installed binary")
+	}
+}
+
+func Test_backupStore_backupSameVersion(t *testing.T) {
+	gobin := t.TempDir()
+	binPath := filepath.Join(gobin, "gal")
+	helper_CopyFile(t, filepath.Join("testdata", "check_success", "gal"), binPath)
+
+	// A rebuild of the same version, e.g. after a toolchain-only update, must
+	// not overwrite the earlier backup.
+	store := newBackupStore(t.TempDir(), 3, 0)
+	now := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 2 {
+		store.now = func() time.Time { return now.Add(time.Duration(i) * time.Hour) }
+		if err := store.backup(binPath); err != nil {
+			t.Fatalf("backupStore.backup() error = %v", err)
+		}
+	}
+
+	backups, err := store.list("gal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 || backups[0].version != "v1.1.1" || backups[1].version != "v1.1.1" {
+		t.Fatalf("backupStore.list() = %+v, want two backups of v1.1.1", backups)
+	}
+	if !backups[0].createdAt.After(backups[1].createdAt) {
+		t.Errorf("backupStore.list() is not sorted newest first: %+v", backups)
+	}
+}
+
+func Test_backupVersion(t *testing.T) {
+	tests := []struct {
+		fileName string
+		want     string
+	}{
+		{fileName: "v1.2.0@20260501T000000.000000000Z", want: "v1.2.0"},
+		{fileName: "%28devel%29@20260501T000000.000000000Z", want: "%28devel%29"},
+		{fileName: "v1.2.0", want: "v1.2.0"},
+		{fileName: "v1.2.0@latest", want: "v1.2.0@latest"},
+	}
+	for _, tt := range tests {
+		if got := backupVersion(tt.fileName); got != tt.want {
+			t.Errorf("backupVersion(%q) = %q, want %q", tt.fileName, got, tt.want)
+		}
+	}
+}
+
+func Test_backupStore_backupMissingBinary(t *testing.T) {
+	store := newBackupStore(t.TempDir(), 2, 0)
+	if err := store.backup(filepath.Join(t.TempDir(), "missing")); err != nil {
+		t.Fatalf("backupStore.backup() error = %v, want nil for a missing binary", err)
+	}
+	backups, err := store.list("missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 0 {
+		t.Fatalf("backupStore.list() = %+v, want empty", backups)
+	}
+}
+
+func Test_backupStore_prune(t *testing.T) {
+	now := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	helper_writeBackups := func(t *testing.T, store *backupStore) {
+		t.Helper()
+		dir := filepath.Join(store.dir, "tool")
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			t.Fatal(err)
+		}
+		for i, ver := range []string{"v1.0.0", "v1.1.0", "v1.2.0", "v1.3.0"} {
+			path := filepath.Join(dir, ver)
+			if err := os.WriteFile(path, []byte(ver), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			// v1.3.0 is the newest, taken one day after v1.2.0 and so on.
+			created := now.Add(-time.Duration(3-i) * 24 * time.Hour)
+			if err := os.Chtimes(path, created, created); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// Temporary files of interrupted backups are not backups.
+		if err := os.WriteFile(filepath.Join(dir, ".v1.4.0.tmp-1"), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		keep   int
+		maxAge time.Duration
+		want   []string
+	}{
+		{name: "by count", keep: 2, want: []string{"v1.3.0", "v1.2.0"}},
+		{name: "by age", keep: 10, maxAge: 36 * time.Hour, want: []string{"v1.3.0", "v1.2.0"}},
+		{name: "count and age", keep: 1, maxAge: 36 * time.Hour, want: []string{"v1.3.0"}},
+		{name: "nothing to prune", keep: 10, want: []string{"v1.3.0", "v1.2.0", "v1.1.0", "v1.0.0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newBackupStore(t.TempDir(), tt.keep, tt.maxAge)
+			store.now = func() time.Time { return now }
+			helper_writeBackups(t, store)
+
+			if err := store.prune("tool"); err != nil {
+				t.Fatalf("backupStore.prune() error = %v", err)
+			}
+			backups, err := store.list("tool")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, 0, len(backups))
+			for _, b := range backups {
+				got = append(got, b.version)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("versions after prune = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("versions after prune = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func Test_openBackupStore(t *testing.T) {
+	origDir := backupDirPath
+	defer func() { backupDirPath = origDir }()
+
+	dir := t.TempDir()
+	backupDirPath = func() string { return dir }
+	if store := openBackupStore(3, time.Hour); store == nil || store.dir != dir || store.keep != 3 || store.maxAge != time.Hour {
+		t.Fatalf("openBackupStore() = %+v, want store in %s", store, dir)
+	}
+	if store := openBackupStore(0, 0); store != nil {
+		t.Fatalf("openBackupStore(0) = %+v, want nil", store)
+	}
+
+	backupDirPath = func() string { return "" }
+	if store := openBackupStore(3, 0); store != nil {
+		t.Fatalf("openBackupStore() with empty dir = %+v, want nil", store)
+	}
+}
+
+func Test_updateWithChannels_backupBeforeInstall(t *testing.T) {
+	gobin := t.TempDir()
+	t.Setenv("GOBIN", gobin)
+	helper_CopyFile(t, filepath.Join("testdata", "check_success", "gal"), filepath.Join(gobin, "gal"))
+
+	origGetLatest := getLatestVer
+	origInstallLatest := installLatest
+	defer func() {
+		getLatestVer = origGetLatest
+		installLatest = origInstallLatest
+	}()
+	getLatestVer = func(string) (string, error) { return testVersionNine, nil }
+	installLatest = func(string) error { return nil }
+
+	galPkgs := func() []goutil.Package {
+		return []goutil.Package{{
+			Name:       "gal",
+			ImportPath: "github.com/nao1215/gal/cmd/gal",
+			ModulePath: "github.com/nao1215/gal",
+			Version:    &goutil.Version{Current: "v1.1.1"},
+			GoVersion:  &goutil.Version{Current: "go1.22.4", Latest: "go1.22.4"},
+		}}
+	}
+	store := newBackupStore(t.TempDir(), defaultBackupKeep, 0)
+	result, _, _ := updateWithChannels(galPkgs(), updateOptions{cpus: 1, ignoreGoUpdate: true, backups: store})
+	if result != 0 {
+		t.Fatalf("updateWithChannels() = %d, want 0", result)
+	}
+	backups, err := store.list("gal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 || backups[0].version != "v1.1.1" {
+		t.Fatalf("backups after update = %+v, want one backup of v1.1.1", backups)
+	}
+
+	// Dry run must not touch the backup directory.
+	dryRunStore := newBackupStore(t.TempDir(), defaultBackupKeep, 0)
+	if result, _, _ := updateWithChannels(galPkgs(), updateOptions{dryRun: true, cpus: 1, ignoreGoUpdate: true, backups: dryRunStore}); result != 0 {
+		t.Fatalf("updateWithChannels() dry run = %d, want 0", result)
+	}
+	if backups, _ := dryRunStore.list("gal"); len(backups) != 0 {
+		t.Fatalf("backups after dry run = %+v, want none", backups)
+	}
+}
@@ -0,0 +1,339 @@
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/fileutil"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+	"github.com/spf13/cobra"
+)
+
+// bundleConfigName is the name of gup.json in a bundle, stored next to the
+// module directories.
+const bundleConfigName = config.ConfigFileName
+
+var downloadModulesCtx = goutil.DownloadWithContext //nolint:gochecknoglobals // swapped in tests
+
+func newBundleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "Package module sources for installing without network access",
+		Long: `Package module sources for installing without network access.
+
+'gup bundle create' downloads the modules that every binary in gup.json
+needs, with their full dependency graph, into an archive in the GOPROXY
+layout. Copy the archive to a host without internet access and run
+'gup import --bundle <archive>' to install the binaries from it.`,
+		Args:              cobra.NoArgs,
+		ValidArgsFunction: cobra.NoFileCompletions,
+	}
+	cmd.AddCommand(newBundleCreateCmd())
+	return cmd
+}
+
+func newBundleCreateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "create",
+		Short:             "Create a bundle of the modules listed in gup.json",
+		Args:              cobra.NoArgs,
+		ValidArgsFunction: cobra.NoFileCompletions,
+		Run: func(cmd *cobra.Command, args []string) {
+			OsExit(bundleCreate(cmd, args))
+		},
+	}
+	cmd.Flags().StringP("output", "o", "gup-bundle.tar.gz", "specify the bundle file path to create")
+	cmd.Flags().StringP("file", "f", "", "specify gup.json file path to bundle")
+	if err := cmd.MarkFlagFilename("file", "json"); err != nil {
+		panic(err)
+	}
+	cmd.Flags().IntP("jobs", "j", runtime.NumCPU(), "Specify the number of CPU cores to use")
+	if err := cmd.RegisterFlagCompletionFunc("jobs", completeNCPUs); err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func bundleCreate(cmd *cobra.Command, _ []string) int {
+	if err := ensureGoCommandAvailable(); err != nil {
+		print.Err(err)
+		return 1
+	}
+
+	output, err := getFlagString(cmd, "output")
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	confFile, err := getFlagString(cmd, "file")
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	confFile = config.ResolveImportFilePath(confFile)
+
+	cpus, err := getFlagInt(cmd, "jobs")
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	cpus = clampJobs(cpus)
+
+	if !fileutil.IsFile(confFile) {
+		print.Err(fmt.Errorf("%s is not found", confFile))
+		return 1
+	}
+	pkgs, err := config.ReadConfFile(confFile)
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	if len(pkgs) == 0 {
+		print.Err("unable to create bundle: no package information")
+		return 1
+	}
+
+	stagingDir, err := os.MkdirTemp("", "gup-bundle-")
+	if err != nil {
+		print.Err(fmt.Errorf("can't create bundle: %w", err))
+		return 1
+	}
+	defer os.RemoveAll(stagingDir) //nolint:errcheck // best effort cleanup
+	modCacheDir := filepath.Join(stagingDir, "mod")
+
+	print.Info("download modules listed in " + confFile)
+	if result := downloadBundleModules(pkgs, modCacheDir, cpus); result != 0 {
+		return result
+	}
+
+	if err := writeBundle(output, filepath.Join(modCacheDir, "cache", "download"), confFile); err != nil {
+		print.Err(err)
+		return 1
+	}
+	print.Info("Create " + output)
+	return 0
+}
+
+// downloadBundleModules downloads the modules of pkgs into modCacheDir.
+func downloadBundleModules(pkgs []goutil.Package, modCacheDir string, cpus int) int {
+	result := 0
+	countFmt := "[%" + pkgDigit(pkgs) + "d/%" + pkgDigit(pkgs) + "d]"
+	ctx, cancel, signals := newSignalCancelContext()
+	defer stopSignalCancelContext(cancel, signals)
+
+	downloader := func(ctx context.Context, p goutil.Package) updateResult {
+		ver, err := versionFromConfig(p)
+		if err != nil {
+			return updateResult{pkg: p, err: fmt.Errorf("%s: %w", p.Name, err)}
+		}
+		if p.ImportPath == "" {
+			return updateResult{pkg: p, err: fmt.Errorf("%s: import path is empty", p.Name)}
+		}
+		if p.Version == nil {
+			p.Version = &goutil.Version{}
+		}
+		p.Version.Current = ver
+
+		build := p.Build
+		build.Toolchain = p.Toolchain
+		ctx = goutil.WithBuildSettings(ctx, build)
+		if err := downloadModulesCtx(ctx, p.ImportPath, ver, modCacheDir); err != nil {
+			return updateResult{pkg: p, err: fmt.Errorf("%s: %w", p.Name, err)}
+		}
+		return updateResult{updated: true, pkg: p}
+	}
+
+	ch := forEachPackage(ctx, pkgs, cpus, downloader)
+	for i := 0; i < len(pkgs); i++ {
+		v := <-ch
+		if v.err == nil {
+			print.Info(fmt.Sprintf(countFmt+" %s@%s", i+1, len(pkgs), v.pkg.ImportPath, v.pkg.Version.Current))
+		} else {
+			result = 1
+			print.Err(fmt.Errorf(countFmt+" %s", i+1, len(pkgs), v.err.Error()))
+		}
+	}
+	return result
+}
+
+// writeBundle writes a gzip-compressed tar archive to path. The archive holds
+// confFile as gup.json and the files under proxyDir, a directory in the
+// GOPROXY layout. The checksum database cache in proxyDir is skipped.
+func writeBundle(path, proxyDir, confFile string) (err error) {
+	dir := filepath.Dir(filepath.Clean(path))
+	file, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("can't create %s: %w", path, err)
+	}
+	tmpPath := file.Name()
+	defer func() {
+		if file != nil {
+			_ = file.Close()
+		}
+		if err != nil {
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	if err = addFileToBundle(tw, confFile, bundleConfigName); err != nil {
+		return fmt.Errorf("can't create %s: %w", path, err)
+	}
+	err = filepath.WalkDir(proxyDir, func(p string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		rel, err := filepath.Rel(proxyDir, p)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if rel == "sumdb" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		return addFileToBundle(tw, p, filepath.ToSlash(rel))
+	})
+	if err != nil {
+		return fmt.Errorf("can't create %s: %w", path, err)
+	}
+	if err = tw.Close(); err != nil {
+		return fmt.Errorf("can't create %s: %w", path, err)
+	}
+	if err = gz.Close(); err != nil {
+		return fmt.Errorf("can't create %s: %w", path, err)
+	}
+	if err = file.Close(); err != nil {
+		file = nil
+		return fmt.Errorf("can't create %s: %w", path, err)
+	}
+	file = nil
+
+	if err = fileutil.RenameWithReplace(tmpPath, path); err != nil {
+		return fmt.Errorf("can't create %s: %w", path, err)
+	}
+	return nil
+}
+
+func addFileToBundle(tw *tar.Writer, src, name string) error {
+	//nolint:gosec // src is gup.json or a file in the temporary module cache.
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close() //nolint:errcheck // read-only file
+
+	stat, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    int64(fileutil.FileModeCreatingFile),
+		Size:    stat.Size(),
+		ModTime: stat.ModTime(),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, in)
+	return err
+}
+
+// extractBundle extracts the bundle at path into a new temporary directory
+// and returns the directory. Entries that would escape the directory are rejected.
+func extractBundle(path string) (dir string, err error) {
+	//nolint:gosec // path is the bundle specified by the user.
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("can't open bundle: %w", err)
+	}
+	defer file.Close() //nolint:errcheck // read-only file
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return "", fmt.Errorf("can't read bundle %s: %w", path, err)
+	}
+	defer gz.Close() //nolint:errcheck // read-only stream
+
+	dir, err = os.MkdirTemp("", "gup-bundle-")
+	if err != nil {
+		return "", fmt.Errorf("can't extract bundle %s: %w", path, err)
+	}
+	defer func() {
+		if err != nil {
+			_ = os.RemoveAll(dir)
+		}
+	}()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("can't read bundle %s: %w", path, err)
+		}
+		if hdr.Typeflag == tar.TypeDir {
+			continue
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return "", fmt.Errorf("can't extract bundle %s: unsupported entry %s", path, hdr.Name)
+		}
+		name := filepath.FromSlash(hdr.Name)
+		if !filepath.IsLocal(name) {
+			return "", fmt.Errorf("can't extract bundle %s: invalid entry %s", path, hdr.Name)
+		}
+		if err := extractBundleFile(tr, filepath.Join(dir, name)); err != nil {
+			return "", fmt.Errorf("can't extract bundle %s: %w", path, err)
+		}
+	}
+	return dir, nil
+}
+
+func extractBundleFile(r io.Reader, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), fileutil.FileModeCreatingDir); err != nil {
+		return err
+	}
+	//nolint:gosec // dst is checked to stay in the extraction directory.
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, fileutil.FileModeCreatingFile)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil { //nolint:gosec // the bundle is created by 'gup bundle create'.
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// useBundle extracts the bundle and makes the go command install modules only
+// from it. It returns the extraction directory, which holds the bundled gup.json.
+func useBundle(path string) (string, error) {
+	dir, err := extractBundle(path)
+	if err != nil {
+		return "", err
+	}
+	if err := goutil.UseModuleProxyDir(dir); err != nil {
+		_ = os.RemoveAll(dir)
+		return "", fmt.Errorf("can't use bundle %s: %w", path, err)
+	}
+	return dir, nil
+}
@@ -0,0 +1,154 @@
+//nolint:paralleltest
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/fileutil"
+	"github.com/nao1215/gup/internal/goutil"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_writeBundle_extractBundle(t *testing.T) {
+	src := t.TempDir()
+	proxyDir := filepath.Join(src, "download")
+	writeTestFile(t, filepath.Join(proxyDir, "example.com", "tool", "@v", "list"), "v1.0.0\n")
+	writeTestFile(t, filepath.Join(proxyDir, "example.com", "tool", "@v", "v1.0.0.zip"), "zip")
+	writeTestFile(t, filepath.Join(proxyDir, "sumdb", "sum.golang.org", "lookup", "example.com", "tool@v1.0.0"), "sum")
+	confFile := filepath.Join(src, "gup.json")
+	writeTestFile(t, confFile, `{"schema_version": 1, "packages": []}`)
+
+	bundle := filepath.Join(t.TempDir(), "tools.tar.gz")
+	if err := writeBundle(bundle, proxyDir, confFile); err != nil {
+		t.Fatalf("writeBundle() error = %v", err)
+	}
+
+	dir, err := extractBundle(bundle)
+	if err != nil {
+		t.Fatalf("extractBundle() error = %v", err)
+	}
+	defer os.RemoveAll(dir) //nolint:errcheck // test cleanup
+
+	for _, name := range []string{"gup.json", "example.com/tool/@v/list", "example.com/tool/@v/v1.0.0.zip"} {
+		if !fileutil.IsFile(filepath.Join(dir, filepath.FromSlash(name))) {
+			t.Errorf("%s is not extracted", name)
+		}
+	}
+	if fileutil.IsDir(filepath.Join(dir, "sumdb")) {
+		t.Error("the checksum database cache should not be bundled")
+	}
+}
+
+func Test_extractBundle_rejectsEscapingEntry(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	content := []byte("evil")
+	if err := tw.WriteHeader(&tar.Header{Name: "../evil", Mode: 0o600, Size: int64(len(content))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	bundle := filepath.Join(t.TempDir(), "evil.tar.gz")
+	writeTestFile(t, bundle, buf.String())
+
+	if _, err := extractBundle(bundle); err == nil || !strings.Contains(err.Error(), "invalid entry ../evil") {
+		t.Errorf("extractBundle() error = %v, want invalid entry error", err)
+	}
+}
+
+func Test_bundleCreate_importBundle(t *testing.T) {
+	// useBundle changes the go command environment of the process.
+	t.Setenv("GOPROXY", os.Getenv("GOPROXY"))
+	t.Setenv("GOSUMDB", os.Getenv("GOSUMDB"))
+
+	origDownload := downloadModulesCtx
+	origInstall := installByVersionCtx
+	t.Cleanup(func() {
+		downloadModulesCtx = origDownload
+		installByVersionCtx = origInstall
+	})
+
+	downloaded := map[string]goutil.BuildSettings{}
+	downloadModulesCtx = func(ctx context.Context, importPath, version, modCacheDir string) error {
+		downloaded[importPath+"@"+version] = goutil.BuildSettingsFromContext(ctx)
+		writeTestFile(t, filepath.Join(modCacheDir, "cache", "download", importPath, "@v", version+".zip"), "zip")
+		return nil
+	}
+
+	dir := t.TempDir()
+	confFile := filepath.Join(dir, "gup.json")
+	pkgs := []goutil.Package{
+		{Name: "gal", ImportPath: "github.com/nao1215/gal/cmd/gal", Version: &goutil.Version{Current: "v1.1.1"}},
+		{Name: "sqly", ImportPath: "github.com/nao1215/sqly", Version: &goutil.Version{Current: "v0.12.0"},
+			Build: goutil.BuildSettings{Tags: []string{"netgo"}}, Toolchain: "go1.22.4"},
+	}
+	var conf bytes.Buffer
+	if err := config.WriteConfFile(&conf, pkgs); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, confFile, conf.String())
+
+	bundle := filepath.Join(dir, "tools.tar.gz")
+	if _, err := helper_runGup(t, []string{"gup", "bundle", "create", "-j", "1", "-o", bundle, "-f", confFile}); err != nil {
+		t.Fatal(err)
+	}
+	if !fileutil.IsFile(bundle) {
+		t.Fatalf("%s is not created", bundle)
+	}
+	wantDownloaded := map[string]goutil.BuildSettings{
+		"github.com/nao1215/gal/cmd/gal@v1.1.1": {},
+		"github.com/nao1215/sqly@v0.12.0":       {Tags: []string{"netgo"}, Toolchain: "go1.22.4"},
+	}
+	if diff := cmp.Diff(wantDownloaded, downloaded); diff != "" {
+		t.Errorf("downloaded modules mismatch (-want +got):\n%s", diff)
+	}
+
+	installed := map[string]string{}
+	proxies := []string{}
+	installByVersionCtx = func(_ context.Context, importPath, version string) error {
+		installed[importPath] = version
+		proxies = append(proxies, os.Getenv("GOPROXY"))
+		return nil
+	}
+	if _, err := helper_runGup(t, []string{"gup", "import", "-j", "1", "--bundle", bundle}); err != nil {
+		t.Fatal(err)
+	}
+
+	if installed["github.com/nao1215/gal/cmd/gal"] != "v1.1.1" || installed["github.com/nao1215/sqly"] != "v0.12.0" {
+		t.Errorf("installed = %v, want the versions in the bundled gup.json", installed)
+	}
+	for _, proxy := range proxies {
+		if !strings.HasPrefix(proxy, "file://") {
+			t.Errorf("GOPROXY = %q, want the extracted bundle", proxy)
+		}
+	}
+	if got := os.Getenv("GOSUMDB"); got != "off" {
+		t.Errorf("GOSUMDB = %q, want off", got)
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 
+	"github.com/nao1215/gup/internal/config"
 	"github.com/nao1215/gup/internal/goutil"
 	"github.com/nao1215/gup/internal/print"
 	"github.com/spf13/cobra"
@@ -21,7 +23,15 @@ func newCheckCmd() *cobra.Command {
 check subcommand checks if the binary is the latest version
 and if it has been built with the current version of go installed,
 and displays the name of the binary that needs to be updated.
-However, do not update`,
+However, do not update.
+
+With --vuln, check also reports the known vulnerabilities of each binary
+and whether 'gup update' fixes them. --fail-on makes check exit with a
+non-zero status when a binary has a vulnerability that 'gup update' fixes
+(vuln), when a binary is outdated (outdated), or both (any).
+
+With --major, check also looks up new major versions of each binary
+(e.g. /v2 to /v3). Binaries with allow_major in gup.json are always looked up.`,
 		ValidArgsFunction: completePathBinaries,
 		Run: func(cmd *cobra.Command, args []string) {
 			OsExit(check(cmd, args))
@@ -33,11 +43,72 @@ However, do not update`,
 		panic(err)
 	}
 	cmd.Flags().Bool("ignore-go-update", false, "Ignore updates to the Go toolchain")
+	cmd.Flags().Bool("major", false, "Look up new major versions of each binary (always done for allow_major in gup.json)")
+	addUpdatePolicyFlag(cmd)
+	addCooldownFlag(cmd)
+	addLatestVerCacheFlags(cmd)
+	addOfflineFlag(cmd)
+	addOutputFlag(cmd)
+	cmd.Flags().Bool("vuln", false, "Report known vulnerabilities of each binary (implied by --fail-on vuln|any)")
+	addVulnDBFlag(cmd)
+	cmd.Flags().String("fail-on", "", "exit with status 1 when a binary has a vulnerability that update fixes ('vuln'), is outdated ('outdated'), or either ('any')")
+	if err := cmd.RegisterFlagCompletionFunc("fail-on", completeFailOn); err != nil {
+		panic(err)
+	}
 
 	return cmd
 }
 
+// failOn selects the findings that make 'gup check' exit with status 1.
+// Errors always do.
+type failOn string
+
+const (
+	failOnNone     failOn = ""
+	failOnVuln     failOn = "vuln"
+	failOnOutdated failOn = "outdated"
+	failOnAny      failOn = "any"
+)
+
+// vuln reports whether a vulnerability that 'gup update' fixes is a failure.
+func (f failOn) vuln() bool {
+	return f == failOnVuln || f == failOnAny
+}
+
+// outdated reports whether an outdated binary is a failure.
+func (f failOn) outdated() bool {
+	return f == failOnOutdated || f == failOnAny
+}
+
+// getFlagFailOn returns the --fail-on value.
+func getFlagFailOn(cmd *cobra.Command) (failOn, error) {
+	raw, err := getFlagString(cmd, "fail-on")
+	if err != nil {
+		return failOnNone, err
+	}
+	switch f := failOn(strings.ToLower(strings.TrimSpace(raw))); f {
+	case failOnNone, failOnVuln, failOnOutdated, failOnAny:
+		return f, nil
+	default:
+		return failOnNone, fmt.Errorf("can not parse command line argument (--fail-on): want %s, %s or %s: %s",
+			failOnVuln, failOnOutdated, failOnAny, raw)
+	}
+}
+
+func completeFailOn(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	return []string{string(failOnVuln), string(failOnOutdated), string(failOnAny)}, cobra.ShellCompDirectiveNoFileComp
+}
+
 func check(cmd *cobra.Command, args []string) int {
+	report, err := openReportWriter(cmd, "check")
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	return report.close(runCheck(cmd, args, report))
+}
+
+func runCheck(cmd *cobra.Command, args []string, report *reportWriter) int {
 	if err := ensureGoCommandAvailable(); err != nil {
 		print.Err(err)
 		return 1
@@ -56,6 +127,44 @@ func check(cmd *cobra.Command, args []string) int {
 		return 1
 	}
 
+	policy, err := getFlagUpdatePolicy(cmd)
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	cooldownDays, err := getFlagCooldownDays(cmd)
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+
+	probeMajor, err := getFlagBool(cmd, "major")
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+
+	fail, err := getFlagFailOn(cmd)
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	scanVulns, err := getFlagBool(cmd, "vuln")
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+
+	verCache, err := latestVerCacheFromFlags(cmd)
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	if err := setupOfflineMode(cmd, verCache); err != nil {
+		print.Err(err)
+		return 1
+	}
+
 	pkgs, err := getPackageInfoByTargets(args)
 	if err != nil {
 		print.Err(err)
@@ -67,28 +176,112 @@ func check(cmd *cobra.Command, args []string) int {
 		print.Err("unable to check package: no package information")
 		return 1
 	}
+
+	confPath := config.ResolveImportFilePath("")
+	confPkgs, err := readConfFileIfExists(confPath)
+	if err != nil {
+		print.Warn(fmt.Sprintf("failed to read %s: %s (continuing without config)", confPath, err))
+		confPkgs = []goutil.Package{}
+	}
+	// An unreadable file has been reported above.
+	settings, err := readConfSettingsIfExists(confPath)
+	if err != nil {
+		settings = config.Settings{}
+	}
+	channelMap, err := resolveUpdateChannels(pkgs, confPkgs, nil, nil, nil, nil, nil)
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	warnIgnoredVersionRules(confPkgs, channelMap)
+
+	pkgs = applyToolchains(pkgs, confPkgs)
 	ctx, cancel, signals := newSignalCancelContext()
 	defer stopSignalCancelContext(cancel, signals)
-	return doCheck(ctx, pkgs, cpus, ignoreGoUpdate)
+
+	var vulns *vulnScanner
+	if scanVulns || fail.vuln() {
+		db, err := newVulnDBFromFlags(cmd)
+		if err != nil {
+			print.Err(err)
+			return 1
+		}
+		if vulns, err = newVulnScanner(ctx, db); err != nil {
+			print.Err(err)
+			return 1
+		}
+	}
+
+	return doCheck(ctx, pkgs, checkOptions{
+		cpus:           cpus,
+		ignoreGoUpdate: ignoreGoUpdate,
+		channelMap:     channelMap,
+		rules:          resolveVersionRules(pkgs, confPkgs, policy, cooldownOption{flag: cooldownDays, global: settings.CooldownDays}, probeMajor),
+		verCache:       verCache,
+		report:         report,
+		vulns:          vulns,
+		failOn:         fail,
+	})
+}
+
+// checkOptions holds the settings of a 'gup check' run.
+type checkOptions struct {
+	cpus           int
+	ignoreGoUpdate bool
+	// channelMap maps binary names to their update channel.
+	channelMap map[string]goutil.UpdateChannel
+	// rules maps binary names to the rules that restrict their target version.
+	rules map[string]versionRule
+	// verCache looks up latest versions. When nil, an in-memory cache is used.
+	verCache *latestVerCache
+	// report receives the result of each package. When nil, nothing is reported.
+	report *reportWriter
+	// vulns annotates each package with its known vulnerabilities. When nil, they are not scanned.
+	vulns  *vulnScanner
+	failOn failOn
 }
 
-func doCheck(ctx context.Context, pkgs []goutil.Package, cpus int, ignoreGoUpdate bool) int {
+func doCheck(ctx context.Context, pkgs []goutil.Package, opts checkOptions) int {
+	cpus, ignoreGoUpdate, verCache := opts.cpus, opts.ignoreGoUpdate, opts.verCache
+	if verCache == nil {
+		verCache = newLatestVerCache()
+	}
 	result := 0
 	countFmt := "[%" + pkgDigit(pkgs) + "d/%" + pkgDigit(pkgs) + "d]"
 	var mu sync.Mutex
 	needUpdatePkgs := []goutil.Package{}
-	verCache := newLatestVerCache()
 
 	print.Info("check binary under $GOPATH/bin or $GOBIN")
 
 	checker := func(ctx context.Context, p goutil.Package) updateResult {
+		installed := p
+		var findings []vulnFinding
+		var vulnErr error
+		if opts.vulns != nil {
+			findings, vulnErr = opts.vulns.scan(ctx, installed)
+		}
+
 		var err error
-		if p.ModulePath == "" {
+		var target targetVersion
+		var major majorVersion
+		outdated := false
+		modulePathChanged := false
+		name := p.Name
+		if pin := opts.rules[name].pin; pin != "" {
+			p.Pin = pin
+			p.Version.Latest = pin
+			if p.Version.Current != pin {
+				outdated = true
+				mu.Lock()
+				needUpdatePkgs = append(needUpdatePkgs, p)
+				mu.Unlock()
+			}
+		} else if p.ModulePath == "" {
 			err = fmt.Errorf(" %s is not installed by 'go install' (or permission incorrect)", p.Name)
 		} else {
 			var latestVer string
-			modulePathChanged := false
-			latestVer, err = verCache.get(ctx, p.ModulePath)
+			channel := packageUpdateChannel(name, p.UpdateChannel, opts.channelMap)
+			latestVer, err = verCache.channelVersion(ctx, p.ModulePath, channel)
 			if err != nil {
 				newPkg, changed := resolveModulePathChange(p, err)
 				if !changed {
@@ -96,7 +289,7 @@ func doCheck(ctx context.Context, pkgs []goutil.Package, cpus int, ignoreGoUpdat
 				} else {
 					modulePathChanged = true
 					p = newPkg
-					latestVer, err = verCache.get(ctx, p.ModulePath)
+					latestVer, err = verCache.channelVersion(ctx, p.ModulePath, channel)
 					if err != nil {
 						err = fmt.Errorf(" %s %w", p.Name, err)
 					}
@@ -104,40 +297,126 @@ func doCheck(ctx context.Context, pkgs []goutil.Package, cpus int, ignoreGoUpdat
 			}
 			if err == nil {
 				p.Version.Latest = latestVer
+				p.UpdateChannel = channel
 
+				target, err = resolveTargetVersion(ctx, p, channel, opts.rules[name], latestVer, verCache)
+				if err != nil {
+					err = fmt.Errorf(" %s %w", p.Name, err)
+				} else if target.version != "" {
+					p.Version.Latest = target.version
+				}
+			}
+			if err == nil {
 				shouldUpdate := modulePathChanged || !p.IsPackageUpToDate() || (!ignoreGoUpdate && !p.IsGoUpToDate())
 				if shouldUpdate {
+					outdated = true
 					mu.Lock()
 					needUpdatePkgs = append(needUpdatePkgs, p)
 					mu.Unlock()
 				}
+				// Probing every binary for a new major version costs a lookup
+				// per binary, so it is only done when asked (--major, allow_major).
+				if rule := opts.rules[name]; rule.allowMajor && canSwitchMajor(channel, rule) {
+					major, _ = findNewMajor(ctx, p.ModulePath, verCache)
+				}
 			}
 		}
 
+		if vulnErr != nil && err == nil {
+			err = fmt.Errorf(" %s %w", p.Name, vulnErr)
+		}
+		if len(findings) != 0 {
+			setVulnFixStatuses(ctx, findings, installed, resolvedUpdate{
+				pkg:               p,
+				target:            target,
+				shouldUpdate:      outdated,
+				modulePathChanged: modulePathChanged,
+				err:               err,
+			})
+		}
+
 		return updateResult{
-			pkg: p,
-			err: err,
+			pkg:      p,
+			err:      err,
+			heldBack: target.heldBack,
+			newMajor: major,
+			outdated: outdated,
+			vulns:    findings,
 		}
 	}
 
 	ch := forEachPackage(ctx, pkgs, cpus, checker)
 
 	// print result
+	heldBackPkgs := []heldBackPkg{}
+	newMajorPkgs := []newMajorPkg{}
+	outdatedCount := 0
+	vulnFixablePkgs := []string{}
 	for i := 0; i < len(pkgs); i++ {
 		v := <-ch
+		if v.outdated {
+			outdatedCount++
+		}
+		if hasVulnFixedByUpdate(v.vulns) {
+			vulnFixablePkgs = append(vulnFixablePkgs, v.pkg.Name)
+		}
+		if v.heldBack != "" {
+			heldBackPkgs = append(heldBackPkgs, heldBackPkg{name: v.pkg.Name, heldBack: v.heldBack, target: v.pkg.Version.Latest})
+		}
+		if v.newMajor.modulePath != "" {
+			newMajorPkgs = append(newMajorPkgs, newMajorPkg{name: v.pkg.Name, major: v.newMajor})
+		}
+		if v.outdated {
+			opts.report.add(reportFromResult(v, statusOutdated))
+		} else {
+			opts.report.add(reportFromResult(v, statusUpToDate))
+		}
 		if v.err == nil {
+			status := v.pkg.VersionCheckResultStr()
+			if v.pkg.Pin != "" {
+				status = pinnedStatusStr(v.pkg, false)
+			}
 			print.Info(fmt.Sprintf(countFmt+" %s (%s)",
-				i+1, len(pkgs), v.pkg.ImportPath, v.pkg.VersionCheckResultStr()))
+				i+1, len(pkgs), v.pkg.ImportPath, status))
+		} else if isUnknownOffline(v.err) {
+			print.Info(fmt.Sprintf(countFmt+" %s (%s)",
+				i+1, len(pkgs), v.pkg.ImportPath, unknownOfflineStatusStr(v.pkg)))
 		} else {
 			result = 1
 			print.Err(fmt.Errorf(countFmt+"%s", i+1, len(pkgs), v.err.Error()))
 		}
+		for _, f := range v.vulns {
+			print.Info(fmt.Sprintf("%s [%s] %s", strings.Repeat(" ", len(fmt.Sprintf(countFmt, i+1, len(pkgs)))), f.id, f))
+		}
 	}
 
+	printHeldBackPkgs(heldBackPkgs)
+	printNewMajorPkgs(newMajorPkgs)
 	printUpdatablePkgInfo(needUpdatePkgs)
+
+	if opts.failOn.vuln() && len(vulnFixablePkgs) != 0 {
+		result = 1
+		sort.Strings(vulnFixablePkgs)
+		print.Err(fmt.Sprintf("%d binaries have vulnerabilities that 'gup update' fixes: %s",
+			len(vulnFixablePkgs), strings.Join(vulnFixablePkgs, ", ")))
+	}
+	if opts.failOn.outdated() && outdatedCount != 0 {
+		result = 1
+		print.Err(fmt.Sprintf("%d binaries are outdated", outdatedCount))
+	}
 	return result
 }
 
+// hasVulnFixedByUpdate reports whether 'gup update' fixes one of findings.
+func hasVulnFixedByUpdate(findings []vulnFinding) bool {
+	for _, f := range findings {
+		if f.fix == vulnFixedByUpdate {
+			return true
+		}
+	}
+	return false
+}
+
 func printUpdatablePkgInfo(pkgs []goutil.Package) {
 	if len(pkgs) == 0 {
 		return
@@ -150,7 +429,7 @@ func printUpdatablePkgInfo(pkgs []goutil.Package) {
 	}
 
 	const indentSpaces = 11
-	fmt.Println("")
+	print.Info("")
 	print.Info("If you want to update binaries, run the following command.\n" +
 		strings.Repeat(" ", indentSpaces) +
 		"$ gup update " + b.String())
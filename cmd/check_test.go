@@ -4,11 +4,13 @@ package cmd
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"io"
 	"os"
 	"path/filepath"
 	"runtime"
+	"runtime/debug"
 	"strings"
 	"testing"
 
@@ -443,7 +445,7 @@ func Test_doCheck_modulePathChanged(t *testing.T) {
 			},
 		},
 	}
-	got := doCheck(context.Background(), pkgs, 1, true)
+	got := doCheck(context.Background(), pkgs, checkOptions{cpus: 1, ignoreGoUpdate: true})
 
 	pw.Close()
 	print.Stdout = orgStdout
@@ -493,7 +495,7 @@ func Test_doCheck_customGoBuildTag_noFalsePositiveUpdate(t *testing.T) {
 			},
 		},
 	}
-	got := doCheck(context.Background(), pkgs, 1, false)
+	got := doCheck(context.Background(), pkgs, checkOptions{cpus: 1})
 
 	if err := pw.Close(); err != nil {
 		t.Fatal(err)
@@ -553,7 +555,7 @@ func Test_doCheck_customGoBuildTag_goVersionDiffColor(t *testing.T) {
 		},
 	}
 
-	got := doCheck(context.Background(), pkgs, 1, false)
+	got := doCheck(context.Background(), pkgs, checkOptions{cpus: 1})
 	if err := pw.Close(); err != nil {
 		t.Fatal(err)
 	}
@@ -576,3 +578,160 @@ func Test_doCheck_customGoBuildTag_goVersionDiffColor(t *testing.T) {
 		t.Fatalf("expected latest go version in green, got:\n%s", buf.String())
 	}
 }
+
+func Test_doCheck_refChannel(t *testing.T) {
+	const develop = "v1.0.1-0.20260304050607-4f2c1a9b8e7d"
+	origGetLatest := getLatestVer
+	origResolveRef := resolveRefCtx
+	defer func() {
+		getLatestVer = origGetLatest
+		resolveRefCtx = origResolveRef
+	}()
+	getLatestVer = func(string) (string, error) { return "v9.9.9", nil }
+	resolveRefCtx = func(_ context.Context, _, ref string) (string, error) {
+		if ref != "develop" {
+			return "", errors.New("unexpected ref " + ref)
+		}
+		return develop, nil
+	}
+
+	orgStdout := print.Stdout
+	orgStderr := print.Stderr
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	print.Stdout = pw
+	print.Stderr = pw
+
+	pkgs := []goutil.Package{
+		{
+			Name:       "gal",
+			ImportPath: "github.com/nao1215/gal/cmd/gal",
+			ModulePath: "github.com/nao1215/gal",
+			Version:    &goutil.Version{Current: develop},
+			GoVersion:  &goutil.Version{Current: "go1.22.4", Latest: "go1.22.4"},
+		},
+		{
+			Name:       "subaru",
+			ImportPath: "github.com/nao1215/subaru",
+			ModulePath: "github.com/nao1215/subaru",
+			Version:    &goutil.Version{Current: "v1.1.0"},
+			GoVersion:  &goutil.Version{Current: "go1.22.4", Latest: "go1.22.4"},
+		},
+	}
+	got := doCheck(context.Background(), pkgs, checkOptions{
+		cpus:           1,
+		ignoreGoUpdate: true,
+		channelMap: map[string]goutil.UpdateChannel{
+			"gal":    goutil.RefUpdateChannel("develop"),
+			"subaru": goutil.RefUpdateChannel("develop"),
+		},
+	})
+
+	pw.Close()
+	print.Stdout = orgStdout
+	print.Stderr = orgStderr
+
+	buf := bytes.Buffer{}
+	if _, err := io.Copy(&buf, pr); err != nil {
+		t.Fatal(err)
+	}
+	_ = pr.Close()
+
+	if got != 0 {
+		t.Fatalf("doCheck() = %v, want 0", got)
+	}
+	// subaru is newer than the ref, but it is not at the ref's commit.
+	if !strings.Contains(buf.String(), "$ gup update subaru ") || strings.Contains(buf.String(), "gal subaru") {
+		t.Fatalf("expected update hint for subaru only, got:\n%s", buf.String())
+	}
+}
+
+func Test_doCheck_failOn(t *testing.T) {
+	stubVulnUpdate(t)
+	net := &debug.Module{Path: "golang.org/x/net", Version: "v0.15.0"}
+
+	tests := []struct {
+		name   string
+		pkg    goutil.Package
+		failOn failOn
+		want   int
+	}{
+		{name: "unfixed vulnerability is not a failure", pkg: newVulnTestPackage("tool", "go1.22.5", net), failOn: failOnAny, want: 0},
+		{name: "outdated without vulnerability", pkg: newVulnTestPackage("fixed", "go1.22.5"), failOn: failOnVuln, want: 0},
+		{name: "outdated fails on outdated", pkg: newVulnTestPackage("fixed", "go1.22.5"), failOn: failOnOutdated, want: 1},
+		{name: "outdated fails on any", pkg: newVulnTestPackage("fixed", "go1.22.5"), failOn: failOnAny, want: 1},
+		{name: "vulnerability fixed by update", pkg: newVulnTestPackage("fixed", "go1.22.5", net), failOn: failOnVuln, want: 1},
+		{name: "not set", pkg: newVulnTestPackage("fixed", "go1.22.5", net), failOn: failOnNone, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner, err := newVulnScanner(context.Background(), newTestVulnDB(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			captureStdout(t)
+			got := doCheck(context.Background(), []goutil.Package{tt.pkg}, checkOptions{cpus: 1, vulns: scanner, failOn: tt.failOn})
+			if got != tt.want {
+				t.Errorf("doCheck() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_doCheck_vulnReport(t *testing.T) {
+	stubVulnUpdate(t)
+	scanner, err := newVulnScanner(context.Background(), newTestVulnDB(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := captureStdout(t)
+	w, err := openReportWriter(newOutputTestCmd(t, outputFormatJSON), "check")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkgs := []goutil.Package{newVulnTestPackage("fixed", "go1.22.5", &debug.Module{Path: "golang.org/x/net", Version: "v0.15.0"})}
+	w.close(doCheck(context.Background(), pkgs, checkOptions{cpus: 1, vulns: scanner, report: w}))
+
+	var doc outputDocument
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not a JSON document: %v\n%s", err, out.String())
+	}
+	want := []vulnReport{{
+		ID:           "GO-2023-2102",
+		ModulePath:   "golang.org/x/net",
+		Version:      "v0.15.0",
+		FixedVersion: "v0.17.0",
+		Fix:          vulnFixedByUpdate,
+		Summary:      "HTTP/2 rapid reset can cause excessive work in net/http",
+	}}
+	if len(doc.Packages) != 1 {
+		t.Fatalf("document = %+v, want 1 package", doc)
+	}
+	if diff := cmp.Diff(want, doc.Packages[0].Vulns); diff != "" {
+		t.Errorf("vulns mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_getFlagFailOn(t *testing.T) {
+	for raw, want := range map[string]failOn{"": failOnNone, "vuln": failOnVuln, "Outdated": failOnOutdated, "any": failOnAny} {
+		cmd := newCheckCmd()
+		if err := cmd.Flags().Set("fail-on", raw); err != nil {
+			t.Fatal(err)
+		}
+		got, err := getFlagFailOn(cmd)
+		if err != nil || got != want {
+			t.Errorf("getFlagFailOn(%q) = %q, %v, want %q", raw, got, err, want)
+		}
+	}
+
+	cmd := newCheckCmd()
+	if err := cmd.Flags().Set("fail-on", "minor"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := getFlagFailOn(cmd); err == nil {
+		t.Error("getFlagFailOn() accepts an unknown value")
+	}
+}
@@ -1,21 +1,26 @@
 package cmd
 
 import (
-	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
-	"runtime"
 
 	"github.com/nao1215/gup/internal/config"
 	"github.com/nao1215/gup/internal/fileutil"
 	"github.com/nao1215/gup/internal/goutil"
 )
 
-var writeConfFile = config.WriteConfFile //nolint:gochecknoglobals // swapped in tests
+var writeConfFile = config.WriteConfFileWithSettings //nolint:gochecknoglobals // swapped in tests
 
+// writeConfigFile replaces the packages of the configuration-file at path.
+// The top-level settings of the file are kept; a file that can't be read,
+// which callers have already warned about, is replaced without them.
 func writeConfigFile(path string, pkgs []goutil.Package) (err error) {
 	path = filepath.Clean(path)
+	settings, readErr := readConfSettingsIfExists(path)
+	if readErr != nil {
+		settings = config.Settings{}
+	}
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, fileutil.FileModeCreatingDir); err != nil {
 		return fmt.Errorf("%s: %w", "can not make config directory", err)
@@ -37,7 +42,7 @@ func writeConfigFile(path string, pkgs []goutil.Package) (err error) {
 		}
 	}()
 
-	if err = writeConfFile(file, pkgs); err != nil {
+	if err = writeConfFile(file, pkgs, settings); err != nil {
 		return err
 	}
 	if err = file.Sync(); err != nil {
@@ -49,72 +54,18 @@ func writeConfigFile(path string, pkgs []goutil.Package) (err error) {
 	}
 	file = nil
 
-	if err = renameWithReplace(tmpPath, path); err != nil {
+	if err = fileutil.RenameWithReplace(tmpPath, path); err != nil {
 		return fmt.Errorf("%s %s: %w", "can't update", path, err)
 	}
 
 	return nil
 }
 
-func renameWithReplace(src, dst string) error {
-	//nolint:gosec // src/dst are created by this process and not user-controlled.
-	if err := os.Rename(src, dst); err != nil {
-		// Windows cannot overwrite an existing file with os.Rename.
-		// Retry via destination backup swap when the destination likely exists.
-		if !shouldRetryRenameWithReplace(err, dst) {
-			return err
-		}
-		return renameWithBackupSwap(src, dst)
-	}
-	return nil
-}
-
-func renameWithBackupSwap(src, dst string) error {
-	backupPath, err := prepareBackupPath(dst)
-	if err != nil {
-		return err
-	}
-
-	if err = os.Rename(dst, backupPath); err != nil {
-		return err
-	}
-	//nolint:gosec // src/dst are created by this process and not user-controlled.
-	if err = os.Rename(src, dst); err != nil {
-		if restoreErr := os.Rename(backupPath, dst); restoreErr != nil {
-			return errors.Join(err, fmt.Errorf("can't restore original file %s after failed update: %w", dst, restoreErr))
-		}
-		return err
-	}
-
-	_ = os.Remove(backupPath)
-	return nil
-}
-
-func prepareBackupPath(dst string) (string, error) {
-	backupFile, err := os.CreateTemp(filepath.Dir(dst), filepath.Base(dst)+".bak-*")
-	if err != nil {
-		return "", err
-	}
-	backupPath := backupFile.Name()
-	if err := backupFile.Close(); err != nil {
-		//nolint:gosec // backupPath is created by os.CreateTemp in this function.
-		_ = os.Remove(backupPath)
-		return "", err
-	}
-	//nolint:gosec // backupPath is created by os.CreateTemp in this function.
-	if err := os.Remove(backupPath); err != nil {
-		return "", err
-	}
-	return backupPath, nil
-}
-
-func shouldRetryRenameWithReplace(renameErr error, dst string) bool {
-	if os.IsExist(renameErr) {
-		return true
-	}
-	if runtime.GOOS != goosWindows {
-		return false
+// readConfSettingsIfExists returns the top-level settings of the
+// configuration-file at path, or no settings if there is no file.
+func readConfSettingsIfExists(path string) (config.Settings, error) {
+	if !fileutil.IsFile(path) {
+		return config.Settings{}, nil
 	}
-	_, err := os.Stat(dst)
-	return err == nil
+	return config.ReadSettings(path)
 }
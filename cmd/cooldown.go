@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-version"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+	"github.com/spf13/cobra"
+)
+
+// addCooldownFlag registers --cooldown-days.
+func addCooldownFlag(cmd *cobra.Command) {
+	cmd.Flags().Int("cooldown-days", 0, "skip versions published less than this many days ago (overrides cooldown_days in gup.json)")
+}
+
+// getFlagCooldownDays returns the --cooldown-days value, or nil if the flag is not set.
+func getFlagCooldownDays(cmd *cobra.Command) (*int, error) {
+	days, err := getFlagInt(cmd, "cooldown-days")
+	if err != nil {
+		return nil, err
+	}
+	if days < 0 {
+		return nil, fmt.Errorf("can not parse command line argument (--cooldown-days): must not be negative: %d", days)
+	}
+	if !cmd.Flags().Changed("cooldown-days") {
+		return nil, nil
+	}
+	return &days, nil
+}
+
+// cooldownOption is the release cooldown given outside the package entries of gup.json.
+type cooldownOption struct {
+	// flag is --cooldown-days. It wins over gup.json.
+	flag *int
+	// global is the top-level cooldown_days in gup.json. The cooldown_days
+	// of a package wins over it.
+	global *int
+}
+
+// days returns the cooldown of a package whose cooldown_days in gup.json is pkgDays.
+// The order is --cooldown-days, then the package, then the top-level setting.
+func (o cooldownOption) days(pkgDays *int) int {
+	for _, d := range []*int{o.flag, pkgDays, o.global} {
+		if d != nil {
+			return *d
+		}
+	}
+	return 0
+}
+
+func cooldownDuration(days int) time.Duration {
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// applyCooldown returns the newest version, from target down to the installed
+// version, that was published at least rule.cooldown ago. When no newer version
+// is old enough, the installed version is kept.
+func applyCooldown(ctx context.Context, p goutil.Package, rule versionRule, target string,
+	versions []string, verCache *latestVerCache) (targetVersion, error) {
+	candidates := []string{target}
+	if targetVer, err := version.NewVersion(target); err == nil {
+		for _, v := range goutil.AllowedVersions(p.Version.Current, versions, rule.policy) {
+			if ver, err := version.NewVersion(v); err == nil && ver.LessThan(targetVer) {
+				candidates = append(candidates, v)
+			}
+		}
+	}
+
+	for _, v := range candidates {
+		published, err := verCache.versionTime(ctx, p.ModulePath, v)
+		if err != nil {
+			return targetVersion{}, err
+		}
+		if time.Since(published) < rule.cooldown {
+			continue
+		}
+		held := ""
+		if v != target {
+			held = target
+		}
+		return targetVersion{version: v, heldBack: held}, nil
+	}
+	return targetVersion{version: p.Version.Current, heldBack: target}, nil
+}
+
+// heldBackPkg is a package whose newest version was skipped by the release cooldown.
+type heldBackPkg struct {
+	name     string
+	heldBack string
+	target   string
+}
+
+func printHeldBackPkgs(pkgs []heldBackPkg) {
+	for _, p := range pkgs {
+		print.Info(fmt.Sprintf("%s: %s is held back by the release cooldown (using %s)", p.name, p.heldBack, p.target))
+	}
+}
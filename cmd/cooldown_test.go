@@ -0,0 +1,125 @@
+//nolint:paralleltest
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+)
+
+// stubVersionTimes makes getVersionTimeCtx report versions as published
+// the given number of days ago.
+func stubVersionTimes(t *testing.T, daysAgo map[string]int) {
+	t.Helper()
+
+	orig := getVersionTimeCtx
+	t.Cleanup(func() { getVersionTimeCtx = orig })
+	getVersionTimeCtx = func(_ context.Context, _ string, ver string) (time.Time, error) {
+		days, ok := daysAgo[ver]
+		if !ok {
+			t.Fatalf("unexpected publish time lookup of %s", ver)
+		}
+		return time.Now().Add(-cooldownDuration(days)), nil
+	}
+}
+
+func Test_resolveTargetVersion_cooldown(t *testing.T) {
+	origGetVersionList := getVersionListCtx
+	defer func() { getVersionListCtx = origGetVersionList }()
+	getVersionListCtx = func(context.Context, string) ([]string, error) {
+		return []string{"v1.0.0", "v1.0.1", "v1.0.2", "v1.1.0"}, nil
+	}
+	stubVersionTimes(t, map[string]int{"v1.1.0": 1, "v1.0.2": 3, "v1.0.1": 10})
+
+	pkg := goutil.Package{
+		Name:       "tool",
+		ModulePath: "example.com/tool",
+		Version:    &goutil.Version{Current: "v1.0.0"},
+	}
+	tests := []struct {
+		name string
+		rule versionRule
+		want targetVersion
+	}{
+		{name: "latest is old enough", rule: versionRule{cooldown: cooldownDuration(1)}, want: targetVersion{version: "v1.1.0"}},
+		{name: "falls back to an older version", rule: versionRule{cooldown: cooldownDuration(2)}, want: targetVersion{version: "v1.0.2", heldBack: "v1.1.0"}},
+		{name: "policy and cooldown", rule: versionRule{policy: goutil.UpdatePolicyPatch, cooldown: cooldownDuration(5)}, want: targetVersion{version: "v1.0.1", heldBack: "v1.0.2"}},
+		{name: "nothing is old enough", rule: versionRule{cooldown: cooldownDuration(30)}, want: targetVersion{version: "v1.0.0", heldBack: "v1.1.0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveTargetVersion(context.Background(), pkg, goutil.UpdateChannelLatest, tt.rule, "v1.1.0", newLatestVerCache())
+			if err != nil {
+				t.Fatalf("resolveTargetVersion() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("resolveTargetVersion() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_doCheck_cooldownReportsHeldBack(t *testing.T) {
+	origGetLatest := getLatestVer
+	origGetVersionList := getVersionListCtx
+	defer func() {
+		getLatestVer = origGetLatest
+		getVersionListCtx = origGetVersionList
+	}()
+	getLatestVer = func(string) (string, error) { return "v1.2.0", nil }
+	getVersionListCtx = func(context.Context, string) ([]string, error) {
+		return []string{"v1.1.1", "v1.1.2", "v1.2.0"}, nil
+	}
+	stubVersionTimes(t, map[string]int{"v1.2.0": 1, "v1.1.2": 20})
+
+	orgStdout := print.Stdout
+	orgStderr := print.Stderr
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	print.Stdout = pw
+	print.Stderr = pw
+
+	pkgs := []goutil.Package{{
+		Name:       "gal",
+		ImportPath: "github.com/nao1215/gal/cmd/gal",
+		ModulePath: "github.com/nao1215/gal",
+		Version:    &goutil.Version{Current: "v1.1.1"},
+		GoVersion:  &goutil.Version{Current: "go1.22.4", Latest: "go1.22.4"},
+	}}
+	got := doCheck(context.Background(), pkgs, checkOptions{
+		cpus:           1,
+		ignoreGoUpdate: true,
+		rules:          map[string]versionRule{"gal": {cooldown: cooldownDuration(7)}},
+	})
+
+	pw.Close()
+	print.Stdout = orgStdout
+	print.Stderr = orgStderr
+
+	buf := bytes.Buffer{}
+	if _, err := io.Copy(&buf, pr); err != nil {
+		t.Fatal(err)
+	}
+	_ = pr.Close()
+
+	if got != 0 {
+		t.Fatalf("doCheck() = %v, want 0", got)
+	}
+	for _, want := range []string{
+		"gal: v1.2.0 is held back by the release cooldown (using v1.1.2)",
+		"$ gup update gal",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("doCheck() output should contain %q, got:\n%s", want, buf.String())
+		}
+	}
+}
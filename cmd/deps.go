@@ -0,0 +1,296 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/hashicorp/go-version"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+	"github.com/spf13/cobra"
+)
+
+func newDepsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "deps <binary>",
+		Short: "Print the module dependencies embedded in a binary",
+		Long: `Print the module dependencies embedded in a binary under $GOPATH/bin or $GOBIN.
+
+deps prints the version, checksum and replace directive of each module
+that the binary is built with, like 'go version -m'.
+With --outdated, deps looks up the latest version of each module and
+flags the modules that have a newer version.
+--output json or ndjson prints the dependencies in the "deps" field of the
+binary, in the same format as the other commands. --json is the same as
+--output json.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completePathBinaries,
+		Run: func(cmd *cobra.Command, args []string) {
+			OsExit(deps(cmd, args))
+		},
+	}
+	cmd.Flags().Bool("outdated", false, "Flag the modules that have a newer version")
+	addLatestVerCacheFlags(cmd)
+	addOfflineFlag(cmd)
+	addOutputFlag(cmd)
+	cmd.Flags().Bool("json", false, "Print the dependencies as JSON (same as --output json)")
+	cmd.Flags().IntP("jobs", "j", runtime.NumCPU(), "Specify the number of CPU cores to use with --outdated")
+	if err := cmd.RegisterFlagCompletionFunc("jobs", completeNCPUs); err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+// depModule is a module dependency of a binary.
+type depModule struct {
+	Path    string      `json:"path"`
+	Version string      `json:"version"`
+	Sum     string      `json:"sum,omitempty"`
+	Replace *depReplace `json:"replace,omitempty"`
+	// LatestVersion is the newer version found by --outdated.
+	LatestVersion string `json:"latest_version,omitempty"`
+	Outdated      bool   `json:"outdated,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// depReplace is the module that replaces a dependency. A local directory has no version.
+type depReplace struct {
+	Path    string `json:"path"`
+	Version string `json:"version,omitempty"`
+	Sum     string `json:"sum,omitempty"`
+}
+
+// depsReport is what 'gup deps' prints as text.
+type depsReport struct {
+	Name       string
+	ImportPath string
+	ModulePath string
+	Version    string
+	GoVersion  string
+	Deps       []depModule
+}
+
+func deps(cmd *cobra.Command, args []string) int {
+	if err := applyJSONFlag(cmd); err != nil {
+		print.Err(err)
+		return 1
+	}
+	report, err := openReportWriter(cmd, "deps")
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	return report.close(runDeps(cmd, args, report))
+}
+
+// applyJSONFlag turns --json into --output json.
+func applyJSONFlag(cmd *cobra.Command) error {
+	asJSON, err := getFlagBool(cmd, "json")
+	if err != nil || !asJSON {
+		return err
+	}
+	if cmd.Flags().Changed("output") {
+		format, err := getFlagString(cmd, "output")
+		if err != nil {
+			return err
+		}
+		if format != outputFormatJSON {
+			return fmt.Errorf("--json and --output %s can not be used together", format)
+		}
+	}
+	return cmd.Flags().Set("output", outputFormatJSON)
+}
+
+func runDeps(cmd *cobra.Command, args []string, report *reportWriter) int {
+	if err := ensureGoCommandAvailable(); err != nil {
+		print.Err(err)
+		return 1
+	}
+
+	outdated, err := getFlagBool(cmd, "outdated")
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	cpus, err := getFlagInt(cmd, "jobs")
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+
+	verCache, err := latestVerCacheFromFlags(cmd)
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	if err := setupOfflineMode(cmd, verCache); err != nil {
+		print.Err(err)
+		return 1
+	}
+
+	pkgs, err := getPackageInfoByTargets(args)
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	pkgs = extractUserSpecifyPkg(pkgs, args)
+	if len(pkgs) == 0 {
+		print.Err(fmt.Errorf("%s is not installed by 'go install' (or permission incorrect)", args[0]))
+		return 1
+	}
+	p := pkgs[0]
+
+	r := depsReport{
+		Name:       p.Name,
+		ImportPath: p.ImportPath,
+		ModulePath: p.ModulePath,
+		Version:    p.Version.Current,
+		GoVersion:  p.GoVersion.Current,
+		Deps:       newDepModules(p.Deps),
+	}
+	result := 0
+	if outdated {
+		ctx, cancel, signals := newSignalCancelContext()
+		defer stopSignalCancelContext(cancel, signals)
+		result = findOutdatedDeps(ctx, r.Deps, clampJobs(cpus), verCache)
+	}
+
+	pkgReport := newPackageReport(p, statusInstalled)
+	pkgReport.Deps = r.Deps
+	report.add(pkgReport)
+	if err := printDeps(r, outdated); err != nil {
+		print.Err(fmt.Errorf("can't print the dependencies: %w", err))
+		return 1
+	}
+	return result
+}
+
+func newDepModules(mods []*debug.Module) []depModule {
+	deps := make([]depModule, 0, len(mods))
+	for _, m := range mods {
+		if m == nil {
+			continue
+		}
+		d := depModule{Path: m.Path, Version: m.Version, Sum: m.Sum}
+		if m.Replace != nil {
+			d.Replace = &depReplace{Path: m.Replace.Path, Version: m.Replace.Version, Sum: m.Replace.Sum}
+		}
+		deps = append(deps, d)
+	}
+	return deps
+}
+
+// findOutdatedDeps looks up the latest version of each module in parallel and
+// sets LatestVersion of the modules that have a newer version. A module
+// replaced by another module is compared with the latest version of the
+// replacement; one replaced by a local directory is skipped.
+// It returns 1 if a lookup failed.
+func findOutdatedDeps(ctx context.Context, deps []depModule, cpus int, verCache *latestVerCache) int {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, cpus)
+	for i := range deps {
+		d := &deps[i]
+		modulePath, current := d.Path, d.Version
+		if d.Replace != nil {
+			if d.Replace.Version == "" {
+				continue
+			}
+			modulePath, current = d.Replace.Path, d.Replace.Version
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			latest, err := verCache.channelVersion(ctx, modulePath, goutil.UpdateChannelLatest)
+			if isUnknownOffline(err) {
+				// Not a failure: the newer versions can't be known offline.
+				return
+			}
+			if err != nil {
+				d.Error = err.Error()
+				return
+			}
+			if isNewerVersion(latest, current) {
+				d.LatestVersion, d.Outdated = latest, true
+			}
+		}()
+	}
+	wg.Wait()
+
+	result := 0
+	for _, d := range deps {
+		if d.Error != "" {
+			result = 1
+			print.Err(fmt.Errorf("can't check %s: %s", d.Path, d.Error))
+		}
+	}
+	return result
+}
+
+// isNewerVersion reports whether latest is newer than current.
+func isNewerVersion(latest, current string) bool {
+	latestVer, err := version.NewVersion(latest)
+	if err != nil {
+		return false
+	}
+	currentVer, err := version.NewVersion(current)
+	if err != nil {
+		return false
+	}
+	return latestVer.GreaterThan(currentVer)
+}
+
+// printDeps prints the dependencies as a table. With outdated, a LATEST
+// column shows the newer versions.
+func printDeps(r depsReport, outdated bool) error {
+	print.Info(fmt.Sprintf("%s: %s@%s (%s)", r.Name, r.ImportPath, r.Version, r.GoVersion))
+	if len(r.Deps) == 0 {
+		print.Info("no module dependencies")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(print.Stdout, 0, 0, 2, ' ', 0)
+	header := "MODULE\tVERSION\tSUM\tREPLACE"
+	if outdated {
+		header += "\tLATEST"
+	}
+	fmt.Fprintln(w, header) //nolint:errcheck // flushed below
+	count := 0
+	for _, d := range r.Deps {
+		line := fmt.Sprintf("%s\t%s\t%s\t%s", d.Path, d.Version, orDash(d.Sum), depReplaceStr(d.Replace))
+		if outdated {
+			line += "\t" + orDash(d.LatestVersion)
+			if d.Outdated {
+				count++
+			}
+		}
+		fmt.Fprintln(w, line) //nolint:errcheck // flushed below
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if outdated {
+		print.Info(fmt.Sprintf("%d of %d modules have a newer version", count, len(r.Deps)))
+	}
+	return nil
+}
+
+func depReplaceStr(r *depReplace) string {
+	switch {
+	case r == nil:
+		return "-"
+	case r.Version == "":
+		return "=> " + r.Path
+	default:
+		return "=> " + r.Path + "@" + r.Version
+	}
+}
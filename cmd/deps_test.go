@@ -0,0 +1,143 @@
+//nolint:paralleltest // tests mutate global variables and environment variables
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExecute_Deps(t *testing.T) {
+	gobin := t.TempDir()
+	t.Setenv("GOBIN", gobin)
+	helper_CopyFile(t, filepath.Join("testdata", "check_success", "gal"), filepath.Join(gobin, withExecSuffix("gal")))
+
+	got, err := helper_runGup(t, []string{"gup", "deps", "gal"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"gal: github.com/nao1215/gal/cmd/gal@v1.1.1 (go1.18)",
+		"MODULE                        VERSION                             SUM                                              REPLACE",
+		"github.com/jessevdk/go-flags  v1.5.0                              h1:1jKYvbxEjfUl0fmqTCOfonvskHHXMjBySTLW4y9LFvc=  -",
+		"golang.org/x/sys              v0.0.0-20210320140829-1e4c9ba3b0c4  h1:EZ2mChiOa8udjfp6rRmswTbtZN/QzUQp4ptM4rnjHvc=  -",
+		"",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("output mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err = helper_runGup(t, []string{"gup", "deps", "--output", "json", "gal"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc outputDocument
+	if err := json.Unmarshal([]byte(strings.Join(got, "\n")), &doc); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, got)
+	}
+	if doc.SchemaVersion != outputSchemaVersion || doc.Command != "deps" || doc.ExitCode != 0 || len(doc.Packages) != 1 {
+		t.Fatalf("output = %+v, want one package of the deps command", doc)
+	}
+	r := doc.Packages[0]
+	if r.Name != "gal" || r.Version != "v1.1.1" || r.Status != statusInstalled || len(r.Deps) != 2 ||
+		r.Deps[0].Path != "github.com/jessevdk/go-flags" || r.Deps[0].Sum == "" {
+		t.Errorf("report = %+v, want gal v1.1.1 with 2 modules", r)
+	}
+}
+
+func TestExecute_Deps_jsonFlag(t *testing.T) {
+	gobin := t.TempDir()
+	t.Setenv("GOBIN", gobin)
+	helper_CopyFile(t, filepath.Join("testdata", "check_success", "gal"), filepath.Join(gobin, withExecSuffix("gal")))
+
+	got, err := helper_runGup(t, []string{"gup", "deps", "--json", "gal"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc outputDocument
+	if err := json.Unmarshal([]byte(strings.Join(got, "\n")), &doc); err != nil {
+		t.Fatalf("--json output is not JSON: %v\n%s", err, got)
+	}
+	if doc.Command != "deps" || len(doc.Packages) != 1 || len(doc.Packages[0].Deps) != 2 {
+		t.Errorf("--json output = %+v, want the same document as --output json", doc)
+	}
+
+	cmd := newDepsCmd()
+	if err := cmd.ParseFlags([]string{"--json", "--output", "ndjson"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyJSONFlag(cmd); err == nil {
+		t.Error("applyJSONFlag() error = nil, want error for --json with --output ndjson")
+	}
+}
+
+func TestExecute_Deps_offline(t *testing.T) {
+	gobin := t.TempDir()
+	t.Setenv("GOBIN", gobin)
+	t.Setenv("GOMODCACHE", t.TempDir())
+	// --offline sets GOPROXY and GOFLAGS for the go command; restore them after the test.
+	t.Setenv("GOPROXY", os.Getenv("GOPROXY"))
+	t.Setenv("GOFLAGS", os.Getenv("GOFLAGS"))
+	helper_CopyFile(t, filepath.Join("testdata", "check_success", "gal"), filepath.Join(gobin, withExecSuffix("gal")))
+
+	origGetLatest := getLatestVerCtx
+	defer func() { getLatestVerCtx = origGetLatest }()
+	getLatestVerCtx = func(context.Context, string) (string, error) {
+		t.Fatal("--offline must not look up the latest version over the network")
+		return "", nil
+	}
+
+	// An empty module cache knows no newer versions, which is not a failure.
+	got, err := helper_runGup(t, []string{"gup", "deps", "--outdated", "--offline", "--output", "ndjson", "gal"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || !strings.Contains(got[0], `"type":"package"`) || !strings.Contains(got[1], `"exit_code":0`) {
+		t.Errorf("ndjson output = %q, want a package record and a summary with exit code 0", got)
+	}
+}
+
+func Test_findOutdatedDeps(t *testing.T) {
+	origGetLatest := getLatestVerCtx
+	defer func() {
+		getLatestVerCtx = origGetLatest
+	}()
+	getLatestVerCtx = func(_ context.Context, modulePath string) (string, error) {
+		switch modulePath {
+		case "example.com/broken":
+			return "", errors.New("not found")
+		case "example.com/fork":
+			return "v1.3.0", nil
+		}
+		return "v1.2.0", nil
+	}
+
+	deps := newDepModules([]*debug.Module{
+		{Path: "example.com/old", Version: "v1.1.0"},
+		{Path: "example.com/new", Version: "v1.2.0"},
+		{Path: "example.com/replaced", Version: "v1.0.0", Replace: &debug.Module{Path: "example.com/fork", Version: "v1.3.0"}},
+		{Path: "example.com/local", Version: "v1.0.0", Replace: &debug.Module{Path: "../local"}},
+		{Path: "example.com/broken", Version: "v1.0.0"},
+	})
+	if got := findOutdatedDeps(context.Background(), deps, 2, newLatestVerCache()); got != 1 {
+		t.Errorf("findOutdatedDeps() = %d, want 1 for the failed lookup", got)
+	}
+
+	want := []depModule{
+		{Path: "example.com/old", Version: "v1.1.0", LatestVersion: "v1.2.0", Outdated: true},
+		{Path: "example.com/new", Version: "v1.2.0"},
+		{Path: "example.com/replaced", Version: "v1.0.0", Replace: &depReplace{Path: "example.com/fork", Version: "v1.3.0"}},
+		{Path: "example.com/local", Version: "v1.0.0", Replace: &depReplace{Path: "../local"}},
+		{Path: "example.com/broken", Version: "v1.0.0", Error: "not found"},
+	}
+	if diff := cmp.Diff(want, deps); diff != "" {
+		t.Errorf("deps mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -69,7 +69,7 @@ func export(cmd *cobra.Command, _ []string) int {
 	}
 
 	if output {
-		err = outputConfig(pkgs)
+		err = outputConfig(configPath, pkgs)
 	} else {
 		err = writeConfigFile(configPath, pkgs)
 	}
@@ -83,8 +83,13 @@ func export(cmd *cobra.Command, _ []string) int {
 	return 0
 }
 
-func outputConfig(pkgs []goutil.Package) error {
-	return config.WriteConfFile(print.Stdout, pkgs)
+// outputConfig prints pkgs with the top-level settings of the configuration-file at configPath.
+func outputConfig(configPath string, pkgs []goutil.Package) error {
+	settings, err := readConfSettingsIfExists(configPath)
+	if err != nil {
+		settings = config.Settings{}
+	}
+	return config.WriteConfFileWithSettings(print.Stdout, pkgs, settings)
 }
 
 func validPkgInfo(pkgs []goutil.Package) []goutil.Package {
@@ -94,25 +99,29 @@ func validPkgInfo(pkgs []goutil.Package) []goutil.Package {
 			print.Warn("can't get '" + v.Name + "' package path information. old go version binary")
 			continue
 		}
-		result = append(result, goutil.Package{Name: v.Name, ImportPath: v.ImportPath, Version: v.Version})
+		result = append(result, goutil.Package{Name: v.Name, ImportPath: v.ImportPath, Version: v.Version, Build: v.Build, Sum: v.Sum})
 	}
 	return result
 }
 
+// applySavedChannels applies the channels and other per-package settings
+// saved in gup.json to pkgs.
 func applySavedChannels(pkgs, confPkgs []goutil.Package) []goutil.Package {
-	channelByName := make(map[string]goutil.UpdateChannel, len(confPkgs))
+	confByName := make(map[string]goutil.Package, len(confPkgs))
 	for _, p := range confPkgs {
-		channelByName[p.Name] = goutil.NormalizeUpdateChannel(string(p.UpdateChannel))
+		confByName[p.Name] = p
 	}
 
 	result := make([]goutil.Package, 0, len(pkgs))
 	for _, p := range pkgs {
-		channel, ok := channelByName[p.Name]
+		conf, ok := confByName[p.Name]
 		if !ok {
-			channel = goutil.UpdateChannelLatest
+			p.UpdateChannel = goutil.UpdateChannelLatest
+			result = append(result, p)
+			continue
 		}
-		p.UpdateChannel = channel
-		result = append(result, p)
+		p.UpdateChannel = goutil.NormalizeUpdateChannel(string(conf.UpdateChannel))
+		result = append(result, withConfigSettings(p, conf))
 	}
 	return result
 }
@@ -41,6 +41,47 @@ func Test_validPkgInfo(t *testing.T) {
 			},
 			want: []goutil.Package{},
 		},
+		{
+			name: "build settings are kept",
+			args: args{
+				pkgs: []goutil.Package{
+					{
+						Name:       "test",
+						ImportPath: "example.com/test",
+						ModulePath: "example.com/test",
+						Build:      goutil.BuildSettings{Tags: []string{"netgo"}, Trimpath: true},
+					},
+				},
+			},
+			want: []goutil.Package{
+				{
+					Name:       "test",
+					ImportPath: "example.com/test",
+					Build:      goutil.BuildSettings{Tags: []string{"netgo"}, Trimpath: true},
+				},
+			},
+		},
+		{
+			name: "checksum is kept",
+			args: args{
+				pkgs: []goutil.Package{
+					{
+						Name:       "test",
+						ImportPath: "example.com/test",
+						Version:    &goutil.Version{Current: "v1.0.0"},
+						Sum:        "h1:abc=",
+					},
+				},
+			},
+			want: []goutil.Package{
+				{
+					Name:       "test",
+					ImportPath: "example.com/test",
+					Version:    &goutil.Version{Current: "v1.0.0"},
+					Sum:        "h1:abc=",
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -52,6 +93,26 @@ func Test_validPkgInfo(t *testing.T) {
 	}
 }
 
+func Test_applySavedChannels(t *testing.T) {
+	recovered := goutil.BuildSettings{Tags: []string{"netgo"}}
+	saved := goutil.BuildSettings{Ldflags: "-s -w"}
+	pkgs := []goutil.Package{
+		{Name: "saved", ImportPath: "example.com/saved", Build: recovered},
+		{Name: "new", ImportPath: "example.com/new", Build: recovered},
+	}
+	confPkgs := []goutil.Package{
+		{Name: "saved", UpdateChannel: goutil.UpdateChannelMain, Policy: goutil.UpdatePolicyMinor, Build: saved},
+	}
+
+	want := []goutil.Package{
+		{Name: "saved", ImportPath: "example.com/saved", UpdateChannel: goutil.UpdateChannelMain, Policy: goutil.UpdatePolicyMinor, Build: saved},
+		{Name: "new", ImportPath: "example.com/new", UpdateChannel: goutil.UpdateChannelLatest, Build: recovered},
+	}
+	if diff := cmp.Diff(want, applySavedChannels(pkgs, confPkgs)); diff != "" {
+		t.Errorf("applySavedChannels() mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func Test_export_not_use_go_cmd(t *testing.T) {
 	t.Run("Not found go command", func(t *testing.T) {
 		t.Setenv("PATH", "")
@@ -252,6 +313,26 @@ func Test_writeConfigFile(t *testing.T) {
 	}
 }
 
+func Test_writeConfigFile_keepsSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gup.json")
+	original := `{"schema_version":1,"cooldown_days":7,"packages":[]}` + "\n"
+	if err := os.WriteFile(path, []byte(original), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	pkgs := []goutil.Package{{Name: "gal", ImportPath: "github.com/nao1215/gal/cmd/gal", Version: &goutil.Version{Current: "v1.1.1"}}}
+	if err := writeConfigFile(path, pkgs); err != nil {
+		t.Fatalf("writeConfigFile() error = %v", err)
+	}
+	settings, err := config.ReadSettings(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.CooldownDays == nil || *settings.CooldownDays != 7 {
+		t.Errorf("cooldown_days after rewrite = %v, want the kept 7", settings.CooldownDays)
+	}
+}
+
 func Test_writeConfigFile_atomicOnWriteError(t *testing.T) {
 	origWriteConfFile := writeConfFile
 	t.Cleanup(func() { writeConfFile = origWriteConfFile })
@@ -262,7 +343,7 @@ func Test_writeConfigFile_atomicOnWriteError(t *testing.T) {
 		t.Fatalf("failed to seed original config: %v", err)
 	}
 
-	writeConfFile = func(w io.Writer, _ []goutil.Package) error {
+	writeConfFile = func(w io.Writer, _ []goutil.Package, _ config.Settings) error {
 		if _, err := w.Write([]byte(`{"schema_version":1,`)); err != nil {
 			return err
 		}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -22,6 +23,14 @@ func getFlagInt(cmd *cobra.Command, name string) (int, error) {
 	return v, nil
 }
 
+func getFlagDuration(cmd *cobra.Command, name string) (time.Duration, error) {
+	v, err := cmd.Flags().GetDuration(name)
+	if err != nil {
+		return 0, fmt.Errorf("can not parse command line argument (--%s): %w", name, err)
+	}
+	return v, nil
+}
+
 func getFlagString(cmd *cobra.Command, name string) (string, error) {
 	v, err := cmd.Flags().GetString(name)
 	if err != nil {
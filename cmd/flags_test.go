@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"testing"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -58,6 +59,32 @@ func TestGetFlagInt(t *testing.T) {
 	})
 }
 
+func TestGetFlagDuration(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		cmd := &cobra.Command{}
+		cmd.Flags().Duration("cache-ttl", time.Hour, "")
+		v, err := getFlagDuration(cmd, "cache-ttl")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if v != time.Hour {
+			t.Errorf("got %v, want %v", v, time.Hour)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+		cmd := &cobra.Command{}
+		_, err := getFlagDuration(cmd, "no-such-flag")
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+	})
+}
+
 func TestGetFlagString(t *testing.T) {
 	t.Parallel()
 
@@ -4,9 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 
+	"github.com/nao1215/gup/internal/cmdinfo"
 	"github.com/nao1215/gup/internal/config"
 	"github.com/nao1215/gup/internal/fileutil"
 	"github.com/nao1215/gup/internal/goutil"
@@ -26,7 +30,14 @@ Use export/import if you want to install the same golang binaries
 across multiple systems.
 First, run 'gup export' on the source environment and copy gup.json.
 Then run 'gup import' on the target environment to install the
-versions recorded in that gup.json.`,
+versions recorded in that gup.json.
+
+With --bundle, the binaries are installed only from a bundle created by
+'gup bundle create', using the gup.json in the bundle unless --file is given.
+
+With --sync, binaries under $GOPATH/bin or $GOBIN that are not listed in
+gup.json are removed after the import succeeds. gup itself and the
+binaries given by --keep are never removed.`,
 		Args:              cobra.NoArgs,
 		ValidArgsFunction: cobra.NoFileCompletions,
 		Run: func(cmd *cobra.Command, args []string) {
@@ -44,11 +55,31 @@ versions recorded in that gup.json.`,
 	if err := cmd.RegisterFlagCompletionFunc("jobs", completeNCPUs); err != nil {
 		panic(err)
 	}
+	cmd.Flags().String("bundle", "", "install from a bundle created by 'gup bundle create' without network access")
+	if err := cmd.MarkFlagFilename("bundle", "tar.gz", "tgz"); err != nil {
+		panic(err)
+	}
+	cmd.Flags().Bool("sync", false, "also remove binaries that are not listed in gup.json")
+	cmd.Flags().StringSlice("keep", []string{}, "specify binaries which --sync never removes (delimiter: ',')")
+	if err := cmd.RegisterFlagCompletionFunc("keep", completePathBinaries); err != nil {
+		panic(err)
+	}
+	addPlanFlag(cmd)
+	addOutputFlag(cmd)
 
 	return cmd
 }
 
-func runImport(cmd *cobra.Command, _ []string) int {
+func runImport(cmd *cobra.Command, args []string) int {
+	report, err := openReportWriter(cmd, "import")
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	return report.close(doImport(cmd, args, report))
+}
+
+func doImport(cmd *cobra.Command, _ []string, report *reportWriter) int {
 	if err := ensureGoCommandAvailable(); err != nil {
 		print.Err(err)
 		return 1
@@ -65,6 +96,22 @@ func runImport(cmd *cobra.Command, _ []string) int {
 		print.Err(err)
 		return 1
 	}
+	bundle, err := getFlagString(cmd, "bundle")
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	if bundle != "" {
+		bundleDir, err := useBundle(bundle)
+		if err != nil {
+			print.Err(err)
+			return 1
+		}
+		defer os.RemoveAll(bundleDir) //nolint:errcheck // best effort cleanup
+		if confFile == "" {
+			confFile = filepath.Join(bundleDir, bundleConfigName)
+		}
+	}
 	confFile = config.ResolveImportFilePath(confFile)
 
 	notify, err := getFlagBool(cmd, "notify")
@@ -80,6 +127,26 @@ func runImport(cmd *cobra.Command, _ []string) int {
 	}
 	cpus = clampJobs(cpus)
 
+	sync, err := getFlagBool(cmd, "sync")
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	keep, err := getFlagStringSlice(cmd, "keep")
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	planFormat, err := getFlagPlanFormat(cmd, nil)
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	if planFormat != "" && report.enabled() {
+		print.Err("--plan and --output can not be used together: use --plan=json")
+		return 1
+	}
+
 	if !fileutil.IsFile(confFile) {
 		print.Err(fmt.Errorf("%s is not found", confFile))
 		return 1
@@ -96,11 +163,90 @@ func runImport(cmd *cobra.Command, _ []string) int {
 		return 1
 	}
 
-	print.Info("start import based on " + confFile)
-	return installFromConfig(pkgs, dryRun, notify, cpus)
+	if planFormat != "" {
+		installed, err := getPackageInfo()
+		if err != nil {
+			print.Err(err)
+			return 1
+		}
+		return printPlan(planImport(pkgs, installed, sync, keep), planFormat)
+	}
+
+	if bundle != "" {
+		print.Info("start import based on " + bundle)
+	} else {
+		print.Info("start import based on " + confFile)
+	}
+	result := installFromConfig(pkgs, dryRun, notify, cpus, report)
+	if !sync {
+		return result
+	}
+	if result != 0 {
+		print.Warn("skip removing binaries not listed in " + confFile + " because the import failed")
+		return result
+	}
+	return removeUnlistedBinaries(pkgs, keep, dryRun)
 }
 
-func installFromConfig(pkgs []goutil.Package, dryRun, notification bool, cpus int) int {
+// removeUnlistedBinaries removes the binaries under $GOBIN that are not listed
+// in confPkgs. gup itself and the binaries in keep are never removed.
+func removeUnlistedBinaries(confPkgs []goutil.Package, keep []string, dryRun bool) int {
+	gobin, err := goutil.GoBin()
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	installed, err := getPackageInfo()
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+
+	targets := unlistedBinaries(confPkgs, installed, keep)
+	if len(targets) == 0 {
+		print.Info("no binaries to remove: every binary is listed in gup.json")
+		return 0
+	}
+	if dryRun {
+		print.Info("binaries not listed in gup.json (not removed because of --dry-run):")
+	} else {
+		print.Info("remove binaries not listed in gup.json:")
+	}
+	for _, name := range targets {
+		print.Info("  " + name)
+	}
+	if dryRun {
+		return 0
+	}
+	return removeLoop(gobin, true, targets)
+}
+
+// unlistedBinaries returns the names of the installed binaries that are
+// listed neither in confPkgs nor in keep, in name order.
+func unlistedBinaries(confPkgs, installed []goutil.Package, keep []string) []string {
+	listed := make(map[string]struct{}, len(confPkgs)+len(keep)+1)
+	listed[normalizeBinaryNameForMatch(cmdinfo.Name)] = struct{}{}
+	for _, p := range confPkgs {
+		listed[normalizeBinaryNameForMatch(p.Name)] = struct{}{}
+	}
+	for _, name := range keep {
+		listed[normalizeBinaryNameForMatch(name)] = struct{}{}
+	}
+
+	names := []string{}
+	for _, p := range installed {
+		if _, ok := listed[normalizeBinaryNameForMatch(p.Name)]; ok {
+			continue
+		}
+		names = append(names, p.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// installFromConfig installs pkgs at the versions in gup.json. The result of
+// each package is added to report, which may be nil.
+func installFromConfig(pkgs []goutil.Package, dryRun, notification bool, cpus int, report *reportWriter) int {
 	result := 0
 	countFmt := "[%" + pkgDigit(pkgs) + "d/%" + pkgDigit(pkgs) + "d]"
 	dryRunManager := goutil.NewGoPaths()
@@ -135,8 +281,16 @@ func installFromConfig(pkgs []goutil.Package, dryRun, notification bool, cpus in
 		if p.Version == nil {
 			p.Version = &goutil.Version{}
 		}
+		// The checksum belongs to the recorded version, not to a different pin.
+		verifySum := p.Sum != "" && strings.TrimSpace(p.Version.Current) == ver
 		p.Version.Current = ver
 
+		build := p.Build
+		build.Toolchain = p.Toolchain
+		ctx = goutil.WithBuildSettings(ctx, build)
+		if verifySum {
+			ctx = goutil.WithModuleSum(ctx, p.Sum)
+		}
 		if err := installByVersionCtx(ctx, p.ImportPath, ver); err != nil {
 			return updateResult{
 				updated: false,
@@ -156,6 +310,7 @@ func installFromConfig(pkgs []goutil.Package, dryRun, notification bool, cpus in
 
 	count := 0
 	for v := range ch {
+		report.add(reportFromResult(v, statusUpdated))
 		if v.err == nil {
 			print.Info(fmt.Sprintf(countFmt+" %s@%s", count+1, len(pkgs), v.pkg.ImportPath, v.pkg.Version.Current))
 		} else {
@@ -180,6 +335,9 @@ func installFromConfig(pkgs []goutil.Package, dryRun, notification bool, cpus in
 }
 
 func versionFromConfig(pkg goutil.Package) (string, error) {
+	if pkg.Pin != "" {
+		return pkg.Pin, nil
+	}
 	if pkg.Version == nil {
 		return "", errors.New("version is missing in gup.json")
 	}
@@ -8,9 +8,13 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/fileutil"
 	"github.com/nao1215/gup/internal/goutil"
 	"github.com/nao1215/gup/internal/print"
 	"github.com/spf13/cobra"
@@ -238,7 +242,7 @@ func Test_installFromConfig_UseVersion(t *testing.T) {
 		},
 	}
 
-	if got := installFromConfig(pkgs, false, false, 1); got != 0 {
+	if got := installFromConfig(pkgs, false, false, 1, nil); got != 0 {
 		t.Fatalf("installFromConfig() = %d, want 0", got)
 	}
 
@@ -250,6 +254,68 @@ func Test_installFromConfig_UseVersion(t *testing.T) {
 	}
 }
 
+func Test_installFromConfig_buildSettings(t *testing.T) {
+	originalInstaller := installByVersionCtx
+	t.Cleanup(func() {
+		installByVersionCtx = originalInstaller
+	})
+
+	got := map[string]goutil.BuildSettings{}
+	installByVersionCtx = func(ctx context.Context, importPath, _ string) error {
+		got[importPath] = goutil.BuildSettingsFromContext(ctx)
+		return nil
+	}
+
+	build := goutil.BuildSettings{Tags: []string{"netgo"}, Env: map[string]string{"GOAMD64": "v3"}}
+	pkgs := []goutil.Package{
+		{Name: "gup", ImportPath: "github.com/nao1215/gup", Version: &goutil.Version{Current: "v1.0.0"}, Build: build},
+		{Name: "gal", ImportPath: "github.com/nao1215/gal/cmd/gal", Version: &goutil.Version{Current: "v1.1.1"}},
+	}
+	if code := installFromConfig(pkgs, false, false, 1, nil); code != 0 {
+		t.Fatalf("installFromConfig() = %d, want 0", code)
+	}
+
+	want := map[string]goutil.BuildSettings{
+		"github.com/nao1215/gup":         build,
+		"github.com/nao1215/gal/cmd/gal": {},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("build settings mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_installFromConfig_moduleSum(t *testing.T) {
+	originalInstaller := installByVersionCtx
+	t.Cleanup(func() {
+		installByVersionCtx = originalInstaller
+	})
+
+	got := map[string]string{}
+	installByVersionCtx = func(ctx context.Context, importPath, _ string) error {
+		got[importPath] = goutil.ModuleSumFromContext(ctx)
+		return nil
+	}
+
+	pkgs := []goutil.Package{
+		{Name: "gup", ImportPath: "github.com/nao1215/gup", Version: &goutil.Version{Current: "v1.0.0"}, Sum: "h1:gup="},
+		{Name: "gal", ImportPath: "github.com/nao1215/gal/cmd/gal", Version: &goutil.Version{Current: "v1.1.1"}, Pin: "v1.0.0", Sum: "h1:gal="},
+		{Name: "sqly", ImportPath: "github.com/nao1215/sqly", Version: &goutil.Version{Current: "v0.12.0"}},
+	}
+	if code := installFromConfig(pkgs, false, false, 1, nil); code != 0 {
+		t.Fatalf("installFromConfig() = %d, want 0", code)
+	}
+
+	want := map[string]string{
+		"github.com/nao1215/gup": "h1:gup=",
+		// The checksum of v1.1.1 can not verify the pinned v1.0.0.
+		"github.com/nao1215/gal/cmd/gal": "",
+		"github.com/nao1215/sqly":        "",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("checksums mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func Test_versionFromConfig_NormalizeDevel(t *testing.T) {
 	t.Parallel()
 
@@ -279,6 +345,14 @@ func Test_versionFromConfig_NormalizeDevel(t *testing.T) {
 			},
 			want: "v1.2.3",
 		},
+		{
+			name: "pinned version",
+			pkg: goutil.Package{
+				Version: &goutil.Version{Current: "v1.2.3"},
+				Pin:     "v1.0.0",
+			},
+			want: "v1.0.0",
+		},
 	}
 
 	for _, tt := range tests {
@@ -353,7 +427,7 @@ func Test_installFromConfig_installError(t *testing.T) {
 		},
 	}
 
-	if got := installFromConfig(pkgs, false, false, 1); got != 1 {
+	if got := installFromConfig(pkgs, false, false, 1, nil); got != 1 {
 		t.Fatalf("installFromConfig() = %d, want 1", got)
 	}
 }
@@ -367,7 +441,7 @@ func Test_installFromConfig_emptyImportPath(t *testing.T) {
 		},
 	}
 
-	if got := installFromConfig(pkgs, false, false, 1); got != 1 {
+	if got := installFromConfig(pkgs, false, false, 1, nil); got != 1 {
 		t.Fatalf("installFromConfig() = %d, want 1", got)
 	}
 }
@@ -390,7 +464,83 @@ func Test_installFromConfig_dryRun(t *testing.T) {
 		},
 	}
 
-	if got := installFromConfig(pkgs, true, false, 1); got != 0 {
+	if got := installFromConfig(pkgs, true, false, 1, nil); got != 0 {
 		t.Fatalf("installFromConfig() dry-run = %d, want 0", got)
 	}
 }
+
+func Test_unlistedBinaries(t *testing.T) {
+	confPkgs := []goutil.Package{{Name: "gal"}}
+	installed := []goutil.Package{{Name: "subaru"}, {Name: "gup"}, {Name: "gal"}, {Name: "posixer"}, {Name: "air"}}
+
+	got := unlistedBinaries(confPkgs, installed, []string{"posixer"})
+	if diff := cmp.Diff([]string{"air", "subaru"}, got); diff != "" {
+		t.Errorf("unlistedBinaries() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_runImport_sync(t *testing.T) {
+	originalInstaller := installByVersionCtx
+	t.Cleanup(func() {
+		installByVersionCtx = originalInstaller
+	})
+	installByVersionCtx = func(context.Context, string, string) error { return nil }
+
+	setup := func() (gobin, confFile string) {
+		t.Helper()
+		gobin = t.TempDir()
+		t.Setenv("GOBIN", gobin)
+		for _, name := range []string{"gal", "subaru"} {
+			helper_CopyFile(t, filepath.Join("testdata", "check_success", name), filepath.Join(gobin, withExecSuffix(name)))
+		}
+		confFile = filepath.Join(t.TempDir(), "gup.json")
+		pkgs := []goutil.Package{
+			{Name: "gal", ImportPath: "github.com/nao1215/gal/cmd/gal", Version: &goutil.Version{Current: "v1.1.1"}},
+		}
+		var conf bytes.Buffer
+		if err := config.WriteConfFile(&conf, pkgs); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(confFile, conf.Bytes(), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return gobin, confFile
+	}
+
+	t.Run("dry run lists the binaries", func(t *testing.T) {
+		gobin, confFile := setup()
+		got, err := helper_runGup(t, []string{"gup", "import", "-j", "1", "--sync", "--dry-run", "--file", confFile})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Contains(got, "  "+withExecSuffix("subaru")) {
+			t.Errorf("output = %q, want subaru listed", got)
+		}
+		if !fileutil.IsFile(filepath.Join(gobin, withExecSuffix("subaru"))) {
+			t.Error("--dry-run removed subaru")
+		}
+	})
+
+	t.Run("keep protects a binary", func(t *testing.T) {
+		gobin, confFile := setup()
+		if _, err := helper_runGup(t, []string{"gup", "import", "-j", "1", "--sync", "--keep", "subaru", "--file", confFile}); err != nil {
+			t.Fatal(err)
+		}
+		if !fileutil.IsFile(filepath.Join(gobin, withExecSuffix("subaru"))) {
+			t.Error("--sync removed subaru given by --keep")
+		}
+	})
+
+	t.Run("sync removes unlisted binaries", func(t *testing.T) {
+		gobin, confFile := setup()
+		if _, err := helper_runGup(t, []string{"gup", "import", "-j", "1", "--sync", "--file", confFile}); err != nil {
+			t.Fatal(err)
+		}
+		if fileutil.IsFile(filepath.Join(gobin, withExecSuffix("subaru"))) {
+			t.Error("--sync did not remove subaru")
+		}
+		if !fileutil.IsFile(filepath.Join(gobin, withExecSuffix("gal"))) {
+			t.Error("--sync removed gal listed in gup.json")
+		}
+	})
+}
@@ -0,0 +1,328 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/fileutil"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+	"github.com/spf13/cobra"
+)
+
+func newInfoCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "info <binary>",
+		Short: "Show detailed information about a binary under $GOPATH/bin or $GOBIN",
+		Long: `Show detailed information about a binary under $GOPATH/bin or $GOBIN.
+
+info shows the module and import path, version, update channel and pin in
+gup.json, Go version, version control information, build settings, file
+size, modification time and SHA-256 digest of the binary, whether it is a
+devel build or built with replaced modules, and the latest available version.
+--output json or ndjson prints the binary in the same format as the other
+commands, with the details of the binary in the "info" field.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completePathBinaries,
+		Run: func(cmd *cobra.Command, args []string) {
+			OsExit(info(cmd, args))
+		},
+	}
+	addLatestVerCacheFlags(cmd)
+	addOfflineFlag(cmd)
+	addOutputFlag(cmd)
+
+	return cmd
+}
+
+// binaryInfo is what 'gup info' prints as text.
+type binaryInfo struct {
+	Name          string
+	ModulePath    string
+	ImportPath    string
+	Version       string
+	LatestVersion string
+	Channel       string
+	Pin           string
+	GoVersion     string
+	// Error is why the latest version is unknown.
+	Error string
+	binaryDetails
+}
+
+// binaryDetails is the part of binaryInfo that is not in packageReport. It is
+// the "info" field of 'gup info --output json'.
+type binaryDetails struct {
+	Path string `json:"path"`
+	// InConfig reports whether the binary is listed in gup.json.
+	InConfig bool       `json:"in_config"`
+	VCS      *vcsInfo   `json:"vcs,omitempty"`
+	Build    []infoPair `json:"build_settings"`
+	Size     int64      `json:"size"`
+	ModTime  time.Time  `json:"mod_time"`
+	SHA256   string     `json:"sha256"`
+	// Devel reports whether the binary was built from a local checkout ("(devel)").
+	Devel bool `json:"devel"`
+	// Replaced lists the replace directives the binary was built with.
+	Replaced []string `json:"replaced,omitempty"`
+}
+
+// vcsInfo is the version control information recorded by "go build".
+type vcsInfo struct {
+	System   string `json:"system"`
+	Revision string `json:"revision,omitempty"`
+	Time     string `json:"time,omitempty"`
+	Modified bool   `json:"modified"`
+}
+
+// infoPair is a build setting.
+type infoPair struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+func info(cmd *cobra.Command, args []string) int {
+	report, err := openReportWriter(cmd, "info")
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	return report.close(runInfo(cmd, args, report))
+}
+
+func runInfo(cmd *cobra.Command, args []string, report *reportWriter) int {
+	if err := ensureGoCommandAvailable(); err != nil {
+		print.Err(err)
+		return 1
+	}
+
+	verCache, err := latestVerCacheFromFlags(cmd)
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	if err := setupOfflineMode(cmd, verCache); err != nil {
+		print.Err(err)
+		return 1
+	}
+
+	binList, err := getBinaryPathList()
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	p, path, ok := firstPackageOfPaths(filterBinaryPathListByTargets(binList, args))
+	if !ok {
+		print.Err(fmt.Errorf("%s is not installed by 'go install' (or permission incorrect)", args[0]))
+		return 1
+	}
+
+	confPath := config.ResolveImportFilePath("")
+	confPkgs, err := readConfFileIfExists(confPath)
+	if err != nil {
+		print.Warn(fmt.Sprintf("failed to read %s: %s (continuing without config)", confPath, err))
+		confPkgs = []goutil.Package{}
+	}
+
+	bi, err := newBinaryInfo(p, path, confPkgs)
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+
+	result := 0
+	status := statusInstalled
+	if p.ModulePath != "" {
+		ctx, cancel, signals := newSignalCancelContext()
+		defer stopSignalCancelContext(cancel, signals)
+		latest, err := verCache.channelVersion(ctx, p.ModulePath, goutil.UpdateChannel(bi.Channel))
+		switch {
+		case isUnknownOffline(err):
+			bi.Error = strings.TrimSpace(err.Error())
+			status = statusUnknown
+			print.Warn(err)
+		case err != nil:
+			bi.Error = strings.TrimSpace(err.Error())
+			status = statusFailed
+			result = 1
+			print.Err(err)
+		case isNewerVersion(latest, bi.Version):
+			status = statusOutdated
+		default:
+			status = statusUpToDate
+		}
+		bi.LatestVersion = latest
+	}
+	report.add(newInfoReport(p, bi, status))
+
+	if err := printBinaryInfo(bi); err != nil {
+		print.Err(fmt.Errorf("can't print the information: %w", err))
+		return 1
+	}
+	return result
+}
+
+// firstPackageOfPaths returns the first binary in paths that was built by
+// 'go install', together with its path. Binaries whose build information
+// can't be read are skipped, so each path is read on its own to keep the
+// package and its path together.
+func firstPackageOfPaths(paths []string) (goutil.Package, string, bool) {
+	for _, path := range paths {
+		if pkgs := goutil.GetPackageInformation([]string{path}); len(pkgs) != 0 {
+			return pkgs[0], path, true
+		}
+	}
+	return goutil.Package{}, "", false
+}
+
+// newBinaryInfo collects the information of p installed at path, except the latest version.
+func newBinaryInfo(p goutil.Package, path string, confPkgs []goutil.Package) (binaryInfo, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return binaryInfo{}, fmt.Errorf("can't get information of %s: %w", path, err)
+	}
+	digest, err := fileutil.SHA256(path)
+	if err != nil {
+		return binaryInfo{}, fmt.Errorf("can't get information of %s: %w", path, err)
+	}
+
+	pkgs := []goutil.Package{p}
+	channelMap, err := resolveUpdateChannels(pkgs, confPkgs, nil, nil, nil, nil, nil)
+	if err != nil {
+		return binaryInfo{}, err
+	}
+	bi := binaryInfo{
+		Name:       p.Name,
+		ModulePath: p.ModulePath,
+		ImportPath: p.ImportPath,
+		Version:    p.Version.Current,
+		Channel:    string(channelMap[p.Name]),
+		Pin:        resolveVersionRules(pkgs, confPkgs, "", cooldownOption{}, false)[p.Name].pin,
+		GoVersion:  p.GoVersion.Current,
+		binaryDetails: binaryDetails{
+			Path:    path,
+			Build:   []infoPair{},
+			Size:    stat.Size(),
+			ModTime: stat.ModTime(),
+			SHA256:  digest,
+			Devel:   p.Version.Current == "(devel)",
+		},
+	}
+	for _, c := range confPkgs {
+		if normalizeBinaryNameForMatch(c.Name) == normalizeBinaryNameForMatch(p.Name) {
+			bi.InConfig = true
+		}
+	}
+
+	vcs := vcsInfo{}
+	for _, s := range p.Settings {
+		switch s.Key {
+		case "vcs":
+			vcs.System = s.Value
+		case "vcs.revision":
+			vcs.Revision = s.Value
+		case "vcs.time":
+			vcs.Time = s.Value
+		case "vcs.modified":
+			vcs.Modified = s.Value == "true"
+		default:
+			bi.Build = append(bi.Build, infoPair{Key: s.Key, Value: s.Value})
+			continue
+		}
+		bi.VCS = &vcs
+	}
+	for _, d := range p.Deps {
+		if d != nil && d.Replace != nil {
+			bi.Replaced = append(bi.Replaced, d.Path+"@"+d.Version+" "+depReplaceStr(&depReplace{Path: d.Replace.Path, Version: d.Replace.Version}))
+		}
+	}
+	return bi, nil
+}
+
+// newInfoReport returns the report of p described by bi.
+func newInfoReport(p goutil.Package, bi binaryInfo, status packageStatus) packageReport {
+	r := newPackageReport(p, status)
+	r.Channel, r.Pin, r.LatestVersion, r.Error = bi.Channel, bi.Pin, bi.LatestVersion, bi.Error
+	r.Info = &bi.binaryDetails
+	return r
+}
+
+func printBinaryInfo(bi binaryInfo) error {
+	w := tabwriter.NewWriter(print.Stdout, 0, 0, 2, ' ', 0)
+	line := func(key, value string) {
+		fmt.Fprintf(w, "%s\t%s\n", key, value) //nolint:errcheck // flushed below
+	}
+	lines := func(key string, values []string) {
+		if len(values) == 0 {
+			line(key, "-")
+			return
+		}
+		for i, v := range values {
+			if i == 0 {
+				line(key, v)
+			} else {
+				line("", v)
+			}
+		}
+	}
+
+	line("name:", bi.Name)
+	line("path:", bi.Path)
+	line("module path:", orDash(bi.ModulePath))
+	line("import path:", bi.ImportPath)
+	line("version:", bi.Version)
+	line("latest version:", latestVersionStr(bi))
+	if bi.InConfig {
+		line("channel:", bi.Channel+" (gup.json)")
+	} else {
+		line("channel:", bi.Channel+" (not listed in gup.json)")
+	}
+	if bi.Pin != "" {
+		line("pin:", bi.Pin)
+	}
+	line("go version:", bi.GoVersion)
+	if bi.VCS != nil {
+		line("vcs:", bi.VCS.System)
+		line("vcs revision:", orDash(bi.VCS.Revision))
+		line("vcs time:", orDash(bi.VCS.Time))
+		line("vcs modified:", strconv.FormatBool(bi.VCS.Modified))
+	} else {
+		line("vcs:", "-")
+	}
+	build := make([]string, 0, len(bi.Build))
+	for _, s := range bi.Build {
+		build = append(build, s.Key+"="+s.Value)
+	}
+	lines("build settings:", build)
+	line("size:", strconv.FormatInt(bi.Size, 10)+" bytes")
+	line("mtime:", bi.ModTime.Format(time.RFC3339))
+	line("sha256:", bi.SHA256)
+	line("devel build:", yesNo(bi.Devel))
+	lines("replaced:", bi.Replaced)
+	return w.Flush()
+}
+
+func latestVersionStr(bi binaryInfo) string {
+	switch {
+	case bi.LatestVersion == "":
+		return "unknown"
+	case isNewerVersion(bi.LatestVersion, bi.Version):
+		return bi.LatestVersion + " (update available)"
+	case bi.LatestVersion == bi.Version:
+		return bi.LatestVersion + " (up-to-date)"
+	default:
+		return bi.LatestVersion
+	}
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,173 @@
+//nolint:paralleltest // tests mutate global variables and environment variables
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nao1215/gup/internal/goutil"
+)
+
+func TestExecute_Info(t *testing.T) {
+	setupXDGBase(t)
+	gobin := t.TempDir()
+	t.Setenv("GOBIN", gobin)
+	helper_CopyFile(t, filepath.Join("testdata", "check_success", "gal"), filepath.Join(gobin, withExecSuffix("gal")))
+	origGetLatest := getLatestVerCtx
+	defer func() {
+		getLatestVerCtx = origGetLatest
+	}()
+	getLatestVerCtx = func(_ context.Context, _ string) (string, error) { return "v1.2.0", nil }
+
+	got, err := helper_runGup(t, []string{"gup", "info", "gal"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := strings.Join(got, "\n")
+	for _, want := range []string{
+		"name:            gal",
+		"module path:     github.com/nao1215/gal",
+		"import path:     github.com/nao1215/gal/cmd/gal",
+		"version:         v1.1.1",
+		"latest version:  v1.2.0 (update available)",
+		"channel:         latest (not listed in gup.json)",
+		"go version:      go1.18",
+		"build settings:  -compiler=gc",
+		"devel build:     no",
+		"replaced:        -",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+
+	got, err = helper_runGup(t, []string{"gup", "info", "--output", "json", "gal"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc outputDocument
+	if err := json.Unmarshal([]byte(strings.Join(got, "\n")), &doc); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, got)
+	}
+	if doc.SchemaVersion != outputSchemaVersion || doc.Command != "info" || doc.ExitCode != 0 || len(doc.Packages) != 1 {
+		t.Fatalf("output = %+v, want one package of the info command", doc)
+	}
+	stat, err := os.Stat(filepath.Join(gobin, withExecSuffix("gal")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := doc.Packages[0]
+	if r.Name != "gal" || r.LatestVersion != "v1.2.0" || r.Status != statusOutdated || r.Channel != string(goutil.UpdateChannelLatest) {
+		t.Errorf("report = %+v, want outdated gal on the latest channel", r)
+	}
+	if r.Info == nil || r.Info.Size != stat.Size() || len(r.Info.SHA256) != 64 {
+		t.Errorf("info = %+v", r.Info)
+	}
+}
+
+func TestExecute_Info_offline(t *testing.T) {
+	setupXDGBase(t)
+	gobin := t.TempDir()
+	t.Setenv("GOBIN", gobin)
+	t.Setenv("GOMODCACHE", t.TempDir())
+	// --offline sets GOPROXY and GOFLAGS for the go command; restore them after the test.
+	t.Setenv("GOPROXY", os.Getenv("GOPROXY"))
+	t.Setenv("GOFLAGS", os.Getenv("GOFLAGS"))
+	helper_CopyFile(t, filepath.Join("testdata", "check_success", "gal"), filepath.Join(gobin, withExecSuffix("gal")))
+	origGetLatest := getLatestVerCtx
+	defer func() {
+		getLatestVerCtx = origGetLatest
+	}()
+	getLatestVerCtx = func(context.Context, string) (string, error) {
+		t.Fatal("--offline must not look up the latest version over the network")
+		return "", nil
+	}
+
+	got, err := helper_runGup(t, []string{"gup", "info", "--offline", "--output", "json", "gal"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The warning on STDERR comes first; the document is the last line.
+	if len(got) < 2 {
+		t.Fatalf("output = %q, want a JSON document", got)
+	}
+	var doc outputDocument
+	if err := json.Unmarshal([]byte(got[len(got)-2]), &doc); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, got)
+	}
+	// An empty module cache knows no newer versions, which is not a failure.
+	if doc.ExitCode != 0 || len(doc.Packages) != 1 || doc.Packages[0].Status != statusUnknown {
+		t.Errorf("output = %+v, want gal with an unknown latest version and exit code 0", doc)
+	}
+}
+
+func Test_firstPackageOfPaths(t *testing.T) {
+	dir := t.TempDir()
+	notGo := filepath.Join(dir, "a-gal")
+	if err := os.WriteFile(notGo, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	gal := filepath.Join(dir, "gal")
+	helper_CopyFile(t, filepath.Join("testdata", "check_success", "gal"), gal)
+
+	p, path, ok := firstPackageOfPaths([]string{notGo, gal})
+	if !ok {
+		t.Fatal("firstPackageOfPaths() found no package")
+	}
+	if p.Name != "gal" || path != gal {
+		t.Errorf("firstPackageOfPaths() = %s at %s, want gal at %s", p.Name, path, gal)
+	}
+
+	if _, _, ok := firstPackageOfPaths([]string{notGo}); ok {
+		t.Error("firstPackageOfPaths() of a non-Go binary should find no package")
+	}
+}
+
+func Test_newBinaryInfo(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tool")
+	writeTestFile(t, path, "binary")
+
+	p := goutil.Package{
+		Name:       "tool",
+		ImportPath: "example.com/tool/cmd/tool",
+		ModulePath: "example.com/tool",
+		Version:    &goutil.Version{Current: "(devel)"},
+		GoVersion:  &goutil.Version{Current: "go1.22.4"},
+		Settings: []debug.BuildSetting{
+			{Key: "-ldflags", Value: "-s -w"},
+			{Key: "vcs", Value: "git"},
+			{Key: "vcs.revision", Value: "4f2c1a9b8e7d"},
+			{Key: "vcs.time", Value: "2026-03-04T05:06:07Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+		Deps: []*debug.Module{
+			{Path: "golang.org/x/net", Version: "v0.17.0"},
+			{Path: "golang.org/x/sys", Version: "v0.1.0", Replace: &debug.Module{Path: "../sys"}},
+		},
+	}
+	confPkgs := []goutil.Package{{Name: "tool", UpdateChannel: goutil.UpdateChannelMain, Pin: "v1.0.0"}}
+
+	got, err := newBinaryInfo(p, path, confPkgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Channel != string(goutil.UpdateChannelMain) || !got.InConfig || got.Pin != "v1.0.0" || !got.Devel ||
+		got.Size != int64(len("binary")) || got.SHA256 == "" {
+		t.Errorf("newBinaryInfo() = %+v", got)
+	}
+	if diff := cmp.Diff(&vcsInfo{System: "git", Revision: "4f2c1a9b8e7d", Time: "2026-03-04T05:06:07Z", Modified: true}, got.VCS); diff != "" {
+		t.Errorf("vcs mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]infoPair{{Key: "-ldflags", Value: "-s -w"}}, got.Build); diff != "" {
+		t.Errorf("build settings mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"golang.org/x/sys@v0.1.0 => ../sys"}, got.Replaced); diff != "" {
+		t.Errorf("replaced mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -11,7 +11,7 @@ import (
 )
 
 func newListCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:               "list",
 		Short:             "List up command name with package path and version under $GOPATH/bin or $GOBIN",
 		Long:              `List up command name with package path and version under $GOPATH/bin or $GOBIN`,
@@ -21,9 +21,21 @@ func newListCmd() *cobra.Command {
 			OsExit(list(cmd, args))
 		},
 	}
+	addOutputFlag(cmd)
+
+	return cmd
+}
+
+func list(cmd *cobra.Command, args []string) int {
+	report, err := openReportWriter(cmd, "list")
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	return report.close(runList(cmd, args, report))
 }
 
-func list(_ *cobra.Command, _ []string) int {
+func runList(_ *cobra.Command, _ []string, report *reportWriter) int {
 	if err := ensureGoCommandAvailable(); err != nil {
 		print.Err(err)
 		return 1
@@ -40,6 +52,9 @@ func list(_ *cobra.Command, _ []string) int {
 		return 1
 	}
 	printPackageList(pkgs)
+	for _, p := range pkgs {
+		report.add(newPackageReport(p, statusInstalled))
+	}
 
 	return 0
 }
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+)
+
+// maxMajorProbes limits how many successor major versions are looked up
+// for a single module (e.g. /v3, /v4, ... for a module at /v2).
+const maxMajorProbes = 5
+
+// majorVersion is a successor major version of a module.
+type majorVersion struct {
+	modulePath string
+	version    string
+}
+
+// canSwitchMajor reports whether the channel and the rule let a package move
+// to a new major version. Only the latest channel without a patch or minor
+// policy or a pin follows new major versions.
+func canSwitchMajor(channel goutil.UpdateChannel, rule versionRule) bool {
+	return channel == goutil.UpdateChannelLatest && !rule.policy.IsRestricted() && rule.pin == ""
+}
+
+// findNewMajor returns the highest successor major version of modulePath.
+// A module path that can not be resolved ends the search, so ok is false
+// when even the next major version does not exist.
+func findNewMajor(ctx context.Context, modulePath string, verCache *latestVerCache) (majorVersion, bool) {
+	found := majorVersion{}
+	next := modulePath
+	for range maxMajorProbes {
+		candidate, ok := goutil.NextMajorModulePath(next)
+		if !ok {
+			break
+		}
+		ver, err := verCache.probe(ctx, candidate)
+		if err != nil {
+			break
+		}
+		found = majorVersion{modulePath: candidate, version: ver}
+		next = candidate
+	}
+	return found, found.modulePath != ""
+}
+
+// switchMajor returns pkg with its module and import paths moved to newModulePath.
+func switchMajor(pkg goutil.Package, newModulePath string) goutil.Package {
+	pkg.ImportPath = replaceImportPathPrefix(pkg.ImportPath, pkg.ModulePath, newModulePath)
+	pkg.ModulePath = newModulePath
+	return pkg
+}
+
+// importPathChanged reports whether an updated package moved to another
+// import path than the one recorded in gup.json.
+func importPathChanged(confPkgs, updatedPkgs []goutil.Package) bool {
+	recorded := make(map[string]string, len(confPkgs))
+	for _, p := range confPkgs {
+		recorded[normalizeBinaryNameForMatch(p.Name)] = p.ImportPath
+	}
+	for _, p := range updatedPkgs {
+		if importPath, ok := recorded[normalizeBinaryNameForMatch(p.Name)]; ok && importPath != p.ImportPath {
+			return true
+		}
+	}
+	return false
+}
+
+// newMajorPkg is a package that has a new major version under another module path.
+type newMajorPkg struct {
+	name  string
+	major majorVersion
+}
+
+func printNewMajorPkgs(pkgs []newMajorPkg) {
+	for _, p := range pkgs {
+		print.Info(fmt.Sprintf("%s: new major version available: %s@%s (run 'gup update --allow-major %s')",
+			p.name, p.major.modulePath, p.major.version, p.name))
+	}
+}
@@ -0,0 +1,232 @@
+//nolint:paralleltest
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+)
+
+// stubMajorVersions makes getLatestVer resolve only the given module paths.
+func stubMajorVersions(t *testing.T, latest map[string]string) {
+	t.Helper()
+	origGetLatest := getLatestVer
+	t.Cleanup(func() { getLatestVer = origGetLatest })
+	getLatestVer = func(modulePath string) (string, error) {
+		if ver, ok := latest[modulePath]; ok {
+			return ver, nil
+		}
+		return "", errors.New("not found: " + modulePath)
+	}
+}
+
+func Test_findNewMajor(t *testing.T) {
+	stubMajorVersions(t, map[string]string{
+		"example.com/tool/v2": "v2.5.0",
+		"example.com/tool/v3": "v3.1.0",
+		"example.com/tool/v4": "v4.0.0",
+		"example.com/solo":    "v1.0.0",
+	})
+
+	got, ok := findNewMajor(context.Background(), "example.com/tool/v2", newLatestVerCache())
+	if !ok || got != (majorVersion{modulePath: "example.com/tool/v4", version: "v4.0.0"}) {
+		t.Errorf("findNewMajor() = (%+v, %v), want example.com/tool/v4@v4.0.0", got, ok)
+	}
+	if got, ok := findNewMajor(context.Background(), "example.com/solo", newLatestVerCache()); ok {
+		t.Errorf("findNewMajor() = %+v, want no new major version", got)
+	}
+}
+
+func Test_doCheck_newMajorNotProbedByDefault(t *testing.T) {
+	origProbe := probeLatestVerCtx
+	defer func() { probeLatestVerCtx = origProbe }()
+	probed := []string{}
+	probeLatestVerCtx = func(_ context.Context, modulePath string) (string, error) {
+		probed = append(probed, modulePath)
+		return "", goutil.ErrModuleNotFound
+	}
+	stubMajorVersions(t, map[string]string{"example.com/tool/v2": "v2.5.0"})
+
+	orgStdout := print.Stdout
+	defer func() { print.Stdout = orgStdout }()
+	print.Stdout = io.Discard
+
+	pkgs := []goutil.Package{{
+		Name:       "tool",
+		ImportPath: "example.com/tool/v2/cmd/tool",
+		ModulePath: "example.com/tool/v2",
+		Version:    &goutil.Version{Current: "v2.5.0"},
+		GoVersion:  &goutil.Version{Current: "go1.22.4", Latest: "go1.22.4"},
+	}}
+	if got := doCheck(context.Background(), pkgs, checkOptions{cpus: 1, ignoreGoUpdate: true}); got != 0 {
+		t.Fatalf("doCheck() = %v, want 0", got)
+	}
+	if len(probed) != 0 {
+		t.Errorf("doCheck() probed %v without --major or allow_major", probed)
+	}
+}
+
+func Test_findNewMajor_cachesNotFound(t *testing.T) {
+	origProbe := probeLatestVerCtx
+	defer func() { probeLatestVerCtx = origProbe }()
+	calls := 0
+	probeLatestVerCtx = func(_ context.Context, modulePath string) (string, error) {
+		calls++
+		return "", fmt.Errorf("can't check %s: %w", modulePath, goutil.ErrModuleNotFound)
+	}
+
+	store := newLatestVerStore(t.TempDir(), time.Hour)
+	for range 2 {
+		// Each run has its own in-memory cache, like separate gup processes.
+		if got, ok := findNewMajor(context.Background(), "example.com/tool", newPersistentLatestVerCache(store, false)); ok {
+			t.Fatalf("findNewMajor() = %+v, want no new major version", got)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("probe calls = %d, want 1 (the not found result is cached)", calls)
+	}
+
+	// --refresh looks the module up again.
+	findNewMajor(context.Background(), "example.com/tool", newPersistentLatestVerCache(store, true))
+	if calls != 2 {
+		t.Errorf("probe calls with refresh = %d, want 2", calls)
+	}
+}
+
+func Test_canSwitchMajor(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel goutil.UpdateChannel
+		rule    versionRule
+		want    bool
+	}{
+		{name: "latest", channel: goutil.UpdateChannelLatest, want: true},
+		{name: "major policy", channel: goutil.UpdateChannelLatest, rule: versionRule{policy: goutil.UpdatePolicyMajor}, want: true},
+		{name: "minor policy", channel: goutil.UpdateChannelLatest, rule: versionRule{policy: goutil.UpdatePolicyMinor}, want: false},
+		{name: "pinned", channel: goutil.UpdateChannelLatest, rule: versionRule{pin: "v2.0.0"}, want: false},
+		{name: "main channel", channel: goutil.UpdateChannelMain, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canSwitchMajor(tt.channel, tt.rule); got != tt.want {
+				t.Errorf("canSwitchMajor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_importPathChanged(t *testing.T) {
+	confPkgs := []goutil.Package{{Name: "tool", ImportPath: "example.com/tool/v2"}}
+	if importPathChanged(confPkgs, []goutil.Package{{Name: "tool", ImportPath: "example.com/tool/v2"}}) {
+		t.Error("importPathChanged() = true, want false for the same import path")
+	}
+	if !importPathChanged(confPkgs, []goutil.Package{{Name: "tool", ImportPath: "example.com/tool/v3"}}) {
+		t.Error("importPathChanged() = false, want true for a new major version")
+	}
+	if importPathChanged(confPkgs, []goutil.Package{{Name: "other", ImportPath: "example.com/other"}}) {
+		t.Error("importPathChanged() = true, want false for packages missing in gup.json")
+	}
+}
+
+func Test_updateWithChannels_allowMajor(t *testing.T) {
+	stubMajorVersions(t, map[string]string{
+		"example.com/tool/v2": "v2.5.0",
+		"example.com/tool/v3": "v3.1.0",
+	})
+	origInstallLatest := installLatest
+	defer func() { installLatest = origInstallLatest }()
+	installed := ""
+	installLatest = func(importPath string) error {
+		installed = importPath
+		return nil
+	}
+
+	newPkgs := func() []goutil.Package {
+		return []goutil.Package{{
+			Name:       "tool",
+			ImportPath: "example.com/tool/v2/cmd/tool",
+			ModulePath: "example.com/tool/v2",
+			Version:    &goutil.Version{Current: "v2.5.0"},
+			GoVersion:  &goutil.Version{Current: "go1.22.4", Latest: "go1.22.4"},
+		}}
+	}
+
+	result, _, _ := updateWithChannels(newPkgs(), updateOptions{cpus: 1, ignoreGoUpdate: true})
+	if result != 0 || installed != "" {
+		t.Fatalf("updateWithChannels() = %d, installed %q; want no update without --allow-major", result, installed)
+	}
+
+	result, succeeded, _ := updateWithChannels(newPkgs(), updateOptions{
+		cpus:           1,
+		ignoreGoUpdate: true,
+		rules:          map[string]versionRule{"tool": {allowMajor: true}},
+	})
+	if result != 0 {
+		t.Fatalf("updateWithChannels() = %d, want 0", result)
+	}
+	if installed != "example.com/tool/v3/cmd/tool" {
+		t.Errorf("installed import path = %q, want example.com/tool/v3/cmd/tool", installed)
+	}
+	if len(succeeded) != 1 || succeeded[0].Name != "tool" || succeeded[0].ModulePath != "example.com/tool/v3" {
+		t.Errorf("succeeded packages = %+v, want tool at example.com/tool/v3", succeeded)
+	}
+}
+
+func Test_doCheck_newMajor(t *testing.T) {
+	stubMajorVersions(t, map[string]string{
+		"example.com/tool/v2": "v2.5.0",
+		"example.com/tool/v3": "v3.1.0",
+	})
+
+	orgStdout := print.Stdout
+	orgStderr := print.Stderr
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	print.Stdout = pw
+	print.Stderr = pw
+
+	pkgs := []goutil.Package{{
+		Name:       "tool",
+		ImportPath: "example.com/tool/v2/cmd/tool",
+		ModulePath: "example.com/tool/v2",
+		Version:    &goutil.Version{Current: "v2.5.0"},
+		GoVersion:  &goutil.Version{Current: "go1.22.4", Latest: "go1.22.4"},
+	}}
+	got := doCheck(context.Background(), pkgs, checkOptions{
+		cpus:           1,
+		ignoreGoUpdate: true,
+		rules:          map[string]versionRule{"tool": {allowMajor: true}},
+	})
+
+	pw.Close()
+	print.Stdout = orgStdout
+	print.Stderr = orgStderr
+
+	buf := bytes.Buffer{}
+	if _, err := io.Copy(&buf, pr); err != nil {
+		t.Fatal(err)
+	}
+	_ = pr.Close()
+
+	if got != 0 {
+		t.Fatalf("doCheck() = %v, want 0", got)
+	}
+	want := "tool: new major version available: example.com/tool/v3@v3.1.0 (run 'gup update --allow-major tool')"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("doCheck() output should contain %q, got:\n%s", want, buf.String())
+	}
+	if strings.Contains(buf.String(), "$ gup update") {
+		t.Errorf("doCheck() should not suggest a plain update, got:\n%s", buf.String())
+	}
+}
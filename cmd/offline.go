@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/spf13/cobra"
+)
+
+// addOfflineFlag registers --offline.
+func addOfflineFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("offline", false, "resolve versions and rebuild binaries only from the local module cache (GOPROXY=off)")
+}
+
+// setupOfflineMode switches verCache and the go command to the module cache
+// when --offline is set.
+func setupOfflineMode(cmd *cobra.Command, verCache *latestVerCache) error {
+	offline, err := getFlagBool(cmd, "offline")
+	if err != nil || !offline {
+		return err
+	}
+	dir := goutil.ModCacheDir()
+	if dir == "" {
+		return errors.New("can not use --offline: the module cache directory ($GOMODCACHE) is unknown")
+	}
+	if err := goutil.EnableOfflineMode(); err != nil {
+		return fmt.Errorf("can not use --offline: %w", err)
+	}
+	verCache.modCacheDir = dir
+	return nil
+}
+
+// isUnknownOffline reports whether err means that the versions of a package
+// are not in the module cache, so newer versions can't be known offline.
+func isUnknownOffline(err error) bool {
+	return errors.Is(err, goutil.ErrNotInModCache)
+}
+
+// unknownOfflineStatusStr describes a package whose newer versions can't be known offline.
+func unknownOfflineStatusStr(p goutil.Package) string {
+	current := "unknown"
+	if p.Version != nil {
+		current = p.Version.Current
+	}
+	return current + ", " + color.YellowString("latest version unknown offline (not in the module cache)")
+}
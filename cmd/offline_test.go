@@ -0,0 +1,130 @@
+//nolint:paralleltest
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+)
+
+// newOfflineVerCache returns a latestVerCache that reads a temporary module
+// cache holding the given versions of each module.
+func newOfflineVerCache(t *testing.T, versions map[string][]string) *latestVerCache {
+	t.Helper()
+	dir := t.TempDir()
+	for modulePath, list := range versions {
+		vdir := filepath.Join(dir, "cache", "download", filepath.FromSlash(modulePath), "@v")
+		if err := os.MkdirAll(vdir, 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(vdir, "list"), []byte(strings.Join(list, "\n")+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := newLatestVerCache()
+	c.modCacheDir = dir
+	return c
+}
+
+func Test_latestVerCache_offline(t *testing.T) {
+	origGetLatest := getLatestVer
+	defer func() { getLatestVer = origGetLatest }()
+	getLatestVer = func(string) (string, error) {
+		t.Fatal("the module proxy must not be queried offline")
+		return "", nil
+	}
+
+	c := newOfflineVerCache(t, map[string][]string{
+		"example.com/tool": {"v1.0.0", "v1.2.0", "v1.3.0-rc.1"},
+	})
+	ctx := context.Background()
+
+	if got, err := c.get(ctx, "example.com/tool"); err != nil || got != "v1.2.0" {
+		t.Errorf("get() = %q, %v, want v1.2.0", got, err)
+	}
+	if got, err := c.channelVersion(ctx, "example.com/tool", goutil.UpdateChannelPrerelease); err != nil || got != "v1.3.0-rc.1" {
+		t.Errorf("channelVersion(prerelease) = %q, %v, want v1.3.0-rc.1", got, err)
+	}
+	if _, err := c.get(ctx, "example.com/missing"); !isUnknownOffline(err) {
+		t.Errorf("get() error = %v, want a module cache miss", err)
+	}
+	for _, channel := range []goutil.UpdateChannel{goutil.UpdateChannelMain, goutil.RefUpdateChannel("dev")} {
+		if _, err := c.channelVersion(ctx, "example.com/tool", channel); !isUnknownOffline(err) {
+			t.Errorf("channelVersion(%s) error = %v, want a module cache miss", channel, err)
+		}
+	}
+}
+
+func Test_updateWithChannels_offline(t *testing.T) {
+	origInstallLatest := installLatest
+	origInstallByVersion := installByVersionUpd
+	defer func() {
+		installLatest = origInstallLatest
+		installByVersionUpd = origInstallByVersion
+	}()
+	installLatest = func(string) error {
+		t.Fatal("@latest must not be installed offline")
+		return nil
+	}
+	installed := map[string]string{}
+	installByVersionUpd = func(importPath, version string) error {
+		installed[importPath] = version
+		return nil
+	}
+
+	orgStdout := print.Stdout
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	print.Stdout = pw
+
+	pkgs := []goutil.Package{
+		{
+			Name:       "tool",
+			ImportPath: "example.com/tool/cmd/tool",
+			ModulePath: "example.com/tool",
+			Version:    &goutil.Version{Current: "v1.0.0"},
+			GoVersion:  &goutil.Version{Current: "go1.22.4", Latest: "go1.22.4"},
+		},
+		{
+			Name:       "other",
+			ImportPath: "example.com/other",
+			ModulePath: "example.com/other",
+			Version:    &goutil.Version{Current: "v0.1.0"},
+			GoVersion:  &goutil.Version{Current: "go1.22.4", Latest: "go1.22.4"},
+		},
+	}
+	result, succeeded, _ := updateWithChannels(pkgs, updateOptions{
+		cpus:     1,
+		verCache: newOfflineVerCache(t, map[string][]string{"example.com/tool": {"v1.0.0", "v1.2.0"}}),
+	})
+
+	pw.Close()
+	print.Stdout = orgStdout
+	buf := bytes.Buffer{}
+	if _, err := io.Copy(&buf, pr); err != nil {
+		t.Fatal(err)
+	}
+	_ = pr.Close()
+
+	if result != 0 {
+		t.Fatalf("updateWithChannels() = %d, want 0", result)
+	}
+	if len(installed) != 1 || installed["example.com/tool/cmd/tool"] != "v1.2.0" {
+		t.Errorf("installed = %v, want only example.com/tool/cmd/tool@v1.2.0", installed)
+	}
+	if len(succeeded) != 1 || succeeded[0].Name != "tool" {
+		t.Errorf("succeeded packages = %+v, want only tool", succeeded)
+	}
+	if !strings.Contains(buf.String(), "example.com/other (v0.1.0, latest version unknown offline") {
+		t.Errorf("updateWithChannels() output should label other as unknown offline, got:\n%s", buf.String())
+	}
+}
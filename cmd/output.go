@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+	"github.com/spf13/cobra"
+)
+
+const (
+	outputFormatText   = "text"
+	outputFormatJSON   = "json"
+	outputFormatNDJSON = "ndjson"
+)
+
+// outputSchemaVersion is the version of the JSON output. It is incremented
+// when a field is removed or changes its meaning; new fields may be added
+// without a new version.
+const outputSchemaVersion = 1
+
+// packageStatus is the result of a command for one binary.
+type packageStatus string
+
+const (
+	// statusInstalled is reported by 'gup list'.
+	statusInstalled packageStatus = "installed"
+	// statusUpToDate means the binary needs no update.
+	statusUpToDate packageStatus = "up-to-date"
+	// statusOutdated is reported by 'gup check' for a binary that 'gup update' would update.
+	statusOutdated packageStatus = "outdated"
+	// statusUpdated means the binary was installed by 'gup update' or 'gup import'.
+	statusUpdated packageStatus = "updated"
+	// statusUnknown means the latest version is not in the module cache (--offline).
+	statusUnknown packageStatus = "unknown"
+	// statusVulnerable is reported by 'gup vuln' for a binary with a known vulnerability.
+	statusVulnerable packageStatus = "vulnerable"
+	// statusFailed means the command failed for the binary. Error holds the reason.
+	statusFailed packageStatus = "failed"
+)
+
+// packageReport is the machine-readable result of a command for one binary.
+type packageReport struct {
+	Name          string        `json:"name"`
+	ImportPath    string        `json:"import_path"`
+	ModulePath    string        `json:"module_path,omitempty"`
+	Channel       string        `json:"channel,omitempty"`
+	Version       string        `json:"version,omitempty"`
+	LatestVersion string        `json:"latest_version,omitempty"`
+	GoVersion     string        `json:"go_version,omitempty"`
+	LatestGo      string        `json:"latest_go_version,omitempty"`
+	Pin           string        `json:"pin,omitempty"`
+	Status        packageStatus `json:"status"`
+	// RenamedFrom is the old binary name when the module path changed.
+	RenamedFrom string `json:"renamed_from,omitempty"`
+	// HeldBack is the newer version skipped by the release cooldown.
+	HeldBack string `json:"held_back,omitempty"`
+	// Vulns are the known vulnerabilities reported by 'gup check --vuln' and 'gup vuln'.
+	Vulns []vulnReport `json:"vulns,omitempty"`
+	// Deps are the module dependencies reported by 'gup deps'.
+	Deps []depModule `json:"deps,omitempty"`
+	// Info is the details of the binary reported by 'gup info'.
+	Info  *binaryDetails `json:"info,omitempty"`
+	Error string         `json:"error,omitempty"`
+}
+
+// vulnReport is a known vulnerability of a binary.
+type vulnReport struct {
+	ID           string `json:"id"`
+	ModulePath   string `json:"module_path"`
+	Version      string `json:"version"`
+	FixedVersion string `json:"fixed_version,omitempty"`
+	// Fix tells whether 'gup update' installs a build without the vulnerability.
+	Fix     vulnFixStatus `json:"fix"`
+	Summary string        `json:"summary,omitempty"`
+}
+
+// newPackageReport returns the report of p with status.
+func newPackageReport(p goutil.Package, status packageStatus) packageReport {
+	r := packageReport{
+		Name:       p.Name,
+		ImportPath: p.ImportPath,
+		ModulePath: p.ModulePath,
+		Channel:    string(p.UpdateChannel),
+		Pin:        p.Pin,
+		Status:     status,
+	}
+	if p.Version != nil {
+		r.Version, r.LatestVersion = p.Version.Current, p.Version.Latest
+	}
+	if p.GoVersion != nil {
+		r.GoVersion, r.LatestGo = p.GoVersion.Current, p.GoVersion.Latest
+	}
+	return r
+}
+
+// reportFromResult returns the report of a result of 'gup check', 'gup update' or 'gup import'.
+// ok is the status of a result without error.
+func reportFromResult(v updateResult, ok packageStatus) packageReport {
+	status := ok
+	switch {
+	case isUnknownOffline(v.err):
+		status = statusUnknown
+	case v.err != nil:
+		status = statusFailed
+	}
+	r := newPackageReport(v.pkg, status)
+	r.RenamedFrom = v.renamedFrom
+	r.HeldBack = v.heldBack
+	r.Vulns = newVulnReports(v.vulns)
+	if v.err != nil {
+		r.Error = strings.TrimSpace(v.err.Error())
+	}
+	return r
+}
+
+// newVulnReports returns the reports of findings, or nil if there are none.
+func newVulnReports(findings []vulnFinding) []vulnReport {
+	var reports []vulnReport
+	for _, f := range findings {
+		reports = append(reports, vulnReport{
+			ID:           f.id,
+			ModulePath:   f.modulePath,
+			Version:      f.version,
+			FixedVersion: f.fixedIn,
+			Fix:          f.fix,
+			Summary:      f.summary,
+		})
+	}
+	return reports
+}
+
+// outputDocument is the output of --output json.
+type outputDocument struct {
+	SchemaVersion int             `json:"schema_version"`
+	Command       string          `json:"command"`
+	ExitCode      int             `json:"exit_code"`
+	Packages      []packageReport `json:"packages"`
+}
+
+// outputRecord is a line of --output ndjson. A "package" record holds the
+// fields of packageReport, and the last "summary" record holds the exit code.
+type outputRecord struct {
+	SchemaVersion int    `json:"schema_version"`
+	Type          string `json:"type"`
+	Command       string `json:"command"`
+	*packageReport
+	ExitCode *int `json:"exit_code,omitempty"`
+}
+
+// reportWriter writes the machine-readable output of a command. While it is
+// open, the human-readable messages on STDOUT are discarded, and messages on
+// STDERR are kept. A nil reportWriter, or one for text output, does nothing.
+type reportWriter struct {
+	format  string
+	command string
+	out     io.Writer
+	enc     *json.Encoder
+	reports []packageReport
+}
+
+// addOutputFlag registers --output.
+func addOutputFlag(cmd *cobra.Command) {
+	cmd.Flags().String("output", outputFormatText, "output format: 'text', 'json' or 'ndjson' (one JSON object per line)")
+	if err := cmd.RegisterFlagCompletionFunc("output", completeOutputFormats); err != nil {
+		panic(err)
+	}
+}
+
+func completeOutputFormats(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	return []string{outputFormatText, outputFormatJSON, outputFormatNDJSON}, cobra.ShellCompDirectiveNoFileComp
+}
+
+// openReportWriter returns the reportWriter for the --output flag of cmd.
+// A command without the flag prints text.
+func openReportWriter(cmd *cobra.Command, command string) (*reportWriter, error) {
+	if cmd == nil || cmd.Flags().Lookup("output") == nil {
+		return &reportWriter{format: outputFormatText, command: command}, nil
+	}
+	format, err := getFlagString(cmd, "output")
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case outputFormatText:
+		return &reportWriter{format: format, command: command}, nil
+	case outputFormatJSON, outputFormatNDJSON:
+	default:
+		return nil, fmt.Errorf("can not parse command line argument (--output): want %s, %s or %s: %s",
+			outputFormatText, outputFormatJSON, outputFormatNDJSON, format)
+	}
+
+	w := &reportWriter{format: format, command: command, out: print.Stdout, reports: []packageReport{}}
+	w.enc = json.NewEncoder(w.out)
+	print.Stdout = io.Discard
+	return w, nil
+}
+
+// enabled reports whether w writes machine-readable output.
+func (w *reportWriter) enabled() bool {
+	return w != nil && w.format != outputFormatText
+}
+
+// add records the result of a binary. With ndjson, it is written at once.
+func (w *reportWriter) add(r packageReport) {
+	if !w.enabled() {
+		return
+	}
+	if w.format == outputFormatNDJSON {
+		w.write(outputRecord{SchemaVersion: outputSchemaVersion, Type: "package", Command: w.command, packageReport: &r})
+		return
+	}
+	w.reports = append(w.reports, r)
+}
+
+// close writes the rest of the output, restores STDOUT and returns exitCode.
+func (w *reportWriter) close(exitCode int) int {
+	if !w.enabled() {
+		return exitCode
+	}
+	print.Stdout = w.out
+	if w.format == outputFormatNDJSON {
+		w.write(outputRecord{SchemaVersion: outputSchemaVersion, Type: "summary", Command: w.command, ExitCode: &exitCode})
+	} else {
+		w.write(outputDocument{SchemaVersion: outputSchemaVersion, Command: w.command, ExitCode: exitCode, Packages: w.reports})
+	}
+	return exitCode
+}
+
+func (w *reportWriter) write(v any) {
+	if err := w.enc.Encode(v); err != nil {
+		print.Err(fmt.Errorf("can't write %s output: %w", w.format, err))
+	}
+}
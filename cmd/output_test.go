@@ -0,0 +1,187 @@
+//nolint:paralleltest // tests mutate global variables and environment variables
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+	"github.com/spf13/cobra"
+)
+
+func newOutputTestCmd(t *testing.T, format string) *cobra.Command {
+	t.Helper()
+	cmd := &cobra.Command{}
+	addOutputFlag(cmd)
+	if err := cmd.Flags().Set("output", format); err != nil {
+		t.Fatal(err)
+	}
+	return cmd
+}
+
+func captureStdout(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	orgStdout := print.Stdout
+	t.Cleanup(func() {
+		print.Stdout = orgStdout
+	})
+	var out bytes.Buffer
+	print.Stdout = &out
+	return &out
+}
+
+func Test_reportWriter_json(t *testing.T) {
+	out := captureStdout(t)
+	w, err := openReportWriter(newOutputTestCmd(t, outputFormatJSON), "check")
+	if err != nil {
+		t.Fatal(err)
+	}
+	print.Info("human-readable message")
+	w.add(packageReport{Name: "gal", ImportPath: "github.com/nao1215/gal/cmd/gal", Status: statusUpToDate})
+	if got := w.close(1); got != 1 {
+		t.Errorf("close() = %d, want 1", got)
+	}
+
+	want := outputDocument{
+		SchemaVersion: outputSchemaVersion,
+		Command:       "check",
+		ExitCode:      1,
+		Packages:      []packageReport{{Name: "gal", ImportPath: "github.com/nao1215/gal/cmd/gal", Status: statusUpToDate}},
+	}
+	var got outputDocument
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("output is not a JSON document: %v\n%s", err, out.String())
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("document mismatch (-want +got):\n%s", diff)
+	}
+	if print.Stdout != out {
+		t.Error("close() does not restore STDOUT")
+	}
+}
+
+func Test_reportWriter_ndjson(t *testing.T) {
+	out := captureStdout(t)
+	w, err := openReportWriter(newOutputTestCmd(t, outputFormatNDJSON), "update")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.add(packageReport{Name: "gal", Status: statusUpdated, RenamedFrom: "gal-old"})
+	w.add(packageReport{Name: "sqly", Status: statusFailed, Error: "sqly: not found"})
+	w.close(1)
+
+	want := []map[string]any{
+		{"schema_version": 1.0, "type": "package", "command": "update", "name": "gal", "import_path": "",
+			"status": "updated", "renamed_from": "gal-old"},
+		{"schema_version": 1.0, "type": "package", "command": "update", "name": "sqly", "import_path": "",
+			"status": "failed", "error": "sqly: not found"},
+		{"schema_version": 1.0, "type": "summary", "command": "update", "exit_code": 1.0},
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	got := make([]map[string]any, 0, len(lines))
+	for _, line := range lines {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("%q is not a JSON object: %v", line, err)
+		}
+		got = append(got, record)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("records mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_openReportWriter(t *testing.T) {
+	if _, err := openReportWriter(newOutputTestCmd(t, "yaml"), "list"); err == nil {
+		t.Error("openReportWriter() accepts an unknown format")
+	}
+
+	out := captureStdout(t)
+	w, err := openReportWriter(newOutputTestCmd(t, outputFormatText), "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.add(packageReport{Name: "gal"})
+	print.Info("text")
+	w.close(0)
+	if got := out.String(); got != "text\n" {
+		t.Errorf("text output = %q, want only the human-readable message", got)
+	}
+
+	var nilWriter *reportWriter
+	nilWriter.add(packageReport{Name: "gal"})
+	if got := nilWriter.close(1); got != 1 {
+		t.Errorf("close() of nil = %d, want 1", got)
+	}
+}
+
+func Test_doCheck_report(t *testing.T) {
+	origGetLatest := getLatestVerCtx
+	defer func() {
+		getLatestVerCtx = origGetLatest
+	}()
+	getLatestVerCtx = func(_ context.Context, modulePath string) (string, error) {
+		if modulePath == "example.com/broken" {
+			return "", errors.New("not found")
+		}
+		return "v1.2.0", nil
+	}
+
+	out := captureStdout(t)
+	w, err := openReportWriter(newOutputTestCmd(t, outputFormatJSON), "check")
+	if err != nil {
+		t.Fatal(err)
+	}
+	goVer := &goutil.Version{Current: "go1.22.4", Latest: "go1.22.4"}
+	pkgs := []goutil.Package{
+		{Name: "gal", ImportPath: "github.com/nao1215/gal/cmd/gal", ModulePath: "github.com/nao1215/gal",
+			Version: &goutil.Version{Current: "v1.1.1"}, GoVersion: goVer},
+		{Name: "sqly", ImportPath: "github.com/nao1215/sqly", ModulePath: "github.com/nao1215/sqly",
+			Version: &goutil.Version{Current: "v1.2.0"}, GoVersion: goVer},
+		{Name: "broken", ImportPath: "example.com/broken", ModulePath: "example.com/broken",
+			Version: &goutil.Version{Current: "v1.0.0"}, GoVersion: goVer},
+	}
+	w.close(doCheck(context.Background(), pkgs, checkOptions{cpus: 1, report: w}))
+
+	var doc outputDocument
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not a JSON document: %v\n%s", err, out.String())
+	}
+	if doc.ExitCode != 1 || len(doc.Packages) != len(pkgs) {
+		t.Fatalf("document = %+v, want exit code 1 and 3 packages", doc)
+	}
+	statuses := map[string]packageStatus{}
+	for _, p := range doc.Packages {
+		statuses[p.Name] = p.Status
+	}
+	want := map[string]packageStatus{"gal": statusOutdated, "sqly": statusUpToDate, "broken": statusFailed}
+	if diff := cmp.Diff(want, statuses); diff != "" {
+		t.Errorf("statuses mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestExecute_List_json(t *testing.T) {
+	gobin := t.TempDir()
+	t.Setenv("GOBIN", gobin)
+	helper_CopyFile(t, filepath.Join("testdata", "check_success", "gal"), filepath.Join(gobin, withExecSuffix("gal")))
+
+	got, err := helper_runGup(t, []string{"gup", "list", "--output", "json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc outputDocument
+	if err := json.Unmarshal([]byte(strings.Join(got, "\n")), &doc); err != nil {
+		t.Fatalf("output is not a JSON document: %v\n%s", err, got)
+	}
+	if doc.Command != "list" || len(doc.Packages) != 1 || doc.Packages[0].Version != "v1.1.1" ||
+		doc.Packages[0].Status != statusInstalled {
+		t.Errorf("document = %+v, want gal v1.1.1", doc)
+	}
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"debug/buildinfo"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/fileutil"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+	"github.com/spf13/cobra"
+)
+
+func newPinCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "pin <name>[@version]",
+		Short: "Freeze a binary at its current or a given version",
+		Long: `Freeze a binary at its current or a given version.
+
+The pinned version is recorded in gup.json. 'gup update' skips a pinned
+binary, or reinstalls the pinned version if the installed version differs,
+and 'gup check' shows it as pinned. If you omit the version, the installed
+version is pinned. Use 'gup unpin' to update the binary again.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completePathBinaries,
+		Run: func(cmd *cobra.Command, args []string) {
+			OsExit(pin(cmd, args))
+		},
+	}
+}
+
+func newUnpinCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "unpin <name>",
+		Short:             "Let 'gup update' update a pinned binary again",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completePathBinaries,
+		Run: func(cmd *cobra.Command, args []string) {
+			OsExit(unpin(cmd, args))
+		},
+	}
+}
+
+func pin(_ *cobra.Command, args []string) int {
+	name, version, _ := strings.Cut(strings.TrimSpace(args[0]), "@")
+	name = withExecSuffix(name)
+	if !isSafeBinaryName(name) {
+		print.Err(fmt.Errorf("invalid command name: %s", args[0]))
+		return 1
+	}
+
+	goBin, err := goutil.GoBin()
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	binPath := filepath.Join(goBin, name)
+	installed := goutil.GetPackageVersion(name)
+	if version == "" {
+		version = installed
+	}
+	version = normalizePinVersion(version)
+	if version == "" || version == "unknown" || version == "(devel)" {
+		print.Err(fmt.Errorf("can't pin %s: the installed version is unknown (specify <name>@<version>)", name))
+		return 1
+	}
+	if err := goutil.ValidatePin(version); err != nil {
+		print.Err(fmt.Errorf("can't pin %s: %w", name, err))
+		return 1
+	}
+
+	err = updateConfigPackage(name, binPath, func(p *goutil.Package) {
+		p.Pin = version
+	})
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	print.Info(fmt.Sprintf("pin %s at %s", name, version))
+	if installed != version {
+		print.Info(fmt.Sprintf("%s is installed at %s; 'gup update %s' installs %s", name, installed, name, version))
+	}
+	return 0
+}
+
+func unpin(_ *cobra.Command, args []string) int {
+	name := withExecSuffix(strings.TrimSpace(args[0]))
+	if !isSafeBinaryName(name) {
+		print.Err(fmt.Errorf("invalid command name: %s", args[0]))
+		return 1
+	}
+
+	confPath := config.ResolveImportFilePath("")
+	confPkgs, err := readConfFileIfExists(confPath)
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	found := false
+	for i, p := range confPkgs {
+		if normalizeBinaryNameForMatch(p.Name) == normalizeBinaryNameForMatch(name) && p.Pin != "" {
+			confPkgs[i].Pin = ""
+			found = true
+		}
+	}
+	if !found {
+		print.Err(fmt.Errorf("%s is not pinned", name))
+		return 1
+	}
+	if err := writeConfigFile(confPath, confPkgs); err != nil {
+		print.Err(fmt.Errorf("failed to update %s: %w", confPath, err))
+		return 1
+	}
+	print.Info("unpin " + name)
+	return 0
+}
+
+// normalizePinVersion adds the "v" prefix that "go install" requires to
+// versions such as "1.4.2".
+func normalizePinVersion(version string) string {
+	version = strings.TrimSpace(version)
+	if version != "" && version[0] >= '0' && version[0] <= '9' {
+		return "v" + version
+	}
+	return version
+}
+
+// updateConfigPackage applies fn to the gup.json entry of the binary.
+// When gup.json or the entry does not exist, the entry is created from the
+// build information of the installed binary.
+func updateConfigPackage(name, binPath string, fn func(p *goutil.Package)) error {
+	confPath := config.ResolveImportFilePath("")
+	confPkgs, err := readConfFileIfExists(confPath)
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", confPath, err)
+	}
+
+	for i, p := range confPkgs {
+		if normalizeBinaryNameForMatch(p.Name) == normalizeBinaryNameForMatch(name) {
+			fn(&confPkgs[i])
+			return writeUpdatedConfig(confPath, confPkgs)
+		}
+	}
+
+	if !fileutil.IsFile(binPath) {
+		return fmt.Errorf("%s is not installed and not found in %s", name, confPath)
+	}
+	info, err := buildinfo.ReadFile(binPath)
+	if err != nil {
+		return fmt.Errorf("can't read build info of %s: %w", binPath, err)
+	}
+	if info.Path == "" || info.Path == "command-line-arguments" {
+		return errors.New(name + " is not installed by 'go install'")
+	}
+	p := goutil.Package{
+		Name:          name,
+		ImportPath:    info.Path,
+		Version:       &goutil.Version{Current: info.Main.Version},
+		UpdateChannel: goutil.UpdateChannelLatest,
+		Sum:           info.Main.Sum,
+	}
+	fn(&p)
+	return writeUpdatedConfig(confPath, append(confPkgs, p))
+}
+
+func writeUpdatedConfig(confPath string, pkgs []goutil.Package) error {
+	if err := writeConfigFile(confPath, pkgs); err != nil {
+		return fmt.Errorf("failed to update %s: %w", confPath, err)
+	}
+	return nil
+}
+
+// pinnedStatusStr describes a pinned package in the results of update and check.
+func pinnedStatusStr(p goutil.Package, reinstalled bool) string {
+	switch {
+	case reinstalled:
+		return color.YellowString(p.Version.Current) + " to " + color.GreenString(p.Pin) + " (pinned)"
+	case p.Version.Current == p.Pin:
+		return "pinned at " + color.GreenString(p.Pin)
+	default:
+		return "pinned at " + color.YellowString(p.Pin) + ", installed: " + color.YellowString(p.Version.Current)
+	}
+}
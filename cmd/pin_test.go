@@ -0,0 +1,201 @@
+//nolint:paralleltest // tests mutate global function variables and environment variables
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+)
+
+func Test_normalizePinVersion(t *testing.T) {
+	tests := map[string]string{
+		"1.4.2":   "v1.4.2",
+		"v1.4.2":  "v1.4.2",
+		" v1.0.0": "v1.0.0",
+		"":        "",
+	}
+	for in, want := range tests {
+		if got := normalizePinVersion(in); got != want {
+			t.Errorf("normalizePinVersion(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestExecute_PinUnpin(t *testing.T) {
+	setupXDGBase(t)
+	name := withExecSuffix("gal")
+	gobin := t.TempDir()
+	t.Setenv("GOBIN", gobin)
+	helper_CopyFile(t, filepath.Join("testdata", "check_success", "gal"), filepath.Join(gobin, name))
+
+	readPin := func() string {
+		t.Helper()
+		confPkgs, err := config.ReadConfFile(config.FilePath())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(confPkgs) != 1 || confPkgs[0].ImportPath != "github.com/nao1215/gal/cmd/gal" {
+			t.Fatalf("gup.json = %+v, want one gal entry", confPkgs)
+		}
+		return confPkgs[0].Pin
+	}
+
+	got, err := helper_runGup(t, []string{"gup", "pin", "gal"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(strings.Join(got, "\n"), "pin "+name+" at v1.1.1") {
+		t.Fatalf("pin output = %q", got)
+	}
+	if pin := readPin(); pin != "v1.1.1" {
+		t.Fatalf("pin = %q, want the installed v1.1.1", pin)
+	}
+
+	if _, err := helper_runGup(t, []string{"gup", "pin", "gal@1.0.0"}); err != nil {
+		t.Fatal(err)
+	}
+	if pin := readPin(); pin != "v1.0.0" {
+		t.Fatalf("pin = %q, want v1.0.0", pin)
+	}
+
+	if _, err := helper_runGup(t, []string{"gup", "unpin", "gal"}); err != nil {
+		t.Fatal(err)
+	}
+	if pin := readPin(); pin != "" {
+		t.Fatalf("pin after unpin = %q, want none", pin)
+	}
+	if got := unpin(newUnpinCmd(), []string{"gal"}); got != 1 {
+		t.Fatalf("unpin() of an unpinned binary = %d, want 1", got)
+	}
+	if got := pin(newPinCmd(), []string{"../gal"}); got != 1 {
+		t.Fatalf("pin() with unsafe name = %d, want 1", got)
+	}
+}
+
+func Test_updateWithChannels_pinned(t *testing.T) {
+	origGetLatest := getLatestVer
+	origInstallLatest := installLatest
+	origInstallByVersion := installByVersionUpd
+	defer func() {
+		getLatestVer = origGetLatest
+		installLatest = origInstallLatest
+		installByVersionUpd = origInstallByVersion
+	}()
+	getLatestVer = func(string) (string, error) {
+		t.Fatal("the latest version of a pinned package must not be looked up")
+		return "", nil
+	}
+	installLatest = func(string) error {
+		t.Fatal("@latest must not be installed for a pinned package")
+		return nil
+	}
+	installed := []string{}
+	installByVersionUpd = func(_ string, version string) error {
+		installed = append(installed, version)
+		return nil
+	}
+
+	pkgs := func(current string) []goutil.Package {
+		return []goutil.Package{{
+			Name:       "gal",
+			ImportPath: "github.com/nao1215/gal/cmd/gal",
+			ModulePath: "github.com/nao1215/gal",
+			Version:    &goutil.Version{Current: current},
+			GoVersion:  &goutil.Version{Current: "go1.22.4", Latest: "go1.23.0"},
+		}}
+	}
+	opts := updateOptions{cpus: 1, rules: map[string]versionRule{"gal": {pin: "v1.1.1"}}}
+
+	if result, _, _ := updateWithChannels(pkgs("v1.1.1"), opts); result != 0 {
+		t.Fatalf("updateWithChannels() = %d, want 0", result)
+	}
+	if len(installed) != 0 {
+		t.Fatalf("installed %v, want nothing for a package at its pinned version", installed)
+	}
+
+	result, succeeded, _ := updateWithChannels(pkgs("v1.2.0"), opts)
+	if result != 0 {
+		t.Fatalf("updateWithChannels() = %d, want 0", result)
+	}
+	if len(installed) != 1 || installed[0] != "v1.1.1" {
+		t.Fatalf("installed %v, want the pinned v1.1.1", installed)
+	}
+	if len(succeeded) != 1 || succeeded[0].Pin != "v1.1.1" || persistedVersion(succeeded[0]) != "v1.1.1" {
+		t.Fatalf("succeeded packages = %+v, want gal pinned at v1.1.1", succeeded)
+	}
+}
+
+func Test_doCheck_pinned(t *testing.T) {
+	origGetLatest := getLatestVer
+	defer func() { getLatestVer = origGetLatest }()
+	getLatestVer = func(string) (string, error) { return "v9.9.9", nil }
+
+	orgStdout := print.Stdout
+	orgStderr := print.Stderr
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	print.Stdout = pw
+	print.Stderr = pw
+
+	pkgs := []goutil.Package{
+		{
+			Name:       "gal",
+			ImportPath: "github.com/nao1215/gal/cmd/gal",
+			ModulePath: "github.com/nao1215/gal",
+			Version:    &goutil.Version{Current: "v1.1.1"},
+			GoVersion:  &goutil.Version{Current: "go1.22.4", Latest: "go1.22.4"},
+		},
+		{
+			Name:       "subaru",
+			ImportPath: "github.com/nao1215/subaru",
+			ModulePath: "github.com/nao1215/subaru",
+			Version:    &goutil.Version{Current: "v1.0.1"},
+			GoVersion:  &goutil.Version{Current: "go1.22.4", Latest: "go1.22.4"},
+		},
+	}
+	got := doCheck(context.Background(), pkgs, checkOptions{
+		cpus:           1,
+		ignoreGoUpdate: true,
+		rules: map[string]versionRule{
+			"gal":    {pin: "v1.1.1"},
+			"subaru": {pin: "v1.0.0"},
+		},
+	})
+
+	pw.Close()
+	print.Stdout = orgStdout
+	print.Stderr = orgStderr
+
+	buf := bytes.Buffer{}
+	if _, err := io.Copy(&buf, pr); err != nil {
+		t.Fatal(err)
+	}
+	_ = pr.Close()
+
+	if got != 0 {
+		t.Fatalf("doCheck() = %v, want 0", got)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"github.com/nao1215/gal/cmd/gal (pinned at v1.1.1)",
+		"github.com/nao1215/subaru (pinned at v1.0.0, installed: v1.0.1)",
+		"$ gup update subaru ",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("doCheck() output should contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "gup update gal") {
+		t.Errorf("doCheck() should not suggest updating a package at its pinned version, got:\n%s", out)
+	}
+}
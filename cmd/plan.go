@@ -0,0 +1,332 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+	"github.com/spf13/cobra"
+)
+
+const (
+	planFormatTable = "table"
+	planFormatJSON  = "json"
+)
+
+// planAction is what 'gup update' or 'gup import' does to a binary.
+type planAction string
+
+const (
+	planActionNone      planAction = "none"
+	planActionUpdate    planAction = "update"
+	planActionRebuild   planAction = "rebuild"
+	planActionInstall   planAction = "install"
+	planActionReinstall planAction = "reinstall"
+	planActionRemove    planAction = "remove"
+	planActionError     planAction = "error"
+	// planActionUnknown means the latest version is not in the module cache (--offline).
+	planActionUnknown planAction = "unknown"
+)
+
+// planEntry is one binary in the output of --plan.
+type planEntry struct {
+	Name       string               `json:"name"`
+	ImportPath string               `json:"import_path,omitempty"`
+	Current    string               `json:"current_version,omitempty"`
+	Target     string               `json:"target_version,omitempty"`
+	Channel    goutil.UpdateChannel `json:"channel,omitempty"`
+	Pinned     bool                 `json:"pinned,omitempty"`
+	Action     planAction           `json:"action"`
+	// GoCurrent and GoTarget are set when the binary is rebuilt with another Go version.
+	GoCurrent string `json:"go_current,omitempty"`
+	GoTarget  string `json:"go_target,omitempty"`
+	// RenameTo is the new binary name when the module path changes.
+	RenameTo string `json:"rename_to,omitempty"`
+	// HeldBack is the newer version skipped by the release cooldown.
+	HeldBack string `json:"held_back,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// addPlanFlag registers --plan. "--plan" alone prints a table.
+func addPlanFlag(cmd *cobra.Command) {
+	cmd.Flags().String("plan", "", "resolve versions and print what would change without installing anything ('table' or 'json')")
+	cmd.Flags().Lookup("plan").NoOptDefVal = planFormatTable
+	if err := cmd.RegisterFlagCompletionFunc("plan", completePlanFormats); err != nil {
+		panic(err)
+	}
+}
+
+// getFlagPlanFormat returns the --plan value. An empty format means "not set".
+// "--plan json" sets the default format and leaves "json" as an argument, so
+// a format right after "--plan" in the raw command line rawArgs is rejected
+// instead of being taken as a binary name. A binary named "json" or "table"
+// elsewhere in the command line is not affected.
+func getFlagPlanFormat(cmd *cobra.Command, rawArgs []string) (string, error) {
+	format, err := getFlagString(cmd, "plan")
+	if err != nil {
+		return "", err
+	}
+	switch format {
+	case "":
+		return format, nil
+	case planFormatTable, planFormatJSON:
+		if arg := formatAfterPlanFlag(rawArgs); arg != "" {
+			return "", fmt.Errorf("can not parse command line argument (--plan): use --plan=%s instead of --plan %s", arg, arg)
+		}
+		return format, nil
+	default:
+		return "", fmt.Errorf("can not parse command line argument (--plan): want %s or %s: %s", planFormatTable, planFormatJSON, format)
+	}
+}
+
+// formatAfterPlanFlag returns the plan format that immediately follows a bare
+// "--plan" in rawArgs, or "" if there is none. Arguments after "--" are not flags.
+func formatAfterPlanFlag(rawArgs []string) string {
+	for i, arg := range rawArgs {
+		if arg == "--" {
+			return ""
+		}
+		if arg != "--plan" || i+1 == len(rawArgs) {
+			continue
+		}
+		if next := rawArgs[i+1]; next == planFormatTable || next == planFormatJSON {
+			return next
+		}
+	}
+	return ""
+}
+
+func completePlanFormats(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	return []string{planFormatTable, planFormatJSON}, cobra.ShellCompDirectiveNoFileComp
+}
+
+// planUpdates resolves the versions that 'gup update' installs, without installing anything.
+func planUpdates(pkgs []goutil.Package, opts updateOptions) []planEntry {
+	verCache := opts.verCache
+	if verCache == nil {
+		verCache = newLatestVerCache()
+	}
+	ctx, cancel, signals := newSignalCancelContext()
+	defer stopSignalCancelContext(cancel, signals)
+
+	planner := func(ctx context.Context, p goutil.Package) updateResult {
+		originalName := p.Name
+		r := resolveUpdate(ctx, p, opts, verCache)
+		r.pkg.UpdateChannel = r.channel
+		v := updateResult{updated: r.shouldUpdate, pkg: r.pkg, err: r.err, heldBack: r.target.heldBack}
+		if r.err == nil && r.modulePathChanged {
+			if newName := binaryNameFromImportPath(r.pkg.ImportPath); newName != originalName {
+				v.pkg.Name = newName
+				v.renamedFrom = originalName
+			}
+		}
+		return v
+	}
+
+	entries := make([]planEntry, 0, len(pkgs))
+	ch := forEachPackage(ctx, pkgs, opts.cpus, planner)
+	for i := 0; i < len(pkgs); i++ {
+		entries = append(entries, updatePlanEntry(<-ch, opts.ignoreGoUpdate))
+	}
+	sortPlanEntries(entries)
+	return entries
+}
+
+func updatePlanEntry(v updateResult, ignoreGoUpdate bool) planEntry {
+	p := v.pkg
+	e := planEntry{
+		Name:       p.Name,
+		ImportPath: p.ImportPath,
+		Channel:    p.UpdateChannel,
+		Pinned:     p.Pin != "",
+		HeldBack:   v.heldBack,
+	}
+	if v.renamedFrom != "" {
+		e.Name, e.RenameTo = v.renamedFrom, p.Name
+	}
+	if p.Version != nil {
+		e.Current, e.Target = p.Version.Current, p.Version.Latest
+	}
+	if v.err != nil {
+		e.Action, e.Error = planActionError, v.err.Error()
+		if isUnknownOffline(v.err) {
+			e.Action = planActionUnknown
+		}
+		return e
+	}
+
+	goRebuild := !ignoreGoUpdate && p.GoVersion != nil && !p.IsGoUpToDate()
+	if goRebuild {
+		e.GoCurrent, e.GoTarget = p.GoVersion.Current, p.GoVersion.Latest
+		if ver, ok := goutil.ToolchainGoVersion(p.Toolchain); ok {
+			e.GoTarget = ver
+		}
+	}
+	switch {
+	case !v.updated:
+		e.Action = planActionNone
+	case goRebuild && e.RenameTo == "" && p.Version != nil && p.ModulePath != "" && p.IsPackageUpToDate():
+		e.Action = planActionRebuild
+	default:
+		e.Action = planActionUpdate
+	}
+	return e
+}
+
+// planImport resolves the versions that 'gup import' installs, without
+// installing anything. With sync, the binaries that 'gup import --sync'
+// removes are added to the plan.
+func planImport(confPkgs, installed []goutil.Package, sync bool, keep []string) []planEntry {
+	verCache := newLatestVerCache()
+	ctx, cancel, signals := newSignalCancelContext()
+	defer stopSignalCancelContext(cancel, signals)
+
+	installedByName := make(map[string]goutil.Package, len(installed))
+	for _, p := range installed {
+		installedByName[normalizeBinaryNameForMatch(p.Name)] = p
+	}
+
+	entries := make([]planEntry, 0, len(confPkgs))
+	for _, p := range confPkgs {
+		e := planEntry{
+			Name:       p.Name,
+			ImportPath: p.ImportPath,
+			Channel:    goutil.NormalizeUpdateChannel(string(p.UpdateChannel)),
+			Pinned:     p.Pin != "",
+			Action:     planActionInstall,
+		}
+		got, isInstalled := installedByName[normalizeBinaryNameForMatch(p.Name)]
+		if isInstalled && got.Version != nil {
+			e.Current = got.Version.Current
+		}
+
+		ver, err := versionFromConfig(p)
+		if err == nil && p.ImportPath == "" {
+			err = fmt.Errorf("%s: import path is empty", p.Name)
+		}
+		if err != nil {
+			e.Action, e.Error = planActionError, err.Error()
+			entries = append(entries, e)
+			continue
+		}
+		// "go install <pkg>@latest" resolves the version of the installed module.
+		if ver == latestKeyword && isInstalled && got.ModulePath != "" && got.ImportPath == p.ImportPath {
+			if latest, err := verCache.channelVersion(ctx, got.ModulePath, goutil.UpdateChannelLatest); err == nil {
+				ver = latest
+			}
+		}
+		e.Target = ver
+
+		if isInstalled {
+			e.Action = planActionUpdate
+			if e.Current == e.Target && got.ImportPath == p.ImportPath {
+				e.Action = planActionReinstall
+			}
+			if got.GoVersion != nil {
+				goTarget := got.GoVersion.Latest
+				if toolchainVer, ok := goutil.ToolchainGoVersion(p.Toolchain); ok {
+					goTarget = toolchainVer
+				}
+				if goTarget != got.GoVersion.Current {
+					e.GoCurrent, e.GoTarget = got.GoVersion.Current, goTarget
+				}
+			}
+		}
+		entries = append(entries, e)
+	}
+
+	if sync {
+		for _, name := range unlistedBinaries(confPkgs, installed, keep) {
+			p := installedByName[normalizeBinaryNameForMatch(name)]
+			e := planEntry{Name: name, ImportPath: p.ImportPath, Action: planActionRemove}
+			if p.Version != nil {
+				e.Current = p.Version.Current
+			}
+			entries = append(entries, e)
+		}
+	}
+	sortPlanEntries(entries)
+	return entries
+}
+
+func sortPlanEntries(entries []planEntry) {
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+}
+
+// printPlan prints entries in format. It returns 1 if any binary could not be resolved.
+func printPlan(entries []planEntry, format string) int {
+	result := 0
+	for _, e := range entries {
+		if e.Action == planActionError {
+			result = 1
+		}
+	}
+
+	if format == planFormatJSON {
+		enc := json.NewEncoder(print.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(struct {
+			Packages []planEntry `json:"packages"`
+		}{Packages: entries}); err != nil {
+			print.Err(fmt.Errorf("can't print the plan: %w", err))
+			return 1
+		}
+		return result
+	}
+
+	w := tabwriter.NewWriter(print.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCURRENT\tTARGET\tCHANNEL\tGO\tACTION\tDETAIL") //nolint:errcheck // flushed below
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", //nolint:errcheck // flushed below
+			e.Name, orDash(e.Current), orDash(e.Target), orDash(string(e.Channel)),
+			planGoStr(e), e.Action, planDetailStr(e))
+	}
+	if err := w.Flush(); err != nil {
+		print.Err(fmt.Errorf("can't print the plan: %w", err))
+		return 1
+	}
+	return result
+}
+
+func planGoStr(e planEntry) string {
+	switch {
+	case e.GoTarget == "":
+		return "-"
+	case e.GoCurrent == "":
+		return e.GoTarget
+	default:
+		return e.GoCurrent + " -> " + e.GoTarget
+	}
+}
+
+func planDetailStr(e planEntry) string {
+	details := []string{}
+	if e.RenameTo != "" {
+		details = append(details, "rename to "+e.RenameTo)
+	}
+	if e.Pinned {
+		details = append(details, "pinned")
+	}
+	if e.HeldBack != "" {
+		details = append(details, e.HeldBack+" held back by cooldown")
+	}
+	if e.Error != "" {
+		details = append(details, e.Error)
+	}
+	if len(details) == 0 {
+		return "-"
+	}
+	return strings.Join(details, ", ")
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
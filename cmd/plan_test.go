@@ -0,0 +1,205 @@
+//nolint:paralleltest // tests mutate global function variables
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+)
+
+func Test_planUpdates(t *testing.T) {
+	origGetLatest := getLatestVer
+	origInstallLatest := installLatest
+	origInstallByVersion := installByVersionUpd
+	defer func() {
+		getLatestVer = origGetLatest
+		installLatest = origInstallLatest
+		installByVersionUpd = origInstallByVersion
+	}()
+	latest := map[string]string{
+		"github.com/nao1215/gal":     "v1.2.0",
+		"github.com/nao1215/sqly":    "v0.12.0",
+		"github.com/nao1215/posixer": "v0.1.0",
+	}
+	getLatestVer = func(modulePath string) (string, error) {
+		if ver, ok := latest[modulePath]; ok {
+			return ver, nil
+		}
+		return "", errors.New("module not found")
+	}
+	installLatest = func(string) error {
+		t.Fatal("--plan must not install anything")
+		return nil
+	}
+	installByVersionUpd = func(string, string) error {
+		t.Fatal("--plan must not install anything")
+		return nil
+	}
+
+	goVer := func(current string) *goutil.Version {
+		return &goutil.Version{Current: current, Latest: "go1.22.4"}
+	}
+	pkgs := []goutil.Package{
+		{Name: "gal", ImportPath: "github.com/nao1215/gal/cmd/gal", ModulePath: "github.com/nao1215/gal",
+			Version: &goutil.Version{Current: "v1.1.1"}, GoVersion: goVer("go1.22.4")},
+		{Name: "sqly", ImportPath: "github.com/nao1215/sqly", ModulePath: "github.com/nao1215/sqly",
+			Version: &goutil.Version{Current: "v0.12.0"}, GoVersion: goVer("go1.21.0")},
+		{Name: "posixer", ImportPath: "github.com/nao1215/posixer", ModulePath: "github.com/nao1215/posixer",
+			Version: &goutil.Version{Current: "v0.1.0"}, GoVersion: goVer("go1.22.4")},
+		{Name: "tool", ImportPath: "example.com/tool", ModulePath: "example.com/tool",
+			Version: &goutil.Version{Current: "v0.9.0"}, GoVersion: goVer("go1.22.4")},
+		{Name: "broken", ImportPath: "example.com/broken", ModulePath: "example.com/broken",
+			Version: &goutil.Version{Current: "v1.0.0"}, GoVersion: goVer("go1.22.4")},
+	}
+	got := planUpdates(pkgs, updateOptions{
+		cpus:  2,
+		rules: map[string]versionRule{"tool": {pin: "v1.0.0"}},
+	})
+
+	want := []planEntry{
+		{Name: "broken", ImportPath: "example.com/broken", Current: "v1.0.0", Channel: goutil.UpdateChannelLatest,
+			Action: planActionError, Error: "broken: module not found"},
+		{Name: "gal", ImportPath: "github.com/nao1215/gal/cmd/gal", Current: "v1.1.1", Target: "v1.2.0",
+			Channel: goutil.UpdateChannelLatest, Action: planActionUpdate},
+		{Name: "posixer", ImportPath: "github.com/nao1215/posixer", Current: "v0.1.0", Target: "v0.1.0",
+			Channel: goutil.UpdateChannelLatest, Action: planActionNone},
+		{Name: "sqly", ImportPath: "github.com/nao1215/sqly", Current: "v0.12.0", Target: "v0.12.0",
+			Channel: goutil.UpdateChannelLatest, Action: planActionRebuild, GoCurrent: "go1.21.0", GoTarget: "go1.22.4"},
+		{Name: "tool", ImportPath: "example.com/tool", Current: "v0.9.0", Target: "v1.0.0",
+			Channel: goutil.UpdateChannelLatest, Pinned: true, Action: planActionUpdate},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("planUpdates() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_planImport(t *testing.T) {
+	origGetLatest := getLatestVer
+	defer func() {
+		getLatestVer = origGetLatest
+	}()
+	getLatestVer = func(string) (string, error) { return "v0.3.0", nil }
+
+	confPkgs := []goutil.Package{
+		{Name: "gal", ImportPath: "github.com/nao1215/gal/cmd/gal", Version: &goutil.Version{Current: "v1.1.1"}},
+		{Name: "sqly", ImportPath: "github.com/nao1215/sqly", Version: &goutil.Version{Current: "v0.12.0"}, Toolchain: "go1.22.4"},
+		{Name: "mimixbox", ImportPath: "github.com/nao1215/mimixbox/cmd/mimixbox", Version: &goutil.Version{Current: "v0.33.0"}},
+		{Name: "tool", ImportPath: "example.com/tool", Version: &goutil.Version{Current: "(devel)"}},
+		{Name: "empty", ImportPath: "example.com/empty", Version: &goutil.Version{Current: ""}},
+	}
+	installed := []goutil.Package{
+		{Name: "gal", ImportPath: "github.com/nao1215/gal/cmd/gal", ModulePath: "github.com/nao1215/gal",
+			Version: &goutil.Version{Current: "v1.1.1"}, GoVersion: &goutil.Version{Current: "go1.23.0", Latest: "go1.23.0"}},
+		{Name: "sqly", ImportPath: "github.com/nao1215/sqly", ModulePath: "github.com/nao1215/sqly",
+			Version: &goutil.Version{Current: "v0.11.0"}, GoVersion: &goutil.Version{Current: "go1.23.0", Latest: "go1.23.0"}},
+		{Name: "tool", ImportPath: "example.com/tool", ModulePath: "example.com/tool",
+			Version: &goutil.Version{Current: "v0.2.0"}},
+		{Name: "posixer", ImportPath: "github.com/nao1215/posixer", Version: &goutil.Version{Current: "v0.1.0"}},
+		{Name: "gup", ImportPath: "github.com/nao1215/gup", Version: &goutil.Version{Current: "v1.0.0"}},
+	}
+
+	got := planImport(confPkgs, installed, true, nil)
+	want := []planEntry{
+		{Name: "empty", ImportPath: "example.com/empty", Channel: goutil.UpdateChannelLatest, Action: planActionError,
+			Error: "version is empty in gup.json"},
+		{Name: "gal", ImportPath: "github.com/nao1215/gal/cmd/gal", Current: "v1.1.1", Target: "v1.1.1",
+			Channel: goutil.UpdateChannelLatest, Action: planActionReinstall},
+		{Name: "mimixbox", ImportPath: "github.com/nao1215/mimixbox/cmd/mimixbox", Target: "v0.33.0",
+			Channel: goutil.UpdateChannelLatest, Action: planActionInstall},
+		{Name: "posixer", ImportPath: "github.com/nao1215/posixer", Current: "v0.1.0", Action: planActionRemove},
+		{Name: "sqly", ImportPath: "github.com/nao1215/sqly", Current: "v0.11.0", Target: "v0.12.0",
+			Channel: goutil.UpdateChannelLatest, Action: planActionUpdate, GoCurrent: "go1.23.0", GoTarget: "go1.22.4"},
+		{Name: "tool", ImportPath: "example.com/tool", Current: "v0.2.0", Target: "v0.3.0",
+			Channel: goutil.UpdateChannelLatest, Action: planActionUpdate},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("planImport() mismatch (-want +got):\n%s", diff)
+	}
+
+	got = planImport(confPkgs, installed, true, []string{"posixer"})
+	for _, e := range got {
+		if e.Action == planActionRemove {
+			t.Errorf("planImport() removes %s given by --keep", e.Name)
+		}
+	}
+}
+
+func Test_printPlan(t *testing.T) {
+	orgStdout := print.Stdout
+	defer func() {
+		print.Stdout = orgStdout
+	}()
+
+	entries := []planEntry{
+		{Name: "gal", ImportPath: "github.com/nao1215/gal/cmd/gal", Current: "v1.1.1", Target: "v1.2.0",
+			Channel: goutil.UpdateChannelLatest, Action: planActionUpdate, GoCurrent: "go1.21.0", GoTarget: "go1.22.4"},
+		{Name: "air", ImportPath: "github.com/air-verse/air", Current: "v1.61.0", Target: "v1.61.1",
+			Channel: goutil.UpdateChannelLatest, Action: planActionUpdate, RenameTo: "air2"},
+	}
+
+	var out bytes.Buffer
+	print.Stdout = &out
+	if got := printPlan(entries, planFormatTable); got != 0 {
+		t.Errorf("printPlan() = %d, want 0", got)
+	}
+	for _, want := range []string{"NAME", "go1.21.0 -> go1.22.4", "rename to air2"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("table = %q, want %q", out.String(), want)
+		}
+	}
+
+	out.Reset()
+	entries = append(entries, planEntry{Name: "broken", Action: planActionError, Error: "broken: not found"})
+	if got := printPlan(entries, planFormatJSON); got != 1 {
+		t.Errorf("printPlan() with an error = %d, want 1", got)
+	}
+	var decoded struct {
+		Packages []planEntry `json:"packages"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("the plan is not JSON: %v\n%s", err, out.String())
+	}
+	if diff := cmp.Diff(entries, decoded.Packages); diff != "" {
+		t.Errorf("JSON plan mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_getFlagPlanFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{name: "not set", args: []string{"gal"}, want: ""},
+		{name: "plan alone", args: []string{"--plan", "gal"}, want: planFormatTable},
+		{name: "plan with value", args: []string{"--plan=json", "gal"}, want: planFormatJSON},
+		{name: "format as separate argument", args: []string{"--plan", "json"}, wantErr: true},
+		{name: "table as separate argument", args: []string{"gal", "--plan", "table"}, wantErr: true},
+		{name: "unknown format", args: []string{"--plan=yaml"}, wantErr: true},
+		{name: "binary named json", args: []string{"json", "--plan"}, want: planFormatTable},
+		{name: "binary named table with value", args: []string{"--plan=json", "table"}, want: planFormatJSON},
+		{name: "binary named json after separator", args: []string{"--plan", "--", "json"}, want: planFormatTable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := newUpdateCmd()
+			if err := cmd.ParseFlags(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			got, err := getFlagPlanFormat(cmd, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getFlagPlanFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("getFlagPlanFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
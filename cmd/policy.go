@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+	"github.com/spf13/cobra"
+)
+
+// versionRule restricts the version a package is updated to.
+type versionRule struct {
+	// policy limits how far the package is updated.
+	policy goutil.UpdatePolicy
+	// cooldown skips versions published more recently than this.
+	cooldown time.Duration
+	// pin is the version the package is frozen at. It wins over the other rules.
+	pin string
+	// allowMajor lets the package move to the module path of a new major version.
+	allowMajor bool
+}
+
+// targetVersion is the version selected for an update.
+type targetVersion struct {
+	// version is the version to install. Empty means "install by the channel".
+	version string
+	// heldBack is the newer version skipped by the release cooldown.
+	heldBack string
+}
+
+// addUpdatePolicyFlag registers --policy, which overrides the policies in gup.json.
+func addUpdatePolicyFlag(cmd *cobra.Command) {
+	cmd.Flags().String("policy", "", "limit updates to 'patch', 'minor' or 'major' version bumps (overrides gup.json)")
+	if err := cmd.RegisterFlagCompletionFunc("policy", completeUpdatePolicies); err != nil {
+		panic(err)
+	}
+}
+
+// getFlagUpdatePolicy returns the --policy value. An empty policy means "not set".
+func getFlagUpdatePolicy(cmd *cobra.Command) (goutil.UpdatePolicy, error) {
+	raw, err := getFlagString(cmd, "policy")
+	if err != nil {
+		return "", err
+	}
+	policy, err := goutil.ParseUpdatePolicy(raw)
+	if err != nil {
+		return "", fmt.Errorf("can not parse command line argument (--policy): %w", err)
+	}
+	return policy, nil
+}
+
+func completeUpdatePolicies(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	return []string{
+		string(goutil.UpdatePolicyPatch),
+		string(goutil.UpdatePolicyMinor),
+		string(goutil.UpdatePolicyMajor),
+	}, cobra.ShellCompDirectiveNoFileComp
+}
+
+// resolveVersionRules returns the version rule of each package.
+// The --policy flag wins over the policies saved in gup.json, and the cooldown
+// is resolved by cooldownOption.days.
+// allowMajor applies to every package, in addition to allow_major in gup.json.
+func resolveVersionRules(pkgs, confPkgs []goutil.Package, policy goutil.UpdatePolicy, cooldown cooldownOption, allowMajor bool) map[string]versionRule {
+	rules := make(map[string]versionRule, len(pkgs))
+	normalizedToActual := make(map[string]string, len(pkgs))
+	for _, p := range pkgs {
+		rules[p.Name] = versionRule{policy: policy, cooldown: cooldownDuration(cooldown.days(nil)), allowMajor: allowMajor}
+		normalizedToActual[normalizeBinaryNameForMatch(p.Name)] = p.Name
+	}
+	for _, p := range confPkgs {
+		actual, ok := normalizedToActual[normalizeBinaryNameForMatch(p.Name)]
+		if !ok {
+			continue
+		}
+		rule := rules[actual]
+		if policy == "" {
+			rule.policy = p.Policy
+		}
+		rule.cooldown = cooldownDuration(cooldown.days(p.CooldownDays))
+		rule.pin = p.Pin
+		rule.allowMajor = rule.allowMajor || p.AllowMajor
+		rules[actual] = rule
+	}
+	return rules
+}
+
+// warnIgnoredVersionRules warns about packages whose policy or cooldown_days in
+// gup.json has no effect because their update channel is not latest: the
+// prerelease, main, master and ref channels install the version of the channel as is.
+// Pinned packages are skipped, because the pin wins over the channel.
+func warnIgnoredVersionRules(confPkgs []goutil.Package, channelMap map[string]goutil.UpdateChannel) {
+	channels := make(map[string]goutil.UpdateChannel, len(channelMap))
+	for name, channel := range channelMap {
+		channels[normalizeBinaryNameForMatch(name)] = channel
+	}
+	for _, p := range confPkgs {
+		channel, ok := channels[normalizeBinaryNameForMatch(p.Name)]
+		if !ok || channel == goutil.UpdateChannelLatest || p.Pin != "" {
+			continue
+		}
+		if p.Policy.IsRestricted() {
+			print.Warn(fmt.Sprintf("%s: policy %q in gup.json is ignored on the %s channel", p.Name, p.Policy, channel))
+		}
+		if p.CooldownDays != nil && *p.CooldownDays > 0 {
+			print.Warn(fmt.Sprintf("%s: cooldown_days %d in gup.json is ignored on the %s channel", p.Name, *p.CooldownDays, channel))
+		}
+	}
+}
+
+// resolveTargetVersion returns the version that p should be updated to.
+// latest is the version "go install <module>@latest" selects. With the latest
+// channel, a patch or minor policy picks the highest version that the policy
+// allows, and a cooldown picks the newest of those that is old enough.
+// With the prerelease channel, latest is the highest version and is installed as is.
+func resolveTargetVersion(ctx context.Context, p goutil.Package, channel goutil.UpdateChannel,
+	rule versionRule, latest string, verCache *latestVerCache) (targetVersion, error) {
+	if channel == goutil.UpdateChannelPrerelease {
+		return targetVersion{version: latest}, nil
+	}
+	if channel != goutil.UpdateChannelLatest || p.Version == nil {
+		return targetVersion{}, nil
+	}
+	if !rule.policy.IsRestricted() && rule.cooldown <= 0 {
+		return targetVersion{}, nil
+	}
+	versions, err := verCache.versions(ctx, p.ModulePath)
+	if err != nil {
+		return targetVersion{}, err
+	}
+	// @latest may be a version that is not in the list (e.g. a pseudo-version).
+	versions = append(versions, latest)
+
+	target := latest
+	if rule.policy.IsRestricted() {
+		target = goutil.HighestAllowedVersion(p.Version.Current, versions, rule.policy)
+		if target == "" {
+			// The installed version can not be compared, so the policy does not apply.
+			target = latest
+		}
+	}
+	if rule.cooldown <= 0 || target == p.Version.Current {
+		return targetVersion{version: target}, nil
+	}
+	return applyCooldown(ctx, p, rule, target, versions, verCache)
+}
@@ -0,0 +1,226 @@
+//nolint:paralleltest
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+	"github.com/shogo82148/pointer"
+)
+
+func Test_resolveVersionRules(t *testing.T) {
+	pkgs := []goutil.Package{{Name: "tool-a"}, {Name: "tool-b"}}
+	confPkgs := []goutil.Package{{Name: "tool-a", Policy: goutil.UpdatePolicyPatch, CooldownDays: pointer.Ptr(0)}}
+
+	got := resolveVersionRules(pkgs, confPkgs, "", cooldownOption{global: pointer.Ptr(3)}, false)
+	want := map[string]versionRule{
+		"tool-a": {policy: goutil.UpdatePolicyPatch, cooldown: 0},
+		"tool-b": {cooldown: 3 * 24 * time.Hour},
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(versionRule{})); diff != "" {
+		t.Errorf("resolveVersionRules() mismatch (-want +got):\n%s", diff)
+	}
+
+	got = resolveVersionRules(pkgs, confPkgs, goutil.UpdatePolicyMinor, cooldownOption{}, false)
+	if got["tool-a"].policy != goutil.UpdatePolicyMinor || got["tool-b"].policy != goutil.UpdatePolicyMinor {
+		t.Errorf("resolveVersionRules() = %v, want --policy for every package", got)
+	}
+
+	confPkgs = []goutil.Package{{Name: "tool-b", AllowMajor: true}}
+	got = resolveVersionRules(pkgs, confPkgs, "", cooldownOption{}, false)
+	if got["tool-a"].allowMajor || !got["tool-b"].allowMajor {
+		t.Errorf("resolveVersionRules() = %v, want allow_major for tool-b only", got)
+	}
+	got = resolveVersionRules(pkgs, confPkgs, "", cooldownOption{}, true)
+	if !got["tool-a"].allowMajor || !got["tool-b"].allowMajor {
+		t.Errorf("resolveVersionRules() = %v, want --allow-major for every package", got)
+	}
+}
+
+func Test_resolveVersionRules_cooldownPrecedence(t *testing.T) {
+	pkgs := []goutil.Package{{Name: "tool"}}
+	tests := []struct {
+		name     string
+		option   cooldownOption
+		pkgDays  *int
+		wantDays int
+	}{
+		{name: "nothing set", wantDays: 0},
+		{name: "global", option: cooldownOption{global: pointer.Ptr(7)}, wantDays: 7},
+		{name: "package over global", option: cooldownOption{global: pointer.Ptr(7)}, pkgDays: pointer.Ptr(2), wantDays: 2},
+		{name: "package 0 disables global", option: cooldownOption{global: pointer.Ptr(7)}, pkgDays: pointer.Ptr(0), wantDays: 0},
+		{name: "flag over package", option: cooldownOption{flag: pointer.Ptr(1), global: pointer.Ptr(7)}, pkgDays: pointer.Ptr(2), wantDays: 1},
+		{name: "flag 0 over package", option: cooldownOption{flag: pointer.Ptr(0)}, pkgDays: pointer.Ptr(2), wantDays: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			confPkgs := []goutil.Package{{Name: "tool", CooldownDays: tt.pkgDays}}
+			got := resolveVersionRules(pkgs, confPkgs, "", tt.option, false)["tool"].cooldown
+			if want := cooldownDuration(tt.wantDays); got != want {
+				t.Errorf("cooldown = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func Test_getFlagCooldownDays(t *testing.T) {
+	cmd := newCheckCmd()
+	if got, err := getFlagCooldownDays(cmd); err != nil || got != nil {
+		t.Errorf("getFlagCooldownDays() = %v, %v; want nil when the flag is not set", got, err)
+	}
+	if err := cmd.Flags().Set("cooldown-days", "0"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := getFlagCooldownDays(cmd); err != nil || got == nil || *got != 0 {
+		t.Errorf("getFlagCooldownDays() = %v, %v; want 0 when the flag is set to 0", got, err)
+	}
+}
+
+func Test_resolveTargetVersion(t *testing.T) {
+	origGetVersionList := getVersionListCtx
+	defer func() { getVersionListCtx = origGetVersionList }()
+	calls := 0
+	getVersionListCtx = func(context.Context, string) ([]string, error) {
+		calls++
+		return []string{"v1.0.0", "v1.0.1", "v1.1.0"}, nil
+	}
+
+	pkg := goutil.Package{
+		Name:       "tool",
+		ModulePath: "example.com/tool",
+		Version:    &goutil.Version{Current: "v1.0.0"},
+	}
+	tests := []struct {
+		name    string
+		channel goutil.UpdateChannel
+		policy  goutil.UpdatePolicy
+		want    string
+	}{
+		{name: "patch", channel: goutil.UpdateChannelLatest, policy: goutil.UpdatePolicyPatch, want: "v1.0.1"},
+		{name: "minor picks @latest", channel: goutil.UpdateChannelLatest, policy: goutil.UpdatePolicyMinor, want: "v1.2.0"},
+		{name: "no policy", channel: goutil.UpdateChannelLatest, policy: "", want: ""},
+		{name: "main channel", channel: goutil.UpdateChannelMain, policy: goutil.UpdatePolicyPatch, want: ""},
+		{name: "prerelease channel", channel: goutil.UpdateChannelPrerelease, policy: goutil.UpdatePolicyPatch, want: "v1.2.0"},
+	}
+	verCache := newLatestVerCache()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveTargetVersion(context.Background(), pkg, tt.channel, versionRule{policy: tt.policy}, "v1.2.0", verCache)
+			if err != nil {
+				t.Fatalf("resolveTargetVersion() error = %v", err)
+			}
+			if got.version != tt.want {
+				t.Errorf("resolveTargetVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if calls != 1 {
+		t.Errorf("version list fetched %d times, want 1", calls)
+	}
+
+	getVersionListCtx = func(context.Context, string) ([]string, error) { return nil, errors.New("boom") }
+	if _, err := resolveTargetVersion(context.Background(), pkg, goutil.UpdateChannelLatest,
+		versionRule{policy: goutil.UpdatePolicyPatch}, "v1.2.0", newLatestVerCache()); err == nil {
+		t.Error("resolveTargetVersion() should return the version list error")
+	}
+}
+
+func Test_updateWithChannels_policyInstallsAllowedVersion(t *testing.T) {
+	origGetLatest := getLatestVer
+	origGetVersionList := getVersionListCtx
+	origInstallLatest := installLatest
+	origInstallByVersion := installByVersionUpd
+	defer func() {
+		getLatestVer = origGetLatest
+		getVersionListCtx = origGetVersionList
+		installLatest = origInstallLatest
+		installByVersionUpd = origInstallByVersion
+	}()
+	getLatestVer = func(string) (string, error) { return "v2.0.0+incompatible", nil }
+	getVersionListCtx = func(context.Context, string) ([]string, error) {
+		return []string{"v1.1.1", "v1.1.2", "v1.2.0", "v2.0.0+incompatible"}, nil
+	}
+	installLatest = func(string) error {
+		t.Fatal("@latest must not be installed under a patch policy")
+		return nil
+	}
+	installed := ""
+	installByVersionUpd = func(_ string, version string) error {
+		installed = version
+		return nil
+	}
+
+	pkgs := []goutil.Package{{
+		Name:       "gal",
+		ImportPath: "github.com/nao1215/gal/cmd/gal",
+		ModulePath: "github.com/nao1215/gal",
+		Version:    &goutil.Version{Current: "v1.1.1"},
+		GoVersion:  &goutil.Version{Current: "go1.22.4", Latest: "go1.22.4"},
+	}}
+	result, succeeded, _ := updateWithChannels(pkgs, updateOptions{
+		cpus:           1,
+		ignoreGoUpdate: true,
+		rules:          map[string]versionRule{"gal": {policy: goutil.UpdatePolicyPatch}},
+	})
+	if result != 0 {
+		t.Fatalf("updateWithChannels() = %d, want 0", result)
+	}
+	if installed != "v1.1.2" {
+		t.Errorf("installed version = %q, want v1.1.2", installed)
+	}
+	if len(succeeded) != 1 || succeeded[0].Version.Latest != "v1.1.2" {
+		t.Errorf("succeeded packages = %+v, want gal v1.1.2", succeeded)
+	}
+}
+
+func Test_warnIgnoredVersionRules(t *testing.T) {
+	orgStderr := print.Stderr
+	defer func() { print.Stderr = orgStderr }()
+	var out bytes.Buffer
+	print.Stderr = &out
+
+	confPkgs := []goutil.Package{
+		{Name: "on-latest", Policy: goutil.UpdatePolicyPatch},
+		{Name: "on-main", Policy: goutil.UpdatePolicyMinor},
+		{Name: "on-ref", Policy: goutil.UpdatePolicyPatch},
+		{Name: "pinned", Policy: goutil.UpdatePolicyPatch, Pin: "v1.0.0"},
+		{Name: "major-policy", Policy: goutil.UpdatePolicyMajor},
+		{Name: "not-installed", Policy: goutil.UpdatePolicyPatch},
+		{Name: "cooldown-on-latest", CooldownDays: pointer.Ptr(7)},
+		{Name: "cooldown-on-master", CooldownDays: pointer.Ptr(7)},
+		{Name: "no-cooldown-on-master", CooldownDays: pointer.Ptr(0)},
+	}
+	channelMap := map[string]goutil.UpdateChannel{
+		"on-latest":    goutil.UpdateChannelLatest,
+		"on-main":      goutil.UpdateChannelMain,
+		"on-ref":       goutil.RefUpdateChannel("release-1.x"),
+		"pinned":       goutil.UpdateChannelMaster,
+		"major-policy": goutil.UpdateChannelPrerelease,
+
+		"cooldown-on-latest":    goutil.UpdateChannelLatest,
+		"cooldown-on-master":    goutil.UpdateChannelMaster,
+		"no-cooldown-on-master": goutil.UpdateChannelMaster,
+	}
+	warnIgnoredVersionRules(confPkgs, channelMap)
+
+	got := out.String()
+	for _, want := range []string{
+		`on-main: policy "minor" in gup.json is ignored on the main channel`,
+		`on-ref: policy "patch" in gup.json is ignored on the ref:release-1.x channel`,
+		`cooldown-on-master: cooldown_days 7 in gup.json is ignored on the master channel`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("warnings should contain %q, got:\n%s", want, got)
+		}
+	}
+	if n := strings.Count(got, "\n"); n != 3 {
+		t.Errorf("got %d warnings, want 3:\n%s", n, got)
+	}
+}
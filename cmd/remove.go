@@ -63,12 +63,7 @@ func removeLoop(gobin string, force bool, target []string) int {
 	for _, v := range target {
 		orig := v
 		v = strings.TrimSpace(v)
-		// In Windows, $GOEXE is set to the ".exe" extension.
-		// The user-specified command name (arguments) may not have an extension.
-		execSuffix := normalizeExecSuffix(GOOS, os.Getenv("GOEXE"))
-		if GOOS == goosWindows && !hasSuffixFold(v, execSuffix) {
-			v += execSuffix
-		}
+		v = withExecSuffix(v)
 		if !isSafeBinaryName(v) {
 			print.Err(fmt.Errorf("invalid command name: %s", orig))
 			result = 1
@@ -99,6 +94,17 @@ func removeLoop(gobin string, force bool, target []string) int {
 	return result
 }
 
+// withExecSuffix appends $GOEXE to the command name on Windows.
+// In Windows, $GOEXE is set to the ".exe" extension, and the
+// user-specified command name (arguments) may not have an extension.
+func withExecSuffix(name string) string {
+	execSuffix := normalizeExecSuffix(GOOS, os.Getenv("GOEXE"))
+	if GOOS == goosWindows && !hasSuffixFold(name, execSuffix) {
+		return name + execSuffix
+	}
+	return name
+}
+
 func normalizeExecSuffix(goos, goExe string) string {
 	if goos != goosWindows {
 		return goExe
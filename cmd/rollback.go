@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"debug/buildinfo"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/fileutil"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+	"github.com/spf13/cobra"
+)
+
+func newRollbackCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rollback <name> [version]",
+		Short: "Restore a binary replaced by 'gup update' from its backup",
+		Long: `Restore a binary replaced by 'gup update' from its backup.
+
+'gup update' copies each binary to the backup directory
+(default: $XDG_DATA_HOME/gup/backup) before replacing it.
+If you omit the version, rollback restores the newest backup
+whose version differs from the installed binary.
+The restored version is recorded in gup.json if it exists.`,
+		Args:              cobra.RangeArgs(1, 2),
+		ValidArgsFunction: completePathBinaries,
+		Run: func(cmd *cobra.Command, args []string) {
+			OsExit(rollback(cmd, args))
+		},
+	}
+	cmd.Flags().BoolP("list", "l", false, "list the backups of the binary")
+
+	return cmd
+}
+
+func rollback(cmd *cobra.Command, args []string) int {
+	if len(args) == 0 {
+		print.Err("no command name specified")
+		return 1
+	}
+
+	listOnly, err := getFlagBool(cmd, "list")
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+
+	name := withExecSuffix(strings.TrimSpace(args[0]))
+	if !isSafeBinaryName(name) {
+		print.Err(fmt.Errorf("invalid command name: %s", args[0]))
+		return 1
+	}
+
+	store := openBackupStore(defaultBackupKeep, 0)
+	if store == nil {
+		print.Err("backups are disabled")
+		return 1
+	}
+	backups, err := store.list(name)
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	if len(backups) == 0 {
+		print.Err(fmt.Errorf("no backup of %s", name))
+		return 1
+	}
+
+	if listOnly {
+		printBackupList(backups)
+		return 0
+	}
+
+	gobin, err := goutil.GoBin()
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	binPath := filepath.Join(gobin, name)
+
+	wantVersion := ""
+	if len(args) == 2 {
+		wantVersion = strings.TrimSpace(args[1])
+	}
+	target, err := selectBackup(backups, goutil.GetPackageVersion(name), wantVersion)
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+
+	// Keep the binary being replaced so that the rollback itself can be undone.
+	// Rollback never prunes: the retention is set by 'gup update --backup-keep'
+	// and '--backup-max-age', and the next update prunes with those values.
+	if err := store.save(binPath); err != nil {
+		print.Warn(fmt.Sprintf("%s: can't back up the installed binary: %s", name, err))
+	}
+	if err := store.restore(target, binPath); err != nil {
+		print.Err(err)
+		return 1
+	}
+	print.Info(fmt.Sprintf("rollback %s to %s", name, target.version))
+
+	if err := recordRollbackInConfig(name, binPath, target.version); err != nil {
+		print.Warn(err)
+	}
+	return 0
+}
+
+// selectBackup picks the backup of wantVersion, or the newest backup whose
+// version differs from currentVersion when wantVersion is empty.
+func selectBackup(backups []binaryBackup, currentVersion, wantVersion string) (binaryBackup, error) {
+	if wantVersion != "" {
+		for _, b := range backups {
+			if b.version == wantVersion || b.version == "v"+wantVersion {
+				return b, nil
+			}
+		}
+		return binaryBackup{}, fmt.Errorf("no backup of %s %s (run 'gup rollback --list %s')",
+			backups[0].name, wantVersion, backups[0].name)
+	}
+
+	for _, b := range backups {
+		if b.version != currentVersion {
+			return b, nil
+		}
+	}
+	return binaryBackup{}, fmt.Errorf("no backup of %s other than the installed version %s", backups[0].name, currentVersion)
+}
+
+func printBackupList(backups []binaryBackup) {
+	for _, b := range backups {
+		_, _ = fmt.Fprintf(print.Stdout, "%s@%s (backed up at %s)\n",
+			b.name, b.version, b.createdAt.Format(time.RFC3339))
+	}
+}
+
+// recordRollbackInConfig records the restored version of the binary in gup.json.
+// Nothing is written when gup.json does not exist.
+func recordRollbackInConfig(name, binPath, version string) error {
+	confPath := config.ResolveImportFilePath("")
+	if !fileutil.IsFile(confPath) {
+		return nil
+	}
+	confPkgs, err := config.ReadConfFile(confPath)
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", confPath, err)
+	}
+
+	info, err := buildinfo.ReadFile(binPath)
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", confPath, err)
+	}
+
+	found := false
+	for i, p := range confPkgs {
+		if normalizeBinaryNameForMatch(p.Name) != normalizeBinaryNameForMatch(name) {
+			continue
+		}
+		confPkgs[i].ImportPath = info.Path
+		confPkgs[i].Version = &goutil.Version{Current: version}
+		confPkgs[i].Sum = info.Main.Sum
+		found = true
+	}
+	if !found {
+		confPkgs = append(confPkgs, goutil.Package{
+			Name:          name,
+			ImportPath:    info.Path,
+			Version:       &goutil.Version{Current: version},
+			UpdateChannel: goutil.UpdateChannelLatest,
+			Sum:           info.Main.Sum,
+		})
+	}
+
+	if err := writeConfigFile(confPath, confPkgs); err != nil {
+		return fmt.Errorf("failed to update %s: %w", confPath, err)
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+//nolint:paralleltest // tests mutate global function variables and environment variables
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/goutil"
+)
+
+func Test_selectBackup(t *testing.T) {
+	backups := []binaryBackup{
+		{name: "tool", version: "v1.2.0"},
+		{name: "tool", version: "v1.1.0"},
+		{name: "tool", version: "v1.0.0"},
+	}
+
+	tests := []struct {
+		name        string
+		current     string
+		wantVersion string
+		want        string
+		wantErr     bool
+	}{
+		{name: "newest other than installed", current: "v1.2.0", want: "v1.1.0"},
+		{name: "newest when installed is unknown", current: "unknown", want: "v1.2.0"},
+		{name: "explicit version", current: "v1.2.0", wantVersion: "v1.0.0", want: "v1.0.0"},
+		{name: "explicit version without v", current: "v1.2.0", wantVersion: "1.0.0", want: "v1.0.0"},
+		{name: "explicit version not found", current: "v1.2.0", wantVersion: "v0.9.0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectBackup(backups, tt.current, tt.wantVersion)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("selectBackup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.version != tt.want {
+				t.Errorf("selectBackup() = %q, want %q", got.version, tt.want)
+			}
+		})
+	}
+
+	if _, err := selectBackup(backups[:1], "v1.2.0", ""); err == nil {
+		t.Error("selectBackup() error = nil, want error when only the installed version is backed up")
+	}
+}
+
+func TestExecute_Rollback(t *testing.T) {
+	setupXDGBase(t)
+	origDir := backupDirPath
+	defer func() { backupDirPath = origDir }()
+	backupDirPath = config.BackupDirPath
+
+	name := withExecSuffix("gal")
+	gobin := t.TempDir()
+	t.Setenv("GOBIN", gobin)
+	binPath := filepath.Join(gobin, name)
+	helper_CopyFile(t, filepath.Join("testdata", "check_success", "gal"), binPath)
+
+	// An older backup of gal. The content is another binary so that the restore is visible.
+	backupDir := filepath.Join(config.BackupDirPath(), name)
+	if err := os.MkdirAll(backupDir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	oldPath := filepath.Join(backupDir, "v1.0.0")
+	helper_CopyFile(t, filepath.Join("testdata", "check_success", "subaru"), oldPath)
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(oldPath, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(config.DirPath(), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeConfigFile(config.FilePath(), []goutil.Package{{
+		Name:          name,
+		ImportPath:    "github.com/nao1215/gal/cmd/gal",
+		Version:       &goutil.Version{Current: "v1.1.1"},
+		UpdateChannel: goutil.UpdateChannelMain,
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := helper_runGup(t, []string{"gup", "rollback", "--list", "gal"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(strings.Join(got, "\n"), name+"@v1.0.0") {
+		t.Fatalf("rollback --list output = %q, want the v1.0.0 backup", got)
+	}
+
+	got, err = helper_runGup(t, []string{"gup", "rollback", "gal"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(strings.Join(got, "\n"), "rollback "+name+" to v1.0.0") {
+		t.Fatalf("rollback output = %q", got)
+	}
+
+	restored, err := os.ReadFile(binPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(filepath.Join("testdata", "check_success", "subaru"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(restored, want) {
+		t.Fatal("installed binary was not restored from the backup")
+	}
+
+	// The replaced binary is kept so that the rollback can be undone.
+	backups, err := newBackupStore(config.BackupDirPath(), defaultBackupKeep, 0).list(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 || backups[0].version != "v1.1.1" {
+		t.Fatalf("backups after rollback = %+v, want the replaced v1.1.1 binary", backups)
+	}
+
+	confPkgs, err := config.ReadConfFile(config.FilePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(confPkgs) != 1 || confPkgs[0].Version.Current != "v1.0.0" || confPkgs[0].UpdateChannel != goutil.UpdateChannelMain {
+		t.Fatalf("gup.json after rollback = %+v, want v1.0.0 on the main channel", confPkgs[0])
+	}
+	if confPkgs[0].ImportPath != "github.com/nao1215/subaru" {
+		t.Fatalf("gup.json import path = %s, want the restored binary's path", confPkgs[0].ImportPath)
+	}
+}
+
+func TestExecute_Rollback_doesNotPrune(t *testing.T) {
+	setupXDGBase(t)
+	origDir := backupDirPath
+	defer func() { backupDirPath = origDir }()
+	backupDirPath = config.BackupDirPath
+
+	name := withExecSuffix("gal")
+	gobin := t.TempDir()
+	t.Setenv("GOBIN", gobin)
+	helper_CopyFile(t, filepath.Join("testdata", "check_success", "gal"), filepath.Join(gobin, name))
+
+	// More backups than the default keep count, as left by 'gup update --backup-keep 10'.
+	backupDir := filepath.Join(config.BackupDirPath(), name)
+	if err := os.MkdirAll(backupDir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	for i, ver := range []string{"v1.0.0", "v1.0.1", "v1.0.2", "v1.0.3", "v1.0.4"} {
+		path := filepath.Join(backupDir, ver)
+		helper_CopyFile(t, filepath.Join("testdata", "check_success", "subaru"), path)
+		created := time.Now().Add(-time.Duration(10-i) * time.Hour)
+		if err := os.Chtimes(path, created, created); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := rollback(newRollbackCmd(), []string{"gal", "v1.0.0"}); got != 0 {
+		t.Fatalf("rollback() = %d, want 0", got)
+	}
+	backups, err := newBackupStore(config.BackupDirPath(), defaultBackupKeep, 0).list(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The five backups and the replaced v1.1.1 binary.
+	if len(backups) != 6 {
+		t.Fatalf("rollback pruned backups: got %d backups, want 6", len(backups))
+	}
+}
+
+func TestExecute_Rollback_noBackup(t *testing.T) {
+	setupXDGBase(t)
+	origDir := backupDirPath
+	defer func() { backupDirPath = origDir }()
+	backupDirPath = config.BackupDirPath
+	t.Setenv("GOBIN", t.TempDir())
+
+	cmd := newRollbackCmd()
+	if got := rollback(cmd, []string{"gal"}); got != 1 {
+		t.Fatalf("rollback() = %d, want 1", got)
+	}
+	if got := rollback(cmd, []string{"../gal"}); got != 1 {
+		t.Fatalf("rollback() with unsafe name = %d, want 1", got)
+	}
+}
@@ -37,14 +37,23 @@ If you find gup useful, please consider sponsoring the project:
 	cmd.SilenceUsage = true
 	cmd.SilenceErrors = true
 
+	cmd.AddCommand(newBundleCmd())
 	cmd.AddCommand(newCheckCmd())
 	cmd.AddCommand(newCompletionCmd())
+	cmd.AddCommand(newDepsCmd())
 	cmd.AddCommand(newExportCmd())
 	cmd.AddCommand(newImportCmd())
+	cmd.AddCommand(newInfoCmd())
 	cmd.AddCommand(newListCmd())
+	cmd.AddCommand(newPinCmd())
 	cmd.AddCommand(newRemoveCmd())
+	cmd.AddCommand(newRollbackCmd())
+	cmd.AddCommand(newSBOMCmd())
+	cmd.AddCommand(newUnpinCmd())
 	cmd.AddCommand(newUpdateCmd())
+	cmd.AddCommand(newVerifyCmd())
 	cmd.AddCommand(newVersionCmd())
+	cmd.AddCommand(newVulnCmd())
 	cmd.AddCommand(newBugReportCmd())
 
 	if !completion.IsWindows() {
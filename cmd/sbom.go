@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/nao1215/gup/internal/cmdinfo"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+	"github.com/nao1215/gup/internal/sbom"
+	"github.com/spf13/cobra"
+)
+
+// sbomNow returns the creation time of SBOM documents.
+var sbomNow = time.Now //nolint:gochecknoglobals // swapped in tests
+
+func newSBOMCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sbom [binary ...]",
+		Short: "Print a software bill of materials of the binaries under $GOPATH/bin or $GOBIN",
+		Long: `Print a software bill of materials (SBOM) of the binaries under $GOPATH/bin or $GOBIN.
+
+sbom prints one CycloneDX or SPDX JSON document to STDOUT. Each binary is
+a component with its main module, version, module checksum, Go version and
+build settings, and depends on a component of each module it is built with.
+Everything is read from the build information embedded in the binaries.`,
+		ValidArgsFunction: completePathBinaries,
+		Run: func(cmd *cobra.Command, args []string) {
+			OsExit(generateSBOM(cmd, args))
+		},
+	}
+	cmd.Flags().String("format", string(sbom.CycloneDX), "SBOM format: 'cyclonedx' or 'spdx'")
+	if err := cmd.RegisterFlagCompletionFunc("format", completeSBOMFormats); err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func completeSBOMFormats(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	formats := []string{}
+	for _, f := range sbom.Formats() {
+		formats = append(formats, string(f))
+	}
+	return formats, cobra.ShellCompDirectiveNoFileComp
+}
+
+// getFlagSBOMFormat returns the --format value.
+func getFlagSBOMFormat(cmd *cobra.Command) (sbom.Format, error) {
+	raw, err := getFlagString(cmd, "format")
+	if err != nil {
+		return "", err
+	}
+	format := sbom.Format(strings.ToLower(strings.TrimSpace(raw)))
+	for _, f := range sbom.Formats() {
+		if format == f {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("can not parse command line argument (--format): want %s or %s: %s", sbom.CycloneDX, sbom.SPDX, raw)
+}
+
+func generateSBOM(cmd *cobra.Command, args []string) int {
+	if err := ensureGoCommandAvailable(); err != nil {
+		print.Err(err)
+		return 1
+	}
+
+	format, err := getFlagSBOMFormat(cmd)
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+
+	pkgs, err := getPackageInfoByTargets(args)
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	pkgs = extractUserSpecifyPkg(pkgs, args)
+	if len(pkgs) == 0 {
+		print.Err("unable to generate SBOM: no package information")
+		return 1
+	}
+
+	bins := make([]sbom.Binary, 0, len(pkgs))
+	for _, p := range pkgs {
+		bins = append(bins, sbomBinary(p))
+	}
+	if err := sbom.Write(print.Stdout, format, bins, sbom.Options{
+		ToolName:    cmdinfo.Name,
+		ToolVersion: cmdinfo.GetVersionNumber(),
+		Created:     sbomNow(),
+	}); err != nil {
+		print.Err(fmt.Errorf("can't write SBOM: %w", err))
+		return 1
+	}
+	return 0
+}
+
+// sbomBinary returns the SBOM description of p.
+func sbomBinary(p goutil.Package) sbom.Binary {
+	b := sbom.Binary{
+		Name:       p.Name,
+		ImportPath: p.ImportPath,
+		Main:       debug.Module{Path: p.ModulePath, Sum: p.Sum},
+		Settings:   p.Settings,
+		Deps:       p.Deps,
+	}
+	if p.Version != nil {
+		b.Main.Version = p.Version.Current
+	}
+	if p.GoVersion != nil {
+		b.GoVersion = p.GoVersion.Current
+	}
+	return b
+}
@@ -0,0 +1,67 @@
+//nolint:paralleltest // tests mutate global variables and environment variables
+package cmd
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecute_SBOM(t *testing.T) {
+	gobin := t.TempDir()
+	t.Setenv("GOBIN", gobin)
+	helper_CopyFile(t, filepath.Join("testdata", "check_success", "gal"), filepath.Join(gobin, withExecSuffix("gal")))
+	origNow := sbomNow
+	t.Cleanup(func() { sbomNow = origNow })
+	sbomNow = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	got, err := helper_runGup(t, []string{"gup", "sbom"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bom struct {
+		BOMFormat  string `json:"bomFormat"`
+		Components []struct {
+			Type    string `json:"type"`
+			Name    string `json:"name"`
+			Version string `json:"version"`
+			PURL    string `json:"purl"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal([]byte(strings.Join(got, "\n")), &bom); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, got)
+	}
+	if bom.BOMFormat != "CycloneDX" || len(bom.Components) < 2 {
+		t.Fatalf("document = %+v, want a CycloneDX document with gal and its modules", bom)
+	}
+	app := bom.Components[0]
+	if app.Type != "application" || app.Name != "gal" || app.Version != "v1.1.1" ||
+		app.PURL != "pkg:golang/github.com/nao1215/gal@v1.1.1#cmd/gal" {
+		t.Errorf("component = %+v, want gal v1.1.1", app)
+	}
+	for _, c := range bom.Components[1:] {
+		if c.Type != "library" || !strings.HasPrefix(c.PURL, "pkg:golang/") {
+			t.Errorf("component = %+v, want a Go module", c)
+		}
+	}
+
+	got, err = helper_runGup(t, []string{"gup", "sbom", "--format", "SPDX", "gal"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(strings.Join(got, "\n"), `"spdxVersion": "SPDX-2.3"`) {
+		t.Errorf("output = %s, want an SPDX document", got)
+	}
+}
+
+func Test_getFlagSBOMFormat(t *testing.T) {
+	cmd := newSBOMCmd()
+	if err := cmd.Flags().Set("format", "swid"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := getFlagSBOMFormat(cmd); err == nil {
+		t.Error("getFlagSBOMFormat() accepts an unsupported format")
+	}
+}
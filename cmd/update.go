@@ -21,9 +21,14 @@ import (
 
 var (
 	getLatestVerCtx        = goutil.GetLatestVerWithContext        //nolint:gochecknoglobals // swapped in tests
+	probeLatestVerCtx      = goutil.ProbeLatestVerWithContext      //nolint:gochecknoglobals // swapped in tests
+	getVersionListCtx      = goutil.GetVersionListWithContext      //nolint:gochecknoglobals // swapped in tests
+	getVersionTimeCtx      = goutil.GetVersionTimeWithContext      //nolint:gochecknoglobals // swapped in tests
+	resolveRefCtx          = goutil.ResolveRefWithContext          //nolint:gochecknoglobals // swapped in tests
 	installLatestCtx       = goutil.InstallLatestWithContext       //nolint:gochecknoglobals // swapped in tests
 	installMainOrMasterCtx = goutil.InstallMainOrMasterWithContext //nolint:gochecknoglobals // swapped in tests
 	installByVersionUpdCtx = goutil.InstallWithContext             //nolint:gochecknoglobals // swapped in tests
+	getPackageSum          = goutil.GetPackageSum                  //nolint:gochecknoglobals // swapped in tests
 )
 
 const latestKeyword = "latest"
@@ -60,12 +65,27 @@ using the current installed Go toolchain.`,
 	if err := cmd.RegisterFlagCompletionFunc(latestKeyword, completePathBinaries); err != nil {
 		panic(err)
 	}
+	cmd.Flags().StringSlice("prerelease", []string{}, "specify binaries which update to the highest version including prereleases (delimiter: ',')")
+	if err := cmd.RegisterFlagCompletionFunc("prerelease", completePathBinaries); err != nil {
+		panic(err)
+	}
+	cmd.Flags().StringSlice("ref", []string{}, "specify binaries which update by a branch, tag or commit as <name>@<ref> (delimiter: ',')")
 	// cmd.Flags().BoolP("main-all", "M", false, "update all binaries by @main or @master (delimiter: ',')")
 	cmd.Flags().IntP("jobs", "j", runtime.NumCPU(), "Specify the number of CPU cores to use")
 	if err := cmd.RegisterFlagCompletionFunc("jobs", completeNCPUs); err != nil {
 		panic(err)
 	}
 	cmd.Flags().Bool("ignore-go-update", false, "Ignore updates to the Go toolchain")
+	addUpdatePolicyFlag(cmd)
+	addCooldownFlag(cmd)
+	cmd.Flags().Bool("allow-major", false, "switch binaries to the module path of a new major version (e.g. /v2 to /v3)")
+	cmd.Flags().Bool("reset-build-flags", false, "do not reuse the build settings recorded in binaries (gup.json build settings still apply)")
+	addLatestVerCacheFlags(cmd)
+	addOfflineFlag(cmd)
+	addPlanFlag(cmd)
+	addOutputFlag(cmd)
+	cmd.Flags().Int("backup-keep", defaultBackupKeep, "number of backups kept per binary for 'gup rollback' (0 disables backups)")
+	cmd.Flags().Duration("backup-max-age", 0, "remove backups older than this duration (0 keeps them regardless of age)")
 
 	return cmd
 }
@@ -73,6 +93,15 @@ using the current installed Go toolchain.`,
 // gup is main sequence.
 // All errors are handled in this function.
 func gup(cmd *cobra.Command, args []string) int {
+	report, err := openReportWriter(cmd, "update")
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	return report.close(runUpdate(cmd, args, report))
+}
+
+func runUpdate(cmd *cobra.Command, args []string, report *reportWriter) int {
 	if err := ensureGoCommandAvailable(); err != nil {
 		print.Err(err)
 		return 1
@@ -103,6 +132,58 @@ func gup(cmd *cobra.Command, args []string) int {
 		return 1
 	}
 
+	policy, err := getFlagUpdatePolicy(cmd)
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	cooldownDays, err := getFlagCooldownDays(cmd)
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	allowMajor, err := getFlagBool(cmd, "allow-major")
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	resetBuildFlags, err := getFlagBool(cmd, "reset-build-flags")
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+
+	verCache, err := latestVerCacheFromFlags(cmd)
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	if err := setupOfflineMode(cmd, verCache); err != nil {
+		print.Err(err)
+		return 1
+	}
+
+	planFormat, err := getFlagPlanFormat(cmd, os.Args[1:])
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	if planFormat != "" && report.enabled() {
+		print.Err("--plan and --output can not be used together: use --plan=json")
+		return 1
+	}
+
+	backupKeep, err := getFlagInt(cmd, "backup-keep")
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	backupMaxAge, err := getFlagDuration(cmd, "backup-max-age")
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+
 	pkgs, err := getPackageInfoByTargets(args)
 	if err != nil {
 		print.Err(err)
@@ -130,6 +211,16 @@ func gup(cmd *cobra.Command, args []string) int {
 		print.Err(err)
 		return 1
 	}
+	prereleasePkgNames, err := getFlagStringSlice(cmd, "prerelease")
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	refPkgs, err := getFlagStringSlice(cmd, "ref")
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
 
 	pkgs = extractUserSpecifyPkg(pkgs, args)
 	pkgs = excludePkgs(excludePkgList, pkgs)
@@ -141,7 +232,8 @@ func gup(cmd *cobra.Command, args []string) int {
 
 	confReadPath := config.ResolveImportFilePath("")
 	confWritePath := config.FilePath()
-	if fileutil.IsFile(confReadPath) {
+	confExists := fileutil.IsFile(confReadPath)
+	if confExists {
 		confWritePath = confReadPath
 	}
 
@@ -150,16 +242,46 @@ func gup(cmd *cobra.Command, args []string) int {
 		print.Warn(fmt.Sprintf("failed to read %s: %s (continuing without config)", confReadPath, err))
 		confPkgs = []goutil.Package{}
 	}
+	// An unreadable file has been reported above.
+	settings, err := readConfSettingsIfExists(confReadPath)
+	if err != nil {
+		settings = config.Settings{}
+	}
 
-	channelMap, err := resolveUpdateChannels(pkgs, confPkgs, mainPkgNames, masterPkgNames, latestPkgNames)
+	channelMap, err := resolveUpdateChannels(pkgs, confPkgs, mainPkgNames, masterPkgNames, latestPkgNames, prereleasePkgNames, refPkgs)
 	if err != nil {
 		print.Err(err)
 		return 1
 	}
-
-	result, succeededPkgs, renamedPkgs := updateWithChannels(pkgs, dryRun, notify, cpus, ignoreGoUpdate, channelMap)
-
-	if !dryRun && (shouldPersistChannels(mainPkgNames, masterPkgNames, latestPkgNames) || len(renamedPkgs) > 0) {
+	warnIgnoredVersionRules(confPkgs, channelMap)
+
+	pkgs = applyToolchains(pkgs, confPkgs)
+	opts := updateOptions{
+		dryRun:         dryRun,
+		notification:   notify,
+		cpus:           cpus,
+		ignoreGoUpdate: ignoreGoUpdate,
+		channelMap:     channelMap,
+		rules:          resolveVersionRules(pkgs, confPkgs, policy, cooldownOption{flag: cooldownDays, global: settings.CooldownDays}, allowMajor),
+		verCache:       verCache,
+		builds:         resolveBuildSettings(pkgs, confPkgs, resetBuildFlags),
+		report:         report,
+	}
+	if planFormat != "" {
+		return printPlan(planUpdates(pkgs, opts), planFormat)
+	}
+	opts.backups = openBackupStore(backupKeep, backupMaxAge)
+	result, succeededPkgs, renamedPkgs := updateWithChannels(pkgs, opts)
+
+	persist := shouldPersistChannels(mainPkgNames, masterPkgNames, latestPkgNames, prereleasePkgNames, refPkgs) ||
+		len(renamedPkgs) > 0 || importPathChanged(confPkgs, succeededPkgs)
+	if !persist && confExists {
+		// Refresh the version and sum of the binaries listed in gup.json, so
+		// that they follow the installed binaries. Other binaries are not added.
+		succeededPkgs = recordedPackages(confPkgs, succeededPkgs)
+		persist = configRecordChanged(confPkgs, succeededPkgs)
+	}
+	if !dryRun && persist {
 		merged := mergeConfigPackages(confPkgs, succeededPkgs, channelMap, renamedPkgs)
 		if err := writeConfigFile(confWritePath, merged); err != nil {
 			print.Warn("failed to write " + confWritePath + ": " + err.Error())
@@ -206,10 +328,38 @@ type updateResult struct {
 	updated     bool
 	pkg         goutil.Package
 	err         error
-	renamedFrom string // original binary name if renamed during update
+	renamedFrom string       // original binary name if renamed during update
+	heldBack    string       // newer version skipped by the release cooldown
+	newMajor    majorVersion // successor major version found by check
+	outdated    bool         // check found that update would reinstall the binary
+	vulns       []vulnFinding
 }
 
-func updateWithChannels(pkgs []goutil.Package, dryRun, notification bool, cpus int, ignoreGoUpdate bool, channelMap map[string]goutil.UpdateChannel) (int, []goutil.Package, map[string]string) {
+// updateOptions holds the settings of a 'gup update' run.
+type updateOptions struct {
+	dryRun         bool
+	notification   bool
+	cpus           int
+	ignoreGoUpdate bool
+	// channelMap maps binary names to their update channel.
+	channelMap map[string]goutil.UpdateChannel
+	// rules maps binary names to the rules that restrict their target version.
+	rules map[string]versionRule
+	// verCache looks up latest versions. When nil, an in-memory cache is used.
+	verCache *latestVerCache
+	// backups stores replaced binaries. When nil, no backup is taken.
+	backups *backupStore
+	// builds maps binary names to the build settings they are installed with.
+	builds map[string]goutil.BuildSettings
+	// report receives the result of each package. When nil, nothing is reported.
+	report *reportWriter
+}
+
+func updateWithChannels(pkgs []goutil.Package, opts updateOptions) (int, []goutil.Package, map[string]string) {
+	dryRun, notification, cpus, verCache := opts.dryRun, opts.notification, opts.cpus, opts.verCache
+	if verCache == nil {
+		verCache = newLatestVerCache()
+	}
 	result := 0
 	countFmt := "[%" + pkgDigit(pkgs) + "d/%" + pkgDigit(pkgs) + "d]"
 	dryRunManager := goutil.NewGoPaths()
@@ -218,8 +368,6 @@ func updateWithChannels(pkgs []goutil.Package, dryRun, notification bool, cpus i
 	ctx, cancel, signals := newSignalCancelContext()
 	defer stopSignalCancelContext(cancel, signals)
 
-	verCache := newLatestVerCache()
-
 	print.Info("update binary under $GOPATH/bin or $GOBIN")
 	if dryRun {
 		if err := dryRunManager.StartDryRunMode(); err != nil {
@@ -231,63 +379,48 @@ func updateWithChannels(pkgs []goutil.Package, dryRun, notification bool, cpus i
 
 	updater := func(ctx context.Context, p goutil.Package) updateResult {
 		originalName := p.Name
-		// Collect online latest version if possible; else always update
-		shouldUpdate := true
-		modulePathChanged := false
-		if p.ModulePath != "" {
-			ver, err := verCache.get(ctx, p.ModulePath)
-			if err != nil {
-				newPkg, changed := resolveModulePathChange(p, err)
-				if !changed {
-					return updateResult{
-						updated: false,
-						pkg:     p,
-						err:     fmt.Errorf("%s: %w", p.Name, err),
-					}
-				}
-				modulePathChanged = true
-				p = newPkg
-
-				ver, err = verCache.get(ctx, p.ModulePath)
-				if err != nil {
-					return updateResult{
-						updated: false,
-						pkg:     p,
-						err:     fmt.Errorf("%s: %w", p.Name, err),
-					}
-				}
+		r := resolveUpdate(ctx, p, opts, verCache)
+		if r.err != nil {
+			return updateResult{
+				updated: false,
+				pkg:     r.pkg,
+				err:     r.err,
 			}
-			p.Version.Latest = ver
-
-			// Check if we should update the package
-			shouldUpdate = modulePathChanged || !p.IsPackageUpToDate() || (!ignoreGoUpdate && !p.IsGoUpToDate())
 		}
+		p, channel, target, modulePathChanged := r.pkg, r.channel, r.target, r.modulePathChanged
 
-		if !shouldUpdate {
+		if !r.shouldUpdate {
 			return updateResult{
-				updated: false,
-				pkg:     p,
-				err:     nil,
+				updated:  false,
+				pkg:      p,
+				err:      nil,
+				heldBack: target.heldBack,
 			}
 		}
 
 		// Run the update
+		ctx = goutil.WithBuildSettings(ctx, opts.builds[originalName])
 		var updateErr error
 		installedViaRetry := false
 		if p.ImportPath == "" {
 			updateErr = fmt.Errorf("%s is not installed by 'go install' (or permission incorrect)", p.Name)
 		} else {
-			channel := packageUpdateChannel(p.Name, p.UpdateChannel, channelMap)
 			p.UpdateChannel = channel
 
-			if err := installWithSelectedVersion(ctx, p.ImportPath, channel); err != nil {
+			if opts.backups != nil && !dryRun {
+				if err := opts.backups.backupBeforeUpdate(p); err != nil {
+					print.Warn(fmt.Sprintf("%s: rollback will not be possible: %s", p.Name, err))
+				}
+			}
+
+			if err := installPackage(ctx, p.ImportPath, channel, target.version); err != nil {
 				newPkg, changed := resolveModulePathChange(p, err)
 				if !changed {
 					updateErr = fmt.Errorf("%s: %w", p.Name, err)
 				} else {
 					installedViaRetry = true
 					p = newPkg
-					if retryErr := installWithSelectedVersion(ctx, p.ImportPath, channel); retryErr != nil {
+					if retryErr := installPackage(ctx, p.ImportPath, channel, target.version); retryErr != nil {
 						updateErr = fmt.Errorf("%s: %w", originalName, retryErr)
 					} else {
 						newName := binaryNameFromImportPath(p.ImportPath)
@@ -298,6 +431,12 @@ func updateWithChannels(pkgs []goutil.Package, dryRun, notification bool, cpus i
 						p.UpdateChannel = channel
 					}
 				}
+			} else if modulePathChanged {
+				newName := binaryNameFromImportPath(p.ImportPath)
+				if err := removeOldBinaryIfRenamed(originalName, newName); err != nil {
+					updateErr = fmt.Errorf("%s: %w", originalName, err)
+				}
+				p.Name = newName
 			}
 		}
 
@@ -307,6 +446,8 @@ func updateWithChannels(pkgs []goutil.Package, dryRun, notification bool, cpus i
 			if p.UpdateChannel != goutil.UpdateChannelLatest || modulePathChanged || installedViaRetry {
 				p.SetLatestVer()
 			}
+			// The checksum recorded in gup.json must describe the new binary.
+			p.Sum = getPackageSum(p.Name)
 		}
 		var renamed string
 		if updateErr == nil && p.Name != originalName {
@@ -317,6 +458,7 @@ func updateWithChannels(pkgs []goutil.Package, dryRun, notification bool, cpus i
 			pkg:         p,
 			err:         updateErr,
 			renamedFrom: renamed,
+			heldBack:    target.heldBack,
 		}
 	}
 
@@ -325,14 +467,30 @@ func updateWithChannels(pkgs []goutil.Package, dryRun, notification bool, cpus i
 
 	// print result
 	count := 0
+	heldBackPkgs := []heldBackPkg{}
 	for v := range ch {
+		if v.heldBack != "" {
+			heldBackPkgs = append(heldBackPkgs, heldBackPkg{name: v.pkg.Name, heldBack: v.heldBack, target: v.pkg.Version.Latest})
+		}
+		if v.updated {
+			opts.report.add(reportFromResult(v, statusUpdated))
+		} else {
+			opts.report.add(reportFromResult(v, statusUpToDate))
+		}
 		if v.err == nil {
+			status := v.pkg.CurrentToLatestStr()
+			if v.pkg.Pin != "" {
+				status = pinnedStatusStr(v.pkg, v.updated)
+			}
 			print.Info(fmt.Sprintf(countFmt+" %s (%s)",
-				count+1, len(pkgs), v.pkg.ImportPath, v.pkg.CurrentToLatestStr()))
+				count+1, len(pkgs), v.pkg.ImportPath, status))
 			succeededPkgs = append(succeededPkgs, v.pkg)
 			if v.renamedFrom != "" {
 				renamedPkgs[v.renamedFrom] = v.pkg.Name
 			}
+		} else if isUnknownOffline(v.err) {
+			print.Info(fmt.Sprintf(countFmt+" %s (%s)",
+				count+1, len(pkgs), v.pkg.ImportPath, unknownOfflineStatusStr(v.pkg)))
 		} else {
 			result = 1
 			print.Err(fmt.Errorf(countFmt+" %s", count+1, len(pkgs), v.err.Error()))
@@ -343,6 +501,8 @@ func updateWithChannels(pkgs []goutil.Package, dryRun, notification bool, cpus i
 		}
 	}
 
+	printHeldBackPkgs(heldBackPkgs)
+
 	if dryRun {
 		if err := dryRunManager.EndDryRunMode(); err != nil {
 			print.Err(fmt.Errorf("can not change dry run mode to normal mode: %w", err))
@@ -355,6 +515,82 @@ func updateWithChannels(pkgs []goutil.Package, dryRun, notification bool, cpus i
 	return result, succeededPkgs, renamedPkgs
 }
 
+// resolvedUpdate is what 'gup update' does to a package, decided before
+// anything is installed.
+type resolvedUpdate struct {
+	// pkg is the package with its latest version. A module path change is applied to it.
+	pkg     goutil.Package
+	channel goutil.UpdateChannel
+	target  targetVersion
+	// shouldUpdate reports whether the package is reinstalled.
+	shouldUpdate      bool
+	modulePathChanged bool
+	err               error
+}
+
+// resolveUpdate looks up the version that p is updated to, without installing anything.
+func resolveUpdate(ctx context.Context, p goutil.Package, opts updateOptions, verCache *latestVerCache) resolvedUpdate {
+	originalName := p.Name
+	channel := packageUpdateChannel(p.Name, p.UpdateChannel, opts.channelMap)
+	r := resolvedUpdate{channel: channel, shouldUpdate: true}
+	if pin := opts.rules[originalName].pin; pin != "" {
+		// A pinned package is only reinstalled when the installed version drifted.
+		p.Pin = pin
+		p.Version.Latest = pin
+		r.target = targetVersion{version: pin}
+		r.shouldUpdate = p.Version.Current != pin
+		r.pkg = p
+		return r
+	}
+	if p.ModulePath == "" {
+		// Collect online latest version if possible; else always update
+		r.pkg = p
+		return r
+	}
+
+	if rule := opts.rules[originalName]; rule.allowMajor && canSwitchMajor(channel, rule) {
+		if major, ok := findNewMajor(ctx, p.ModulePath, verCache); ok {
+			p = switchMajor(p, major.modulePath)
+			r.modulePathChanged = true
+		}
+	}
+	ver, err := verCache.channelVersion(ctx, p.ModulePath, channel)
+	if err != nil {
+		newPkg, changed := resolveModulePathChange(p, err)
+		if !changed {
+			r.pkg, r.err = p, fmt.Errorf("%s: %w", p.Name, err)
+			return r
+		}
+		r.modulePathChanged = true
+		p = newPkg
+
+		ver, err = verCache.channelVersion(ctx, p.ModulePath, channel)
+		if err != nil {
+			r.pkg, r.err = p, fmt.Errorf("%s: %w", p.Name, err)
+			return r
+		}
+	}
+	p.Version.Latest = ver
+	p.UpdateChannel = channel
+
+	r.target, err = resolveTargetVersion(ctx, p, channel, opts.rules[originalName], ver, verCache)
+	if err != nil {
+		r.pkg, r.err = p, fmt.Errorf("%s: %w", p.Name, err)
+		return r
+	}
+	if r.target.version != "" {
+		p.Version.Latest = r.target.version
+	} else if verCache.offline() {
+		// "go install <pkg>@latest" queries the proxy even if the version is cached.
+		r.target.version = ver
+	}
+
+	// Check if we should update the package
+	r.shouldUpdate = r.modulePathChanged || !p.IsPackageUpToDate() || (!opts.ignoreGoUpdate && !p.IsGoUpToDate())
+	r.pkg = p
+	return r
+}
+
 func desktopNotifyIfNeeded(result int, enable bool) {
 	if enable {
 		if result == 0 {
@@ -365,6 +601,15 @@ func desktopNotifyIfNeeded(result int, enable bool) {
 	}
 }
 
+// installPackage installs importPath at version, or by the channel when version is empty.
+// The prerelease channel always has a version, which resolveTargetVersion selects.
+func installPackage(ctx context.Context, importPath string, channel goutil.UpdateChannel, version string) error {
+	if version != "" {
+		return installByVersionUpdCtx(ctx, importPath, version)
+	}
+	return installWithSelectedVersion(ctx, importPath, channel)
+}
+
 func installWithSelectedVersion(ctx context.Context, importPath string, channel goutil.UpdateChannel) error {
 	switch goutil.NormalizeUpdateChannel(string(channel)) {
 	case goutil.UpdateChannelLatest:
@@ -374,6 +619,9 @@ func installWithSelectedVersion(ctx context.Context, importPath string, channel
 	case goutil.UpdateChannelMaster:
 		return installByVersionUpdCtx(ctx, importPath, "master")
 	default:
+		if ref, ok := channel.Ref(); ok {
+			return installByVersionUpdCtx(ctx, importPath, ref)
+		}
 		return installLatestCtx(ctx, importPath)
 	}
 }
@@ -433,8 +681,14 @@ func binaryNameFromImportPath(importPath string) string {
 	return binaryNameFromImportPathWith(importPath, runtime.GOOS, os.Getenv("GOEXE"))
 }
 
+// binaryNameFromImportPathWith returns the name "go install" gives the binary.
+// Like the go command, it skips a major version suffix: example.com/tool/v2 is
+// installed as "tool".
 func binaryNameFromImportPathWith(importPath, goos, goExe string) string {
 	binName := filepath.Base(importPath)
+	if _, ok := goutil.MajorVersionSuffix(binName); ok && binName != importPath {
+		binName = filepath.Base(filepath.Dir(importPath))
+	}
 	if goos == goosWindows {
 		goExe = strings.TrimSpace(goExe)
 		if goExe == "" {
@@ -465,8 +719,43 @@ func readConfFileIfExists(path string) ([]goutil.Package, error) {
 	return pkgs, nil
 }
 
-func shouldPersistChannels(mainPkgNames, masterPkgNames, latestPkgNames []string) bool {
-	return len(mainPkgNames) > 0 || len(masterPkgNames) > 0 || len(latestPkgNames) > 0
+func shouldPersistChannels(mainPkgNames, masterPkgNames, latestPkgNames, prereleasePkgNames, refPkgs []string) bool {
+	return len(mainPkgNames) > 0 || len(masterPkgNames) > 0 || len(latestPkgNames) > 0 ||
+		len(prereleasePkgNames) > 0 || len(refPkgs) > 0
+}
+
+// recordedPackages returns the packages in pkgs that are listed in confPkgs.
+func recordedPackages(confPkgs, pkgs []goutil.Package) []goutil.Package {
+	recorded := make(map[string]struct{}, len(confPkgs))
+	for _, p := range confPkgs {
+		recorded[normalizeBinaryNameForMatch(p.Name)] = struct{}{}
+	}
+	filtered := make([]goutil.Package, 0, len(pkgs))
+	for _, p := range pkgs {
+		if _, ok := recorded[normalizeBinaryNameForMatch(p.Name)]; ok {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
+
+// configRecordChanged reports whether the version or sum of a package in pkgs
+// differs from the one recorded in confPkgs.
+func configRecordChanged(confPkgs, pkgs []goutil.Package) bool {
+	recorded := make(map[string]goutil.Package, len(confPkgs))
+	for _, p := range confPkgs {
+		recorded[normalizeBinaryNameForMatch(p.Name)] = p
+	}
+	for _, p := range pkgs {
+		conf, ok := recorded[normalizeBinaryNameForMatch(p.Name)]
+		if !ok {
+			continue
+		}
+		if persistedVersion(p) != persistedVersion(conf) || strings.TrimSpace(p.Sum) != strings.TrimSpace(conf.Sum) {
+			return true
+		}
+	}
+	return false
 }
 
 func resolveUpdateChannels(
@@ -475,6 +764,8 @@ func resolveUpdateChannels(
 	mainPkgNames []string,
 	masterPkgNames []string,
 	latestPkgNames []string,
+	prereleasePkgNames []string,
+	refPkgs []string, // <name>@<ref> pairs
 ) (map[string]goutil.UpdateChannel, error) {
 	channelMap := make(map[string]goutil.UpdateChannel, len(pkgs))
 	normalizedToActual := make(map[string]string, len(pkgs))
@@ -520,6 +811,22 @@ func resolveUpdateChannels(
 	if err := apply(latestKeyword, latestPkgNames, goutil.UpdateChannelLatest); err != nil {
 		return nil, err
 	}
+	if err := apply("prerelease", prereleasePkgNames, goutil.UpdateChannelPrerelease); err != nil {
+		return nil, err
+	}
+	for _, raw := range refPkgs {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		name, ref, _ := strings.Cut(raw, "@")
+		channel := goutil.RefUpdateChannel(strings.TrimSpace(ref))
+		if _, ok := channel.Ref(); !ok {
+			return nil, fmt.Errorf("can not parse command line argument (--ref): want <name>@<ref>: %s", raw)
+		}
+		if err := apply("ref", []string{name}, channel); err != nil {
+			return nil, err
+		}
+	}
 	return channelMap, nil
 }
 
@@ -528,17 +835,27 @@ func mergeConfigPackages(confPkgs []goutil.Package, succeededPkgs []goutil.Packa
 	for _, p := range confPkgs {
 		pkgByName[p.Name] = sanitizeConfigPackage(p)
 	}
+	// A renamed binary keeps the settings of its entry under the old name.
+	oldNames := make(map[string]string, len(renamedPkgs))
+	for oldName, newName := range renamedPkgs {
+		oldNames[newName] = oldName
+	}
 	for _, p := range succeededPkgs {
 		if p.Name == "" || p.ImportPath == "" {
 			continue
 		}
+		conf, ok := pkgByName[p.Name]
+		if oldName, renamed := oldNames[p.Name]; renamed && !ok {
+			conf = pkgByName[oldName]
+		}
 		channel := packageUpdateChannel(p.Name, p.UpdateChannel, channelMap)
-		pkgByName[p.Name] = goutil.Package{
+		pkgByName[p.Name] = withConfigSettings(goutil.Package{
 			Name:          p.Name,
 			ImportPath:    p.ImportPath,
 			Version:       &goutil.Version{Current: persistedVersion(p)},
 			UpdateChannel: channel,
-		}
+			Sum:           p.Sum,
+		}, conf)
 	}
 	// Remove stale entries when a binary was renamed during update
 	for oldName := range renamedPkgs {
@@ -575,12 +892,80 @@ func sanitizeConfigPackage(p goutil.Package) goutil.Package {
 		}
 	}
 
-	return goutil.Package{
+	return withConfigSettings(goutil.Package{
 		Name:          strings.TrimSpace(p.Name),
 		ImportPath:    strings.TrimSpace(p.ImportPath),
 		Version:       &goutil.Version{Current: version},
 		UpdateChannel: goutil.NormalizeUpdateChannel(string(p.UpdateChannel)),
+		Sum:           strings.TrimSpace(p.Sum),
+	}, p)
+}
+
+// resolveBuildSettings returns the build settings each package is rebuilt with.
+// The build settings saved in gup.json win over the settings recorded in the
+// binary, which are ignored when reset is true. The toolchain of each package
+// (see applyToolchains) is always kept. Only -ldflags from gup.json keep their
+// -X flags; those recorded in the binary are dropped (see goutil.BuildSettingsFromBuildInfo).
+func resolveBuildSettings(pkgs, confPkgs []goutil.Package, reset bool) map[string]goutil.BuildSettings {
+	builds := make(map[string]goutil.BuildSettings, len(pkgs))
+	normalizedToActual := make(map[string]string, len(pkgs))
+	for _, p := range pkgs {
+		b := goutil.BuildSettings{}
+		if !reset {
+			b = p.Build
+		}
+		b.Toolchain = p.Toolchain
+		builds[p.Name] = b
+		normalizedToActual[normalizeBinaryNameForMatch(p.Name)] = p.Name
+	}
+	for _, p := range confPkgs {
+		if p.Build.IsZero() {
+			continue
+		}
+		if actual, ok := normalizedToActual[normalizeBinaryNameForMatch(p.Name)]; ok {
+			b := p.Build
+			b.Toolchain = builds[actual].Toolchain
+			builds[actual] = b
+		}
+	}
+	return builds
+}
+
+// applyToolchains sets the toolchains saved in gup.json to pkgs. The Go version
+// of a package with a toolchain is compared with that toolchain instead of the
+// installed Go toolchain.
+func applyToolchains(pkgs, confPkgs []goutil.Package) []goutil.Package {
+	toolchains := make(map[string]string, len(confPkgs))
+	for _, p := range confPkgs {
+		if p.Toolchain != "" {
+			toolchains[normalizeBinaryNameForMatch(p.Name)] = p.Toolchain
+		}
+	}
+	for i, p := range pkgs {
+		toolchain, ok := toolchains[normalizeBinaryNameForMatch(p.Name)]
+		if !ok {
+			continue
+		}
+		pkgs[i].Toolchain = toolchain
+		if ver, ok := goutil.ToolchainGoVersion(toolchain); ok && p.GoVersion != nil {
+			pkgs[i].GoVersion.Latest = ver
+		}
 	}
+	return pkgs
+}
+
+// withConfigSettings returns p with the per-package settings of conf.
+// These settings only live in gup.json and can not be read from binaries.
+func withConfigSettings(p, conf goutil.Package) goutil.Package {
+	p.Policy = conf.Policy
+	p.CooldownDays = conf.CooldownDays
+	p.Pin = conf.Pin
+	p.AllowMajor = conf.AllowMajor
+	if !conf.Build.IsZero() {
+		p.Build = conf.Build
+	}
+	p.Toolchain = conf.Toolchain
+	return p
 }
 
 func persistedVersion(p goutil.Package) string {
@@ -21,6 +21,7 @@ import (
 	"github.com/nao1215/gup/internal/config"
 	"github.com/nao1215/gup/internal/goutil"
 	"github.com/nao1215/gup/internal/print"
+	"github.com/shogo82148/pointer"
 	"github.com/spf13/cobra"
 )
 
@@ -43,6 +44,9 @@ func init() {
 	getLatestVerCtx = func(_ context.Context, modulePath string) (string, error) {
 		return getLatestVer(modulePath)
 	}
+	probeLatestVerCtx = func(_ context.Context, modulePath string) (string, error) {
+		return getLatestVer(modulePath)
+	}
 	installLatestCtx = func(_ context.Context, importPath string) error {
 		return installLatest(importPath)
 	}
@@ -52,6 +56,10 @@ func init() {
 	installByVersionUpdCtx = func(_ context.Context, importPath, version string) error {
 		return installByVersionUpd(importPath, version)
 	}
+	// Stubbed lookups must not leak into other tests through the on-disk cache,
+	// and stubbed updates must not back up binaries into the user's data directory.
+	latestVerStoreDir = func() string { return "" }
+	backupDirPath = func() string { return "" }
 }
 
 func Test_gup(t *testing.T) {
@@ -421,6 +429,65 @@ func Test_gup_invalidConfigFile(t *testing.T) {
 	}
 }
 
+func Test_gup_refreshesConfigFile(t *testing.T) {
+	setupXDGBase(t)
+	t.Setenv("GOBIN", filepath.Join("testdata", "check_success"))
+	helper_stubUpdateOps(t)
+	origGetPackageSum := getPackageSum
+	defer func() { getPackageSum = origGetPackageSum }()
+	getPackageSum = func(string) string { return "h1:new=" }
+
+	confPath := config.FilePath()
+	if err := os.MkdirAll(filepath.Dir(confPath), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeConfigFile(confPath, []goutil.Package{{
+		Name:          "gal",
+		ImportPath:    "github.com/nao1215/gal/cmd/gal",
+		Version:       &goutil.Version{Current: "v1.1.1"},
+		UpdateChannel: goutil.UpdateChannelLatest,
+		Sum:           "h1:old=",
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A plain update without channel flags must still refresh the recorded
+	// version and sum, and must not add subaru, which is not listed.
+	if got := gup(newUpdateCmd(), []string{}); got != 0 {
+		t.Fatalf("gup() = %v, want 0", got)
+	}
+	confPkgs, err := config.ReadConfFile(confPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(confPkgs) != 1 || confPkgs[0].Version.Current != testVersionNine || confPkgs[0].Sum != "h1:new=" {
+		t.Fatalf("gup.json after update = %+v, want only gal at %s with the new sum", confPkgs, testVersionNine)
+	}
+}
+
+func Test_configRecordChanged(t *testing.T) {
+	confPkgs := []goutil.Package{
+		{Name: "gal", Version: &goutil.Version{Current: "v1.1.1"}, Sum: "h1:gal="},
+	}
+	tests := []struct {
+		name string
+		pkgs []goutil.Package
+		want bool
+	}{
+		{name: "same version and sum", pkgs: []goutil.Package{{Name: "gal", Version: &goutil.Version{Current: "v1.1.1", Latest: "v1.1.1"}, Sum: "h1:gal="}}},
+		{name: "new version", pkgs: []goutil.Package{{Name: "gal", Version: &goutil.Version{Current: "v1.1.1", Latest: "v1.2.0"}, Sum: "h1:gal="}}, want: true},
+		{name: "new sum", pkgs: []goutil.Package{{Name: "gal", Version: &goutil.Version{Current: "v1.1.1", Latest: "v1.1.1"}, Sum: "h1:new="}}, want: true},
+		{name: "not listed", pkgs: []goutil.Package{{Name: "subaru", Version: &goutil.Version{Current: "v1.0.0", Latest: "v1.0.2"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := configRecordChanged(confPkgs, tt.pkgs); got != tt.want {
+				t.Errorf("configRecordChanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func Test_gup_dryRun(t *testing.T) {
 	t.Setenv("GOBIN", filepath.Join("testdata", "check_success"))
 
@@ -865,7 +932,7 @@ func Test_update_modulePathChangedOnGetLatest(t *testing.T) {
 	}
 
 	channelMap := map[string]goutil.UpdateChannel{"air": goutil.UpdateChannelLatest}
-	if got, _, _ := updateWithChannels(pkgs, false, false, 1, true, channelMap); got != 0 {
+	if got, _, _ := updateWithChannels(pkgs, updateOptions{cpus: 1, ignoreGoUpdate: true, channelMap: channelMap}); got != 0 {
 		t.Fatalf("updateWithChannels() = %d, want 0", got)
 	}
 	if diff := cmp.Diff([]string{oldModule, newModule}, latestCalls); diff != "" {
@@ -934,7 +1001,7 @@ func Test_update_modulePathChangedOnInstall(t *testing.T) {
 	}
 
 	channelMap := map[string]goutil.UpdateChannel{"air": goutil.UpdateChannelLatest}
-	if got, _, _ := updateWithChannels(pkgs, false, false, 1, true, channelMap); got != 0 {
+	if got, _, _ := updateWithChannels(pkgs, updateOptions{cpus: 1, ignoreGoUpdate: true, channelMap: channelMap}); got != 0 {
 		t.Fatalf("updateWithChannels() = %d, want 0", got)
 	}
 	if diff := cmp.Diff([]string{oldImport, newImport}, installCalls); diff != "" {
@@ -961,6 +1028,10 @@ func Test_mergeConfigPackages(t *testing.T) {
 			ImportPath:    "github.com/example/kept-tool",
 			Version:       &goutil.Version{Current: "v0.5.0"},
 			UpdateChannel: goutil.UpdateChannelLatest,
+			Policy:        goutil.UpdatePolicyPatch,
+			CooldownDays:  pointer.Ptr(7),
+			AllowMajor:    true,
+			Toolchain:     "go1.22.4",
 		},
 	}
 	succeededPkgs := []goutil.Package{
@@ -969,6 +1040,11 @@ func Test_mergeConfigPackages(t *testing.T) {
 			ImportPath: "github.com/example/new-tool",
 			Version:    &goutil.Version{Current: testVersionOne, Latest: "v2.0.0"},
 		},
+		{
+			Name:       "kept-tool",
+			ImportPath: "github.com/example/kept-tool",
+			Version:    &goutil.Version{Current: "v0.5.0", Latest: "v0.5.1"},
+		},
 		// empty name/import should be skipped
 		{Name: "", ImportPath: ""},
 	}
@@ -996,6 +1072,55 @@ func Test_mergeConfigPackages(t *testing.T) {
 	if got[1].UpdateChannel != goutil.UpdateChannelMain {
 		t.Errorf("new-tool channel = %q, want main", got[1].UpdateChannel)
 	}
+	if got[0].Version.Current != "v0.5.1" || got[0].Policy != goutil.UpdatePolicyPatch {
+		t.Errorf("kept-tool = %q %q, want v0.5.1 with the saved patch policy", got[0].Version.Current, got[0].Policy)
+	}
+	if got[0].CooldownDays == nil || *got[0].CooldownDays != 7 {
+		t.Errorf("kept-tool cooldown_days = %v, want the saved 7 days", got[0].CooldownDays)
+	}
+	if !got[0].AllowMajor {
+		t.Error("kept-tool allow_major = false, want the saved true")
+	}
+	if got[0].Toolchain != "go1.22.4" {
+		t.Errorf("kept-tool toolchain = %q, want the saved go1.22.4", got[0].Toolchain)
+	}
+}
+
+func Test_mergeConfigPackages_renameKeepsSettings(t *testing.T) {
+	confPkgs := []goutil.Package{{
+		Name:          "air",
+		ImportPath:    "github.com/cosmtrek/air",
+		Version:       &goutil.Version{Current: "v1.49.0"},
+		UpdateChannel: goutil.UpdateChannelLatest,
+		Pin:           "v1.49.0",
+		Policy:        goutil.UpdatePolicyMinor,
+		CooldownDays:  pointer.Ptr(3),
+		AllowMajor:    true,
+		Build:         goutil.BuildSettings{Ldflags: "-s -w", Tags: []string{"netgo"}, Trimpath: true},
+		Toolchain:     "go1.22.4",
+	}}
+	succeededPkgs := []goutil.Package{{
+		Name:       "air2",
+		ImportPath: "github.com/air-verse/air",
+		Version:    &goutil.Version{Current: "v1.49.0", Latest: "v1.49.0"},
+	}}
+
+	got := mergeConfigPackages(confPkgs, succeededPkgs, map[string]goutil.UpdateChannel{"air": goutil.UpdateChannelLatest}, map[string]string{"air": "air2"})
+	want := []goutil.Package{{
+		Name:          "air2",
+		ImportPath:    "github.com/air-verse/air",
+		Version:       &goutil.Version{Current: "v1.49.0"},
+		UpdateChannel: goutil.UpdateChannelLatest,
+		Pin:           "v1.49.0",
+		Policy:        goutil.UpdatePolicyMinor,
+		CooldownDays:  pointer.Ptr(3),
+		AllowMajor:    true,
+		Build:         goutil.BuildSettings{Ldflags: "-s -w", Tags: []string{"netgo"}, Trimpath: true},
+		Toolchain:     "go1.22.4",
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mergeConfigPackages() mismatch (-want +got):\n%s", diff)
+	}
 }
 
 func Test_sanitizeConfigPackage(t *testing.T) {
@@ -1102,7 +1227,7 @@ func Test_resolveUpdateChannels(t *testing.T) {
 	}
 
 	t.Run("assigns channels from flags", func(t *testing.T) {
-		got, err := resolveUpdateChannels(pkgs, nil, []string{"tool-a"}, []string{"tool-b"}, nil)
+		got, err := resolveUpdateChannels(pkgs, nil, []string{"tool-a"}, []string{"tool-b"}, nil, nil, nil)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -1118,7 +1243,7 @@ func Test_resolveUpdateChannels(t *testing.T) {
 	})
 
 	t.Run("error on conflicting flags", func(t *testing.T) {
-		_, err := resolveUpdateChannels(pkgs, nil, []string{"tool-a"}, []string{"tool-a"}, nil)
+		_, err := resolveUpdateChannels(pkgs, nil, []string{"tool-a"}, []string{"tool-a"}, nil, nil, nil)
 		if err == nil {
 			t.Fatal("expected error for conflicting flags")
 		}
@@ -1131,7 +1256,7 @@ func Test_resolveUpdateChannels(t *testing.T) {
 		confPkgs := []goutil.Package{
 			{Name: "tool-c", UpdateChannel: goutil.UpdateChannelMain},
 		}
-		got, err := resolveUpdateChannels(pkgs, confPkgs, nil, nil, nil)
+		got, err := resolveUpdateChannels(pkgs, confPkgs, nil, nil, nil, nil, nil)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -1140,8 +1265,52 @@ func Test_resolveUpdateChannels(t *testing.T) {
 		}
 	})
 
+	t.Run("assigns prerelease from flags", func(t *testing.T) {
+		got, err := resolveUpdateChannels(pkgs, nil, nil, nil, nil, []string{"tool-c"}, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got["tool-c"] != goutil.UpdateChannelPrerelease {
+			t.Errorf("tool-c channel = %q, want prerelease", got["tool-c"])
+		}
+	})
+
+	t.Run("assigns refs from flags", func(t *testing.T) {
+		got, err := resolveUpdateChannels(pkgs, nil, nil, nil, nil, nil, []string{"tool-a@release-2.x"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got["tool-a"] != goutil.RefUpdateChannel("release-2.x") {
+			t.Errorf("tool-a channel = %q, want ref:release-2.x", got["tool-a"])
+		}
+	})
+
+	t.Run("error on ref without name or ref", func(t *testing.T) {
+		for _, raw := range []string{"tool-a", "tool-a@"} {
+			if _, err := resolveUpdateChannels(pkgs, nil, nil, nil, nil, nil, []string{raw}); err == nil {
+				t.Errorf("--ref %s: expected error", raw)
+			}
+		}
+		if _, err := resolveUpdateChannels(pkgs, nil, []string{"tool-a"}, nil, nil, nil, []string{"tool-a@develop"}); err == nil {
+			t.Error("expected error for --main and --ref on the same binary")
+		}
+	})
+
+	t.Run("config ref channel is used as default", func(t *testing.T) {
+		confPkgs := []goutil.Package{
+			{Name: "tool-b", UpdateChannel: goutil.RefUpdateChannel("develop")},
+		}
+		got, err := resolveUpdateChannels(pkgs, confPkgs, nil, nil, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got["tool-b"] != goutil.RefUpdateChannel("develop") {
+			t.Errorf("tool-b channel = %q, want ref:develop", got["tool-b"])
+		}
+	})
+
 	t.Run("empty name in flag is skipped", func(t *testing.T) {
-		got, err := resolveUpdateChannels(pkgs, nil, []string{" "}, nil, nil)
+		got, err := resolveUpdateChannels(pkgs, nil, []string{" "}, nil, nil, nil, nil)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -1176,6 +1345,7 @@ func Test_installWithSelectedVersion(t *testing.T) {
 		{goutil.UpdateChannelLatest, "latest"},
 		{goutil.UpdateChannelMain, "main"},
 		{goutil.UpdateChannelMaster, "version:master"},
+		{goutil.RefUpdateChannel("develop"), "version:develop"},
 		{"unknown", "latest"}, // default case
 	}
 	for _, tt := range tests {
@@ -1287,6 +1457,13 @@ func Test_binaryNameFromImportPathWith(t *testing.T) {
 			goexe:      "",
 			want:       "mytool",
 		},
+		{
+			name:       "major version suffix is skipped",
+			importPath: "github.com/example/tool/v2",
+			goos:       "linux",
+			goexe:      "",
+			want:       "tool",
+		},
 		{
 			name:       "windows adds .exe when GOEXE is empty",
 			importPath: "github.com/air-verse/air",
@@ -1360,18 +1537,300 @@ func Test_removeOldBinaryIfRenamed_emptyNames(t *testing.T) {
 }
 
 func Test_shouldPersistChannels(t *testing.T) {
-	if shouldPersistChannels(nil, nil, nil) {
+	if shouldPersistChannels(nil, nil, nil, nil, nil) {
 		t.Error("all empty should return false")
 	}
-	if !shouldPersistChannels([]string{"a"}, nil, nil) {
+	if !shouldPersistChannels([]string{"a"}, nil, nil, nil, nil) {
 		t.Error("mainPkgNames non-empty should return true")
 	}
-	if !shouldPersistChannels(nil, []string{"b"}, nil) {
+	if !shouldPersistChannels(nil, []string{"b"}, nil, nil, nil) {
 		t.Error("masterPkgNames non-empty should return true")
 	}
-	if !shouldPersistChannels(nil, nil, []string{"c"}) {
+	if !shouldPersistChannels(nil, nil, []string{"c"}, nil, nil) {
 		t.Error("latestPkgNames non-empty should return true")
 	}
+	if !shouldPersistChannels(nil, nil, nil, []string{"d"}, nil) {
+		t.Error("prereleasePkgNames non-empty should return true")
+	}
+	if !shouldPersistChannels(nil, nil, nil, nil, []string{"d@develop"}) {
+		t.Error("refPkgs non-empty should return true")
+	}
+}
+
+func Test_updateWithChannels_prereleaseChannel(t *testing.T) {
+	origGetLatest := getLatestVer
+	origGetVersionList := getVersionListCtx
+	origInstallLatest := installLatest
+	origInstallByVersion := installByVersionUpd
+	defer func() {
+		getLatestVer = origGetLatest
+		getVersionListCtx = origGetVersionList
+		installLatest = origInstallLatest
+		installByVersionUpd = origInstallByVersion
+	}()
+	getLatestVer = func(string) (string, error) { return "v1.1.0", nil }
+	getVersionListCtx = func(context.Context, string) ([]string, error) {
+		return []string{"v1.0.0", "v1.1.0", "v1.2.0-beta.1", "v1.2.0-rc.2", "v1.2.0-rc.10"}, nil
+	}
+	installLatest = func(string) error {
+		t.Fatal("@latest must not be installed by the prerelease channel")
+		return nil
+	}
+	installed := ""
+	installByVersionUpd = func(_ string, version string) error {
+		installed = version
+		return nil
+	}
+
+	newPkgs := func(current string) []goutil.Package {
+		return []goutil.Package{{
+			Name:       "gal",
+			ImportPath: "github.com/nao1215/gal/cmd/gal",
+			ModulePath: "github.com/nao1215/gal",
+			Version:    &goutil.Version{Current: current},
+			GoVersion:  &goutil.Version{Current: "go1.22.4", Latest: "go1.22.4"},
+		}}
+	}
+	opts := updateOptions{
+		cpus:           1,
+		ignoreGoUpdate: true,
+		channelMap:     map[string]goutil.UpdateChannel{"gal": goutil.UpdateChannelPrerelease},
+	}
+
+	if result, _, _ := updateWithChannels(newPkgs("v1.2.0-rc.2"), opts); result != 0 {
+		t.Fatalf("updateWithChannels() = %d, want 0", result)
+	}
+	if installed != "v1.2.0-rc.10" {
+		t.Errorf("installed version = %q, want v1.2.0-rc.10", installed)
+	}
+
+	installed = ""
+	if result, _, _ := updateWithChannels(newPkgs("v1.2.0-rc.10"), opts); result != 0 {
+		t.Fatalf("updateWithChannels() = %d, want 0", result)
+	}
+	if installed != "" {
+		t.Errorf("installed version = %q, want no update at the highest prerelease", installed)
+	}
+}
+
+func Test_latestVerCache_prereleaseVersion(t *testing.T) {
+	origGetLatest := getLatestVer
+	origGetVersionList := getVersionListCtx
+	defer func() {
+		getLatestVer = origGetLatest
+		getVersionListCtx = origGetVersionList
+	}()
+	// Modules without tags only have a pseudo-version.
+	getLatestVer = func(string) (string, error) { return "v0.0.0-20260304050607-4f2c1a9b8e7d", nil }
+	getVersionListCtx = func(context.Context, string) ([]string, error) { return []string{}, nil }
+
+	got, err := newLatestVerCache().prereleaseVersion(context.Background(), "example.com/tool")
+	if err != nil {
+		t.Fatalf("prereleaseVersion() error = %v", err)
+	}
+	if got != "v0.0.0-20260304050607-4f2c1a9b8e7d" {
+		t.Errorf("prereleaseVersion() = %q, want the latest pseudo-version", got)
+	}
+
+	getLatestVer = func(string) (string, error) { return "", errors.New("boom") }
+	if _, err := newLatestVerCache().prereleaseVersion(context.Background(), "example.com/tool"); err == nil {
+		t.Error("prereleaseVersion() should return the latest version error")
+	}
+}
+
+func Test_resolveBuildSettings(t *testing.T) {
+	recorded := goutil.BuildSettings{Tags: []string{"netgo"}, CGOEnabled: pointer.Ptr(false)}
+	pkgs := []goutil.Package{
+		{Name: "tool-a", Build: recorded},
+		{Name: "tool-b", Build: recorded},
+		{Name: "tool-c"},
+	}
+	confPkgs := []goutil.Package{
+		// -X flags set explicitly in gup.json are kept.
+		{Name: "tool-a", Build: goutil.BuildSettings{Ldflags: "-s -w -X main.version=v1.0.0"}},
+		{Name: "tool-b"},
+		{Name: "not-installed", Build: goutil.BuildSettings{Trimpath: true}},
+	}
+
+	want := map[string]goutil.BuildSettings{
+		"tool-a": {Ldflags: "-s -w -X main.version=v1.0.0"},
+		"tool-b": recorded,
+		"tool-c": {},
+	}
+	if diff := cmp.Diff(want, resolveBuildSettings(pkgs, confPkgs, false)); diff != "" {
+		t.Errorf("resolveBuildSettings() mismatch (-want +got):\n%s", diff)
+	}
+
+	want = map[string]goutil.BuildSettings{
+		"tool-a": {Ldflags: "-s -w -X main.version=v1.0.0"},
+		"tool-b": {},
+		"tool-c": {},
+	}
+	if diff := cmp.Diff(want, resolveBuildSettings(pkgs, confPkgs, true)); diff != "" {
+		t.Errorf("resolveBuildSettings(reset) mismatch (-want +got):\n%s", diff)
+	}
+
+	pkgs[0].Toolchain = "go1.22.4"
+	pkgs[2].Toolchain = "go1.23.0"
+	want = map[string]goutil.BuildSettings{
+		"tool-a": {Ldflags: "-s -w -X main.version=v1.0.0", Toolchain: "go1.22.4"},
+		"tool-b": {},
+		"tool-c": {Toolchain: "go1.23.0"},
+	}
+	if diff := cmp.Diff(want, resolveBuildSettings(pkgs, confPkgs, true)); diff != "" {
+		t.Errorf("resolveBuildSettings(toolchain) mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_applyToolchains(t *testing.T) {
+	pkgs := []goutil.Package{
+		{Name: "tool-a", GoVersion: &goutil.Version{Current: "go1.22.4", Latest: "go1.25.0"}},
+		{Name: "tool-b", GoVersion: &goutil.Version{Current: "go1.22.4", Latest: "go1.25.0"}},
+		{Name: "tool-c", GoVersion: &goutil.Version{Current: "go1.22.4", Latest: "go1.25.0"}},
+	}
+	confPkgs := []goutil.Package{
+		{Name: "tool-a", Toolchain: "go1.22.4"},
+		{Name: "tool-b", Toolchain: "local"},
+	}
+
+	got := applyToolchains(pkgs, confPkgs)
+	want := []goutil.Package{
+		{Name: "tool-a", Toolchain: "go1.22.4", GoVersion: &goutil.Version{Current: "go1.22.4", Latest: "go1.22.4"}},
+		{Name: "tool-b", Toolchain: "local", GoVersion: &goutil.Version{Current: "go1.22.4", Latest: "go1.25.0"}},
+		{Name: "tool-c", GoVersion: &goutil.Version{Current: "go1.22.4", Latest: "go1.25.0"}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("applyToolchains() mismatch (-want +got):\n%s", diff)
+	}
+	if !got[0].IsGoUpToDate() {
+		t.Error("tool-a should be up to date with its toolchain")
+	}
+}
+
+func Test_updateWithChannels_buildSettings(t *testing.T) {
+	origGetLatest := getLatestVer
+	origInstallLatestCtx := installLatestCtx
+	defer func() {
+		getLatestVer = origGetLatest
+		installLatestCtx = origInstallLatestCtx
+	}()
+	getLatestVer = func(string) (string, error) { return "v1.2.0", nil }
+	var got goutil.BuildSettings
+	installLatestCtx = func(ctx context.Context, _ string) error {
+		got = goutil.BuildSettingsFromContext(ctx)
+		return nil
+	}
+
+	pkgs := []goutil.Package{{
+		Name:       "gal",
+		ImportPath: "github.com/nao1215/gal/cmd/gal",
+		ModulePath: "github.com/nao1215/gal",
+		Version:    &goutil.Version{Current: "v1.1.1"},
+		GoVersion:  &goutil.Version{Current: "go1.22.4", Latest: "go1.22.4"},
+	}}
+	build := goutil.BuildSettings{Ldflags: "-s -w", Trimpath: true}
+	result, _, _ := updateWithChannels(pkgs, updateOptions{
+		cpus:           1,
+		ignoreGoUpdate: true,
+		builds:         map[string]goutil.BuildSettings{"gal": build},
+	})
+	if result != 0 {
+		t.Fatalf("updateWithChannels() = %d, want 0", result)
+	}
+	if diff := cmp.Diff(build, got); diff != "" {
+		t.Errorf("build settings mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_updateWithChannels_refreshesSum(t *testing.T) {
+	origGetLatest := getLatestVer
+	origInstallLatest := installLatest
+	origGetPackageSum := getPackageSum
+	defer func() {
+		getLatestVer = origGetLatest
+		installLatest = origInstallLatest
+		getPackageSum = origGetPackageSum
+	}()
+	getLatestVer = func(string) (string, error) { return "v1.2.0", nil }
+	installLatest = func(string) error { return nil }
+	getPackageSum = func(string) string { return "h1:new=" }
+
+	pkgs := []goutil.Package{{
+		Name:       "gal",
+		ImportPath: "github.com/nao1215/gal/cmd/gal",
+		ModulePath: "github.com/nao1215/gal",
+		Version:    &goutil.Version{Current: "v1.1.1"},
+		GoVersion:  &goutil.Version{Current: "go1.22.4", Latest: "go1.22.4"},
+		Sum:        "h1:old=",
+	}}
+	result, succeeded, _ := updateWithChannels(pkgs, updateOptions{cpus: 1, ignoreGoUpdate: true})
+	if result != 0 || len(succeeded) != 1 {
+		t.Fatalf("updateWithChannels() = %d, %+v, want one updated package", result, succeeded)
+	}
+
+	confPkgs := []goutil.Package{{Name: "gal", ImportPath: "github.com/nao1215/gal/cmd/gal", Version: &goutil.Version{Current: "v1.1.1"}, Sum: "h1:old="}}
+	merged := mergeConfigPackages(confPkgs, succeeded, nil, nil)
+	if len(merged) != 1 || merged[0].Version.Current != "v1.2.0" || merged[0].Sum != "h1:new=" {
+		t.Errorf("mergeConfigPackages() = %+v, want gal v1.2.0 with the checksum of the new binary", merged)
+	}
+}
+
+func Test_updateWithChannels_refChannel(t *testing.T) {
+	origResolveRef := resolveRefCtx
+	origGetLatest := getLatestVer
+	origInstallByVersion := installByVersionUpd
+	defer func() {
+		resolveRefCtx = origResolveRef
+		getLatestVer = origGetLatest
+		installByVersionUpd = origInstallByVersion
+	}()
+	const develop = "v1.2.0-0.20260304050607-4f2c1a9b8e7d"
+	resolveRefCtx = func(_ context.Context, _, ref string) (string, error) {
+		if ref != "develop" {
+			return "", fmt.Errorf("unexpected ref %s", ref)
+		}
+		return develop, nil
+	}
+	getLatestVer = func(string) (string, error) {
+		t.Fatal("@latest must not be queried for a ref channel")
+		return "", nil
+	}
+	installed := ""
+	installByVersionUpd = func(_ string, version string) error {
+		installed = version
+		return nil
+	}
+
+	newPkgs := func(current string) []goutil.Package {
+		return []goutil.Package{{
+			Name:       "gal",
+			ImportPath: "github.com/nao1215/gal/cmd/gal",
+			ModulePath: "github.com/nao1215/gal",
+			Version:    &goutil.Version{Current: current},
+			GoVersion:  &goutil.Version{Current: "go1.22.4", Latest: "go1.22.4"},
+		}}
+	}
+	opts := updateOptions{
+		cpus:           1,
+		ignoreGoUpdate: true,
+		channelMap:     map[string]goutil.UpdateChannel{"gal": goutil.RefUpdateChannel("develop")},
+	}
+
+	// The branch moved to a commit whose pseudo-version is lower than the installed tag.
+	if result, _, _ := updateWithChannels(newPkgs("v1.3.0"), opts); result != 0 {
+		t.Fatalf("updateWithChannels() = %d, want 0", result)
+	}
+	if installed != "develop" {
+		t.Errorf("installed version = %q, want develop", installed)
+	}
+
+	installed = ""
+	if result, _, _ := updateWithChannels(newPkgs(develop), opts); result != 0 {
+		t.Fatalf("updateWithChannels() = %d, want 0", result)
+	}
+	if installed != "" {
+		t.Errorf("installed version = %q, want no update at the ref's pseudo-version", installed)
+	}
 }
 
 func Test_updateWithChannels_emptyImportPath(t *testing.T) {
@@ -1390,7 +1849,7 @@ func Test_updateWithChannels_emptyImportPath(t *testing.T) {
 	}
 
 	channelMap := map[string]goutil.UpdateChannel{"tool": goutil.UpdateChannelLatest}
-	result, _, _ := updateWithChannels(pkgs, false, false, 1, true, channelMap)
+	result, _, _ := updateWithChannels(pkgs, updateOptions{cpus: 1, ignoreGoUpdate: true, channelMap: channelMap})
 	if result != 1 {
 		t.Fatalf("updateWithChannels() = %d, want 1 (empty import path)", result)
 	}
@@ -1412,7 +1871,7 @@ func Test_updateWithChannels_alreadyUpToDate(t *testing.T) {
 	}
 
 	channelMap := map[string]goutil.UpdateChannel{"tool": goutil.UpdateChannelLatest}
-	result, succeeded, _ := updateWithChannels(pkgs, false, false, 1, true, channelMap)
+	result, succeeded, _ := updateWithChannels(pkgs, updateOptions{cpus: 1, ignoreGoUpdate: true, channelMap: channelMap})
 	if result != 0 {
 		t.Fatalf("updateWithChannels() = %d, want 0", result)
 	}
@@ -1469,7 +1928,7 @@ func Test_updateWithChannels_alreadyUpToDate_customGoBuildTag(t *testing.T) {
 	}
 
 	channelMap := map[string]goutil.UpdateChannel{"tool": goutil.UpdateChannelLatest}
-	result, succeeded, _ := updateWithChannels(pkgs, false, false, 1, false, channelMap)
+	result, succeeded, _ := updateWithChannels(pkgs, updateOptions{cpus: 1, channelMap: channelMap})
 
 	if err := pw.Close(); err != nil {
 		t.Fatal(err)
@@ -1546,7 +2005,7 @@ func Test_updateWithChannels_customGoBuildTag_goVersionDiffColor(t *testing.T) {
 	}
 
 	channelMap := map[string]goutil.UpdateChannel{"tool": goutil.UpdateChannelLatest}
-	result, _, _ := updateWithChannels(pkgs, false, false, 1, false, channelMap)
+	result, _, _ := updateWithChannels(pkgs, updateOptions{cpus: 1, channelMap: channelMap})
 	if err := pw.Close(); err != nil {
 		t.Fatal(err)
 	}
@@ -1586,7 +2045,7 @@ func Test_updateWithChannels_emptyModulePath(t *testing.T) {
 	}
 
 	channelMap := map[string]goutil.UpdateChannel{"tool": goutil.UpdateChannelLatest}
-	result, _, _ := updateWithChannels(pkgs, false, false, 1, true, channelMap)
+	result, _, _ := updateWithChannels(pkgs, updateOptions{cpus: 1, ignoreGoUpdate: true, channelMap: channelMap})
 	if result != 0 {
 		t.Fatalf("updateWithChannels() = %d, want 0", result)
 	}
@@ -1610,7 +2069,7 @@ func Test_updateWithChannels_getLatestVerError(t *testing.T) {
 	}
 
 	channelMap := map[string]goutil.UpdateChannel{"tool": goutil.UpdateChannelLatest}
-	result, _, _ := updateWithChannels(pkgs, false, false, 1, true, channelMap)
+	result, _, _ := updateWithChannels(pkgs, updateOptions{cpus: 1, ignoreGoUpdate: true, channelMap: channelMap})
 	if result != 1 {
 		t.Fatalf("updateWithChannels() = %d, want 1", result)
 	}
@@ -1639,7 +2098,7 @@ func Test_updateWithChannels_masterChannel(t *testing.T) {
 	}
 
 	channelMap := map[string]goutil.UpdateChannel{"tool": goutil.UpdateChannelMaster}
-	result, _, _ := updateWithChannels(pkgs, false, false, 1, true, channelMap)
+	result, _, _ := updateWithChannels(pkgs, updateOptions{cpus: 1, ignoreGoUpdate: true, channelMap: channelMap})
 	if result != 0 {
 		t.Fatalf("updateWithChannels() = %d, want 0", result)
 	}
@@ -1712,7 +2171,7 @@ func Test_updateWithChannels_notify(t *testing.T) {
 	}
 
 	channelMap := map[string]goutil.UpdateChannel{"tool": goutil.UpdateChannelLatest}
-	result, _, _ := updateWithChannels(pkgs, false, true, 1, true, channelMap)
+	result, _, _ := updateWithChannels(pkgs, updateOptions{notification: true, cpus: 1, ignoreGoUpdate: true, channelMap: channelMap})
 	if result != 0 {
 		t.Fatalf("updateWithChannels() with notify = %d, want 0", result)
 	}
@@ -1740,7 +2199,7 @@ func Test_updateWithChannels_installError(t *testing.T) {
 	}
 
 	channelMap := map[string]goutil.UpdateChannel{"tool": goutil.UpdateChannelLatest}
-	result, _, _ := updateWithChannels(pkgs, false, false, 1, true, channelMap)
+	result, _, _ := updateWithChannels(pkgs, updateOptions{cpus: 1, ignoreGoUpdate: true, channelMap: channelMap})
 	if result != 1 {
 		t.Fatalf("updateWithChannels() = %d, want 1", result)
 	}
@@ -1777,7 +2236,7 @@ func Test_updateWithChannels_mainChannel(t *testing.T) {
 	}
 
 	channelMap := map[string]goutil.UpdateChannel{"tool": goutil.UpdateChannelMain}
-	result, _, _ := updateWithChannels(pkgs, false, false, 1, true, channelMap)
+	result, _, _ := updateWithChannels(pkgs, updateOptions{cpus: 1, ignoreGoUpdate: true, channelMap: channelMap})
 	if result != 0 {
 		t.Fatalf("updateWithChannels() = %d, want 0", result)
 	}
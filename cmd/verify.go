@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/hashicorp/go-version"
+	"github.com/nao1215/gup/internal/cmdinfo"
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/fileutil"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+	"github.com/spf13/cobra"
+)
+
+func newVerifyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Report differences between gup.json and the binaries under $GOPATH/bin or $GOBIN",
+		Long: `Report differences between gup.json and the binaries under $GOPATH/bin or $GOBIN.
+
+verify reports binaries that are missing from $GOBIN, binaries that are
+not listed in gup.json, and binaries built from another import path,
+version, channel or main-module checksum than gup.json records.
+A binary is built from another channel if its version has the wrong form:
+the latest channel expects a release, the prerelease channel a tag, and
+the main, master and ref channels a pseudo-version (a ref that is a
+version tag expects that tag). gup itself is never reported as not
+listed, as "gup import --sync" never removes it.
+It changes nothing, and exits with a non-zero status if it finds drift.`,
+		Args:              cobra.NoArgs,
+		ValidArgsFunction: cobra.NoFileCompletions,
+		Run: func(cmd *cobra.Command, args []string) {
+			OsExit(verify(cmd, args))
+		},
+	}
+	cmd.Flags().StringP("file", "f", "", "specify gup.json file path to verify against")
+	if err := cmd.MarkFlagFilename("file", "json"); err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func verify(cmd *cobra.Command, _ []string) int {
+	if err := ensureGoCommandAvailable(); err != nil {
+		print.Err(err)
+		return 1
+	}
+
+	confFile, err := getFlagString(cmd, "file")
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	confFile = config.ResolveImportFilePath(confFile)
+	if !fileutil.IsFile(confFile) {
+		print.Err(fmt.Errorf("%s is not found", confFile))
+		return 1
+	}
+	confPkgs, err := config.ReadConfFile(confFile)
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+
+	installed, err := getPackageInfo()
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+
+	drifts := findDrifts(confPkgs, installed)
+	for _, d := range drifts {
+		print.Info(fmt.Sprintf("[%s] %s: %s", d.kind, d.name, d.detail))
+	}
+	if len(drifts) != 0 {
+		print.Err(fmt.Sprintf("%d difference(s) between %s and the installed binaries", len(drifts), confFile))
+		return 1
+	}
+	print.Info("the installed binaries match " + confFile)
+	return 0
+}
+
+// driftKind is the kind of difference between gup.json and an installed binary.
+type driftKind string
+
+const (
+	driftMissing    driftKind = "missing"
+	driftExtra      driftKind = "extra"
+	driftImportPath driftKind = "import-path"
+	driftVersion    driftKind = "version"
+	driftChannel    driftKind = "channel"
+	driftChecksum   driftKind = "checksum"
+)
+
+// drift is a difference between gup.json and an installed binary.
+type drift struct {
+	kind   driftKind
+	name   string
+	detail string
+}
+
+// findDrifts compares the packages in gup.json with the installed packages.
+// Packages in gup.json come first in name order, followed by extra binaries.
+// gup itself is not an extra binary, as in "gup import --sync".
+func findDrifts(confPkgs, installed []goutil.Package) []drift {
+	installedByName := make(map[string]goutil.Package, len(installed))
+	for _, p := range installed {
+		installedByName[normalizeBinaryNameForMatch(p.Name)] = p
+	}
+	confPkgs = append([]goutil.Package{}, confPkgs...)
+	sort.SliceStable(confPkgs, func(i, j int) bool { return confPkgs[i].Name < confPkgs[j].Name })
+
+	drifts := []drift{}
+	listed := make(map[string]struct{}, len(confPkgs)+1)
+	listed[normalizeBinaryNameForMatch(cmdinfo.Name)] = struct{}{}
+	for _, want := range confPkgs {
+		key := normalizeBinaryNameForMatch(want.Name)
+		listed[key] = struct{}{}
+		got, ok := installedByName[key]
+		if !ok {
+			drifts = append(drifts, drift{kind: driftMissing, name: want.Name, detail: "not installed"})
+			continue
+		}
+		drifts = append(drifts, comparePackage(want, got)...)
+	}
+
+	extras := []goutil.Package{}
+	for _, p := range installed {
+		if _, ok := listed[normalizeBinaryNameForMatch(p.Name)]; !ok {
+			extras = append(extras, p)
+		}
+	}
+	sort.SliceStable(extras, func(i, j int) bool { return extras[i].Name < extras[j].Name })
+	for _, p := range extras {
+		drifts = append(drifts, drift{
+			kind:   driftExtra,
+			name:   p.Name,
+			detail: fmt.Sprintf("%s@%s is not listed", p.ImportPath, installedVersion(p)),
+		})
+	}
+	return drifts
+}
+
+// comparePackage compares a package in gup.json with its installed binary.
+func comparePackage(want, got goutil.Package) []drift {
+	if want.ImportPath != got.ImportPath {
+		return []drift{{
+			kind:   driftImportPath,
+			name:   want.Name,
+			detail: fmt.Sprintf("want %s, installed %s", want.ImportPath, got.ImportPath),
+		}}
+	}
+
+	drifts := []drift{}
+	current := installedVersion(got)
+	wantVer, err := versionFromConfig(want)
+	if err == nil && wantVer != latestKeyword && wantVer != current {
+		drifts = append(drifts, drift{
+			kind:   driftVersion,
+			name:   want.Name,
+			detail: fmt.Sprintf("want %s, installed %s", wantVer, current),
+		})
+	}
+	channel := goutil.NormalizeUpdateChannel(string(want.UpdateChannel))
+	if detail := channelDrift(channel, current); detail != "" {
+		drifts = append(drifts, drift{kind: driftChannel, name: want.Name, detail: detail})
+	}
+	// A checksum only describes the version it was recorded for.
+	if want.Sum != "" && want.Version != nil && want.Version.Current == current && want.Sum != got.Sum {
+		gotSum := got.Sum
+		if gotSum == "" {
+			gotSum = "no checksum"
+		}
+		drifts = append(drifts, drift{
+			kind:   driftChecksum,
+			name:   want.Name,
+			detail: fmt.Sprintf("want %s, installed %s", want.Sum, gotSum),
+		})
+	}
+	return drifts
+}
+
+// channelDrift reports why the form of the installed version ver does not
+// match channel, or "" if it matches. Versions that can't be parsed, such
+// as "(devel)", are not reported.
+func channelDrift(channel goutil.UpdateChannel, ver string) string {
+	if _, err := version.NewVersion(ver); err != nil {
+		return ""
+	}
+	switch channel {
+	case goutil.UpdateChannelLatest:
+		if isBranchOrPrereleaseVersion(ver) {
+			return fmt.Sprintf("installed %s is not a release, but the channel is %s", ver, channel)
+		}
+	case goutil.UpdateChannelPrerelease:
+		if isBranchOrPrereleaseVersion(ver) && isPseudoVersion(ver) {
+			return fmt.Sprintf("installed %s is not a tag, but the channel is %s", ver, channel)
+		}
+	default:
+		// main, master and a ref that is a branch or commit are built as pseudo-versions.
+		if ref, ok := channel.Ref(); ok {
+			if _, err := version.NewVersion(ref); err == nil {
+				return ""
+			}
+		}
+		if !isPseudoVersion(ver) {
+			return fmt.Sprintf("installed %s is a tagged release, but the channel is %s", ver, channel)
+		}
+	}
+	return ""
+}
+
+// pseudoVersionSuffix matches the timestamp and commit hash of a pseudo-version.
+var pseudoVersionSuffix = regexp.MustCompile(`[-.][0-9]{14}-[0-9a-f]{12}(\+incompatible)?$`)
+
+// isPseudoVersion reports whether ver is a pseudo-version, which "go install"
+// selects for a branch or commit.
+func isPseudoVersion(ver string) bool {
+	return pseudoVersionSuffix.MatchString(ver)
+}
+
+func installedVersion(p goutil.Package) string {
+	if p.Version == nil || p.Version.Current == "" {
+		return "unknown"
+	}
+	return p.Version.Current
+}
+
+// isBranchOrPrereleaseVersion reports whether ver is a prerelease, or a
+// pseudo-version built from a branch of a module that has tags.
+// Pseudo-versions of modules without tags (v0.0.0-...) are what
+// @latest selects, so they are not reported.
+func isBranchOrPrereleaseVersion(ver string) bool {
+	v, err := version.NewVersion(ver)
+	if err != nil || v.Prerelease() == "" {
+		return false
+	}
+	for _, s := range v.Segments() {
+		if s != 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+//nolint:paralleltest // tests mutate global variables and environment variables
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+)
+
+func Test_findDrifts(t *testing.T) {
+	const sum = "h1:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="
+	pkg := func(name, importPath, ver string) goutil.Package {
+		return goutil.Package{Name: name, ImportPath: importPath, Version: &goutil.Version{Current: ver}}
+	}
+
+	confPkgs := []goutil.Package{
+		pkg("sqly", "github.com/nao1215/sqly", "v0.12.0"),
+		pkg("gal", "github.com/nao1215/gal/cmd/gal", "v1.1.1"),
+		pkg("gup", "github.com/nao1215/gup", "v1.0.0"),
+		pkg("mimixbox", "github.com/nao1215/mimixbox/cmd/mimixbox", "v0.33.0"),
+		pkg("air", "github.com/air-verse/air", "v1.61.0"),
+		pkg("tool", "example.com/tool", "(devel)"),
+		pkg("lint", "example.com/lint", "v1.0.0"),
+		pkg("beta", "example.com/beta", "v1.0.0"),
+	}
+	confPkgs[2].Pin = "v0.9.0"
+	confPkgs[3].Sum = sum
+	confPkgs[4].ImportPath = "github.com/cosmtrek/air"
+	confPkgs[6].UpdateChannel = goutil.UpdateChannelMain
+	confPkgs[7].UpdateChannel = goutil.UpdateChannelLatest
+
+	installed := []goutil.Package{
+		pkg("gal", "github.com/nao1215/gal/cmd/gal", "v1.1.1"),
+		pkg("gup", "github.com/nao1215/gup", "v1.0.0"),
+		pkg("mimixbox", "github.com/nao1215/mimixbox/cmd/mimixbox", "v0.33.0"),
+		pkg("air", "github.com/air-verse/air", "v1.61.0"),
+		pkg("tool", "example.com/tool", "v0.0.0-20240101000000-abcdefabcdef"),
+		pkg("lint", "example.com/lint", "v1.0.1-0.20240101000000-abcdefabcdef"),
+		pkg("beta", "example.com/beta", "v1.1.0-rc.1"),
+		pkg("posixer", "github.com/nao1215/posixer", "v0.1.0"),
+	}
+	installed[2].Sum = "h1:BBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBB="
+	confPkgs[0].Sum = sum // sqly is not installed, so its checksum is not compared.
+
+	want := []drift{
+		{kind: driftImportPath, name: "air", detail: "want github.com/cosmtrek/air, installed github.com/air-verse/air"},
+		{kind: driftVersion, name: "beta", detail: "want v1.0.0, installed v1.1.0-rc.1"},
+		{kind: driftChannel, name: "beta", detail: "installed v1.1.0-rc.1 is not a release, but the channel is latest"},
+		{kind: driftVersion, name: "gup", detail: "want v0.9.0, installed v1.0.0"},
+		{kind: driftVersion, name: "lint", detail: "want v1.0.0, installed v1.0.1-0.20240101000000-abcdefabcdef"},
+		{kind: driftChecksum, name: "mimixbox", detail: "want " + sum + ", installed h1:BBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBB="},
+		{kind: driftMissing, name: "sqly", detail: "not installed"},
+		{kind: driftExtra, name: "posixer", detail: "github.com/nao1215/posixer@v0.1.0 is not listed"},
+	}
+	got := findDrifts(confPkgs, installed)
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(drift{})); diff != "" {
+		t.Errorf("findDrifts() mismatch (-want +got):\n%s", diff)
+	}
+
+	if got := findDrifts(installed[:1], installed[:1]); len(got) != 0 {
+		t.Errorf("findDrifts() of the same packages = %v, want none", got)
+	}
+}
+
+func Test_isBranchOrPrereleaseVersion(t *testing.T) {
+	tests := map[string]bool{
+		"v1.2.3":                             false,
+		"v1.2.4-rc.1":                        true,
+		"v1.2.4-0.20240101000000-abcdefabcd": true,
+		"v0.0.0-20240101000000-abcdefabcdef": false,
+		"(devel)":                            false,
+	}
+	for ver, want := range tests {
+		if got := isBranchOrPrereleaseVersion(ver); got != want {
+			t.Errorf("isBranchOrPrereleaseVersion(%q) = %v, want %v", ver, got, want)
+		}
+	}
+}
+
+func Test_findDrifts_keepsGup(t *testing.T) {
+	installed := []goutil.Package{
+		{Name: "gup", ImportPath: "github.com/nao1215/gup", Version: &goutil.Version{Current: "v1.0.0"}},
+	}
+	if got := findDrifts(nil, installed); len(got) != 0 {
+		t.Errorf("findDrifts() = %v, want gup not reported as extra", got)
+	}
+}
+
+func Test_channelDrift(t *testing.T) {
+	const pseudo = "v1.0.1-0.20240101000000-abcdefabcdef"
+	tests := []struct {
+		channel goutil.UpdateChannel
+		ver     string
+		want    string
+	}{
+		{goutil.UpdateChannelLatest, "v1.0.0", ""},
+		{goutil.UpdateChannelLatest, "v1.1.0-rc.1", "installed v1.1.0-rc.1 is not a release, but the channel is latest"},
+		{goutil.UpdateChannelLatest, pseudo, "installed " + pseudo + " is not a release, but the channel is latest"},
+		{goutil.UpdateChannelLatest, "v0.0.0-20240101000000-abcdefabcdef", ""},
+		{goutil.UpdateChannelPrerelease, "v1.1.0-rc.1", ""},
+		{goutil.UpdateChannelPrerelease, "v1.0.0", ""},
+		{goutil.UpdateChannelPrerelease, pseudo, "installed " + pseudo + " is not a tag, but the channel is prerelease"},
+		{goutil.UpdateChannelMain, pseudo, ""},
+		{goutil.UpdateChannelMain, "v1.0.0", "installed v1.0.0 is a tagged release, but the channel is main"},
+		{goutil.UpdateChannelMaster, "v1.1.0-rc.1", "installed v1.1.0-rc.1 is a tagged release, but the channel is master"},
+		{goutil.RefUpdateChannel("develop"), pseudo, ""},
+		{goutil.RefUpdateChannel("develop"), "v1.0.0", "installed v1.0.0 is a tagged release, but the channel is ref:develop"},
+		{goutil.RefUpdateChannel("v1.0.0"), "v1.0.0", ""},
+		{goutil.UpdateChannelMain, "(devel)", ""},
+	}
+	for _, tt := range tests {
+		if got := channelDrift(tt.channel, tt.ver); got != tt.want {
+			t.Errorf("channelDrift(%s, %s) = %q, want %q", tt.channel, tt.ver, got, tt.want)
+		}
+	}
+}
+
+func Test_verify(t *testing.T) {
+	setupXDGBase(t)
+	gobin := t.TempDir()
+	t.Setenv("GOBIN", gobin)
+	helper_CopyFile(t, filepath.Join("testdata", "check_success", "gal"), filepath.Join(gobin, withExecSuffix("gal")))
+
+	orgStdout, orgStderr := print.Stdout, print.Stderr
+	t.Cleanup(func() {
+		print.Stdout, print.Stderr = orgStdout, orgStderr
+	})
+
+	run := func(ver string) (int, string) {
+		t.Helper()
+		confFile := filepath.Join(t.TempDir(), "gup.json")
+		var conf bytes.Buffer
+		pkgs := []goutil.Package{
+			{Name: "gal", ImportPath: "github.com/nao1215/gal/cmd/gal", Version: &goutil.Version{Current: ver}},
+		}
+		if err := config.WriteConfFile(&conf, pkgs); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(confFile, conf.Bytes(), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		var out bytes.Buffer
+		print.Stdout, print.Stderr = &out, &out
+		cmd := newVerifyCmd()
+		if err := cmd.Flags().Set("file", confFile); err != nil {
+			t.Fatal(err)
+		}
+		return verify(cmd, nil), out.String()
+	}
+
+	if got, out := run("v1.1.1"); got != 0 || !strings.Contains(out, "the installed binaries match") {
+		t.Errorf("verify() = %d, output %q, want 0 and no drift", got, out)
+	}
+	if got, out := run("v1.0.0"); got != 1 || !strings.Contains(out, "[version] gal: want v1.0.0, installed v1.1.1") {
+		t.Errorf("verify() = %d, output %q, want 1 and a version drift", got, out)
+	}
+}
@@ -3,15 +3,31 @@ package cmd
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/spf13/cobra"
 )
 
 // latestVerCache deduplicates concurrent getLatestVer calls for the same module path.
 // When multiple goroutines request the latest version of the same module,
 // only one network call is made; others wait and share the result.
+//
+// When store is set, results are also read from and written to the on-disk
+// cache so that they are shared across gup processes. refresh skips reading
+// the on-disk cache but still records freshly fetched versions.
+//
+// When modCacheDir is set, versions are read only from the module cache
+// (see --offline), and the on-disk cache is neither read nor written.
 type latestVerCache struct {
-	mu      sync.Mutex
-	entries map[string]*latestVerEntry
+	mu          sync.Mutex
+	entries     map[string]*latestVerEntry
+	store       *latestVerStore
+	refresh     bool
+	modCacheDir string
 }
 
 type latestVerEntry struct {
@@ -27,14 +43,142 @@ func newLatestVerCache() *latestVerCache {
 	return &latestVerCache{entries: make(map[string]*latestVerEntry)}
 }
 
+// newPersistentLatestVerCache returns a latestVerCache backed by the on-disk cache.
+// A nil store behaves like newLatestVerCache.
+func newPersistentLatestVerCache(store *latestVerStore, refresh bool) *latestVerCache {
+	c := newLatestVerCache()
+	c.store = store
+	c.refresh = refresh
+	return c
+}
+
 // get returns the latest version for the given module path,
 // calling getLatestVer at most once per unique module path.
 func (c *latestVerCache) get(ctx context.Context, modulePath string) (string, error) {
+	return c.lookup(ctx, modulePath, func(ctx context.Context) (string, error) {
+		return c.fetch(ctx, modulePath)
+	})
+}
+
+// probe returns the latest version of a module path that may not exist, such
+// as the next major version of a module (see goutil.ProbeLatestVerWithContext).
+// Unlike get, a module that does not exist is also recorded in the on-disk
+// cache, so that it is not looked up again on every run.
+func (c *latestVerCache) probe(ctx context.Context, modulePath string) (string, error) {
+	return c.lookup(ctx, modulePath, func(ctx context.Context) (string, error) {
+		if c.offline() {
+			return goutil.ModCacheLatestVersion(c.modCacheDir, modulePath)
+		}
+		if c.store != nil && !c.refresh {
+			if version, ok := c.store.load(modulePath); ok {
+				return version, nil
+			}
+			if c.store.loadNotFound(modulePath) {
+				return "", fmt.Errorf("can't check %s: %w (cached)", modulePath, goutil.ErrModuleNotFound)
+			}
+		}
+
+		version, err := probeLatestVerCtx(ctx, modulePath)
+		if c.store != nil {
+			// The cache is only an optimization; a failed write must not fail the lookup.
+			switch {
+			case err == nil:
+				_ = c.store.save(modulePath, version)
+			case errors.Is(err, goutil.ErrModuleNotFound):
+				_ = c.store.saveNotFound(modulePath)
+			}
+		}
+		return version, err
+	})
+}
+
+// refVersion returns the version that the ref of the given module path resolves to,
+// calling resolveRef at most once per unique module ref.
+// Refs move, so they are not stored in the on-disk cache.
+func (c *latestVerCache) refVersion(ctx context.Context, modulePath, ref string) (string, error) {
+	return c.lookup(ctx, modulePath+"@"+ref, func(ctx context.Context) (string, error) {
+		if c.offline() {
+			return "", fmt.Errorf("can't check %s@%s: %w", modulePath, ref, goutil.ErrNotInModCache)
+		}
+		return resolveRefCtx(ctx, modulePath, ref)
+	})
+}
+
+// prereleaseVersion returns the highest version of the given module path,
+// including prereleases. The latest version is taken into account for
+// modules without tags, whose latest version is a pseudo-version.
+func (c *latestVerCache) prereleaseVersion(ctx context.Context, modulePath string) (string, error) {
+	latest, err := c.get(ctx, modulePath)
+	if err != nil {
+		return "", err
+	}
+	versions, err := c.versions(ctx, modulePath)
+	if err != nil {
+		return "", err
+	}
+	return goutil.HighestVersion(append(versions, latest), true), nil
+}
+
+// channelVersion returns the version that the channel installs: the version
+// of the ref for "ref:<ref>" channels, the highest version for the prerelease
+// channel and the latest version otherwise.
+func (c *latestVerCache) channelVersion(ctx context.Context, modulePath string, channel goutil.UpdateChannel) (string, error) {
+	if ref, ok := channel.Ref(); ok {
+		return c.refVersion(ctx, modulePath, ref)
+	}
+	if c.offline() && (channel == goutil.UpdateChannelMain || channel == goutil.UpdateChannelMaster) {
+		// Branch heads are not recorded in the module cache.
+		return c.refVersion(ctx, modulePath, string(channel))
+	}
+	if channel == goutil.UpdateChannelPrerelease {
+		return c.prereleaseVersion(ctx, modulePath)
+	}
+	return c.get(ctx, modulePath)
+}
+
+// versions returns the tagged versions of the given module path,
+// calling getVersionList at most once per unique module path.
+// Version lists are not stored in the on-disk cache.
+func (c *latestVerCache) versions(ctx context.Context, modulePath string) ([]string, error) {
+	// The list is kept newline separated, like the proxy's @v/list response.
+	list, err := c.lookup(ctx, modulePath+"/@v/list", func(ctx context.Context) (string, error) {
+		if c.offline() {
+			versions, err := goutil.ModCacheVersions(c.modCacheDir, modulePath)
+			return strings.Join(versions, "\n"), err
+		}
+		versions, err := getVersionListCtx(ctx, modulePath)
+		return strings.Join(versions, "\n"), err
+	})
+	if err != nil || list == "" {
+		return []string{}, err
+	}
+	return strings.Split(list, "\n"), nil
+}
+
+// versionTime returns the publish time of the given module version,
+// calling getVersionTime at most once per unique module version.
+func (c *latestVerCache) versionTime(ctx context.Context, modulePath, ver string) (time.Time, error) {
+	raw, err := c.lookup(ctx, modulePath+"/@v/"+ver+".info", func(ctx context.Context) (string, error) {
+		if c.offline() {
+			published, err := goutil.ModCacheVersionTime(c.modCacheDir, modulePath, ver)
+			return published.Format(time.RFC3339Nano), err
+		}
+		published, err := getVersionTimeCtx(ctx, modulePath, ver)
+		return published.Format(time.RFC3339Nano), err
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339Nano, raw)
+}
+
+// lookup returns the value for key, calling fetch at most once per unique key.
+func (c *latestVerCache) lookup(ctx context.Context, key string, fetch func(context.Context) (string, error)) (string, error) {
 	c.mu.Lock()
-	entry, ok := c.entries[modulePath]
+	entry, ok := c.entries[key]
 	if !ok {
 		entry = &latestVerEntry{}
-		c.entries[modulePath] = entry
+		c.entries[key] = entry
 	}
 	c.mu.Unlock()
 
@@ -61,7 +205,7 @@ func (c *latestVerCache) get(ctx context.Context, modulePath string) (string, er
 		entry.waitCh = make(chan struct{})
 		entry.mu.Unlock()
 
-		version, err := getLatestVerCtx(ctx, modulePath)
+		version, err := fetch(ctx)
 
 		entry.mu.Lock()
 		entry.fetching = false
@@ -89,3 +233,51 @@ func (c *latestVerCache) get(ctx context.Context, modulePath string) (string, er
 		return version, err
 	}
 }
+
+// fetch returns the on-disk cached version if allowed, otherwise it queries
+// the latest version and records it in the on-disk cache.
+func (c *latestVerCache) fetch(ctx context.Context, modulePath string) (string, error) {
+	if c.offline() {
+		version, err := goutil.ModCacheLatestVersion(c.modCacheDir, modulePath)
+		return version, err
+	}
+	if c.store != nil && !c.refresh {
+		if version, ok := c.store.load(modulePath); ok {
+			return version, nil
+		}
+	}
+
+	version, err := getLatestVerCtx(ctx, modulePath)
+	if err != nil {
+		return "", err
+	}
+	if c.store != nil {
+		// The cache is only an optimization; a failed write must not fail the lookup.
+		_ = c.store.save(modulePath, version)
+	}
+	return version, nil
+}
+
+// offline reports whether versions are read only from the module cache.
+func (c *latestVerCache) offline() bool {
+	return c.modCacheDir != ""
+}
+
+// addLatestVerCacheFlags registers the flags that control the on-disk latest-version cache.
+func addLatestVerCacheFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("refresh", false, "ignore cached latest versions and query them again")
+	cmd.Flags().Duration("cache-ttl", defaultLatestVerCacheTTL, "how long cached latest versions are reused across runs (0 disables the cache)")
+}
+
+// latestVerCacheFromFlags builds a latestVerCache according to --refresh and --cache-ttl.
+func latestVerCacheFromFlags(cmd *cobra.Command) (*latestVerCache, error) {
+	refresh, err := getFlagBool(cmd, "refresh")
+	if err != nil {
+		return nil, err
+	}
+	ttl, err := getFlagDuration(cmd, "cache-ttl")
+	if err != nil {
+		return nil, err
+	}
+	return newPersistentLatestVerCache(openLatestVerStore(ttl), refresh), nil
+}
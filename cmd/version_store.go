@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/fileutil"
+)
+
+// defaultLatestVerCacheTTL is how long a latest-version lookup stays valid on disk.
+const defaultLatestVerCacheTTL = time.Hour
+
+// latestVerStoreDir returns the directory of the on-disk latest-version cache.
+// An empty string disables the on-disk cache.
+var latestVerStoreDir = config.CacheDirPath //nolint:gochecknoglobals // swapped in tests
+
+// latestVerStore persists latest-version lookups so that separate gup processes
+// (e.g. 'gup check' followed by 'gup update') do not query the module proxy twice.
+//
+// Each module is stored in its own file and every write goes through a temporary
+// file and a rename, so concurrent gup processes never observe a partially written
+// entry. When two processes race, the last writer wins, which is harmless because
+// both wrote a freshly fetched version.
+type latestVerStore struct {
+	dir string
+	ttl time.Duration
+	now func() time.Time
+}
+
+type latestVerStoreEntry struct {
+	ModulePath string    `json:"module_path"`
+	Version    string    `json:"version"`
+	FetchedAt  time.Time `json:"fetched_at"`
+	// NotFound records that the module does not exist (e.g. a probed major version).
+	NotFound bool `json:"not_found,omitempty"`
+}
+
+func newLatestVerStore(dir string, ttl time.Duration) *latestVerStore {
+	return &latestVerStore{dir: dir, ttl: ttl, now: time.Now}
+}
+
+// load returns the cached latest version of modulePath if it has not expired.
+func (s *latestVerStore) load(modulePath string) (string, bool) {
+	entry, ok := s.loadEntry(modulePath)
+	if !ok || entry.NotFound || strings.TrimSpace(entry.Version) == "" {
+		return "", false
+	}
+	return entry.Version, true
+}
+
+// loadNotFound reports whether modulePath is cached as not existing and the entry has not expired.
+func (s *latestVerStore) loadNotFound(modulePath string) bool {
+	entry, ok := s.loadEntry(modulePath)
+	return ok && entry.NotFound
+}
+
+// loadEntry returns the cache entry of modulePath if it has not expired.
+func (s *latestVerStore) loadEntry(modulePath string) (latestVerStoreEntry, bool) {
+	raw, err := os.ReadFile(s.entryPath(modulePath))
+	if err != nil {
+		return latestVerStoreEntry{}, false
+	}
+
+	entry := latestVerStoreEntry{}
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return latestVerStoreEntry{}, false
+	}
+	// Guard against hash collisions and hand-edited files.
+	if entry.ModulePath != modulePath {
+		return latestVerStoreEntry{}, false
+	}
+
+	age := s.now().Sub(entry.FetchedAt)
+	if age < 0 || age >= s.ttl {
+		return latestVerStoreEntry{}, false
+	}
+	return entry, true
+}
+
+// save stores the latest version of modulePath.
+func (s *latestVerStore) save(modulePath, version string) error {
+	return s.write(latestVerStoreEntry{ModulePath: modulePath, Version: version})
+}
+
+// saveNotFound records that modulePath does not exist, so that it is not
+// looked up again until the entry expires.
+func (s *latestVerStore) saveNotFound(modulePath string) error {
+	return s.write(latestVerStoreEntry{ModulePath: modulePath, NotFound: true})
+}
+
+// write stores entry, stamped with the current time.
+func (s *latestVerStore) write(entry latestVerStoreEntry) (err error) {
+	if err := os.MkdirAll(s.dir, fileutil.FileModeCreatingDir); err != nil {
+		return fmt.Errorf("%s: %w", "can not make cache directory", err)
+	}
+
+	modulePath := entry.ModulePath
+	entry.FetchedAt = s.now().UTC()
+	out, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("can't marshal cache entry for %s: %w", modulePath, err)
+	}
+
+	path := s.entryPath(modulePath)
+	file, err := os.CreateTemp(s.dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", "can't create temp file for", path, err)
+	}
+	tmpPath := file.Name()
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err = file.Write(out); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("%s %s: %w", "can't write cache entry", path, err)
+	}
+	if err = file.Close(); err != nil {
+		return fmt.Errorf("%s %s: %w", "can't close cache entry", path, err)
+	}
+	if err = fileutil.RenameWithReplace(tmpPath, path); err != nil {
+		return fmt.Errorf("%s %s: %w", "can't update", path, err)
+	}
+	return nil
+}
+
+// entryPath returns the cache file path of modulePath.
+// Module paths contain '/' and may differ only by case, so the file name is a hash.
+func (s *latestVerStore) entryPath(modulePath string) string {
+	sum := sha256.Sum256([]byte(modulePath))
+	return filepath.Join(s.dir, "latest-"+hex.EncodeToString(sum[:])+".json")
+}
+
+// openLatestVerStore returns the on-disk cache, or nil when the TTL or
+// the cache directory disables it.
+func openLatestVerStore(ttl time.Duration) *latestVerStore {
+	if ttl <= 0 {
+		return nil
+	}
+	dir := latestVerStoreDir()
+	if dir == "" {
+		return nil
+	}
+	return newLatestVerStore(dir, ttl)
+}
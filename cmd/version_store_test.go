@@ -0,0 +1,115 @@
+//nolint:paralleltest // tests mutate global function variables for stubbing
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_latestVerStore_saveAndLoad(t *testing.T) {
+	store := newLatestVerStore(filepath.Join(t.TempDir(), "cache"), time.Hour)
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	if _, ok := store.load("example.com/tool"); ok {
+		t.Fatal("latestVerStore.load() hit before save")
+	}
+	if err := store.save("example.com/tool", testVersionNine); err != nil {
+		t.Fatalf("latestVerStore.save() error = %v", err)
+	}
+
+	got, ok := store.load("example.com/tool")
+	if !ok || got != testVersionNine {
+		t.Fatalf("latestVerStore.load() = (%q, %v), want (%q, true)", got, ok, testVersionNine)
+	}
+	if _, ok := store.load("example.com/other"); ok {
+		t.Fatal("latestVerStore.load() hit for a module that was never saved")
+	}
+
+	now = now.Add(time.Hour)
+	if _, ok := store.load("example.com/tool"); ok {
+		t.Fatal("latestVerStore.load() hit after TTL expired")
+	}
+}
+
+func Test_latestVerStore_loadIgnoresBrokenEntry(t *testing.T) {
+	store := newLatestVerStore(t.TempDir(), time.Hour)
+
+	if err := os.WriteFile(store.entryPath("example.com/tool"), []byte("{broken"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.load("example.com/tool"); ok {
+		t.Fatal("latestVerStore.load() hit for a broken entry")
+	}
+
+	// An entry recorded for another module (e.g. a hash collision) must not be used.
+	if err := store.save("example.com/other", testVersionOne); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(store.entryPath("example.com/other"), store.entryPath("example.com/tool")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.load("example.com/tool"); ok {
+		t.Fatal("latestVerStore.load() hit for an entry of another module")
+	}
+}
+
+func Test_openLatestVerStore(t *testing.T) {
+	origDir := latestVerStoreDir
+	defer func() { latestVerStoreDir = origDir }()
+
+	dir := t.TempDir()
+	latestVerStoreDir = func() string { return dir }
+	if store := openLatestVerStore(time.Minute); store == nil || store.dir != dir {
+		t.Fatalf("openLatestVerStore() = %v, want store in %s", store, dir)
+	}
+	if store := openLatestVerStore(0); store != nil {
+		t.Fatalf("openLatestVerStore(0) = %v, want nil", store)
+	}
+
+	latestVerStoreDir = func() string { return "" }
+	if store := openLatestVerStore(time.Minute); store != nil {
+		t.Fatalf("openLatestVerStore() with empty dir = %v, want nil", store)
+	}
+}
+
+func Test_latestVerCache_get_sharedAcrossProcesses(t *testing.T) {
+	origGetLatestVerCtx := getLatestVerCtx
+	defer func() { getLatestVerCtx = origGetLatestVerCtx }()
+
+	callCount := 0
+	getLatestVerCtx = func(context.Context, string) (string, error) {
+		callCount++
+		return testVersionNine, nil
+	}
+
+	dir := t.TempDir()
+	// Each cache stands in for a separate gup process sharing the same directory.
+	first := newPersistentLatestVerCache(newLatestVerStore(dir, time.Hour), false)
+	if _, err := first.get(context.Background(), "example.com/tool"); err != nil {
+		t.Fatal(err)
+	}
+
+	second := newPersistentLatestVerCache(newLatestVerStore(dir, time.Hour), false)
+	got, err := second.get(context.Background(), "example.com/tool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != testVersionNine {
+		t.Fatalf("latestVerCache.get() = %q, want %q", got, testVersionNine)
+	}
+	if callCount != 1 {
+		t.Fatalf("getLatestVerCtx call count = %d, want 1", callCount)
+	}
+
+	refreshed := newPersistentLatestVerCache(newLatestVerStore(dir, time.Hour), true)
+	if _, err := refreshed.get(context.Background(), "example.com/tool"); err != nil {
+		t.Fatal(err)
+	}
+	if callCount != 2 {
+		t.Fatalf("getLatestVerCtx call count with refresh = %d, want 2", callCount)
+	}
+}
@@ -0,0 +1,399 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+	"github.com/nao1215/gup/internal/vulndb"
+	"github.com/spf13/cobra"
+)
+
+// getModuleRequirementsCtx reads the requirements of the version that 'gup update' installs.
+var getModuleRequirementsCtx = goutil.GetModuleRequirementsWithContext //nolint:gochecknoglobals // swapped in tests
+
+// vulnDBEnv is the environment variable that govulncheck also reads the database URL from.
+const vulnDBEnv = "GOVULNDB"
+
+func newVulnCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "vuln [binary ...]",
+		Short: "Report known vulnerabilities in the binaries under $GOPATH/bin or $GOBIN",
+		Long: `Report known vulnerabilities in the binaries under $GOPATH/bin or $GOBIN.
+
+vuln matches the main module, the dependencies and the Go version recorded
+in each binary against the Go vulnerability database, and reports whether
+'gup update' would install a version without the vulnerability.
+The database is read from --db, $GOVULNDB or https://vuln.go.dev.
+A local directory or a file:// URL that mirrors the database works offline;
+with --offline, the versions 'gup update' would install are also read only
+from the module cache.
+vuln exits with a non-zero status if it finds a vulnerability.`,
+		ValidArgsFunction: completePathBinaries,
+		Run: func(cmd *cobra.Command, args []string) {
+			OsExit(vuln(cmd, args))
+		},
+	}
+	addVulnDBFlag(cmd)
+	addLatestVerCacheFlags(cmd)
+	addOfflineFlag(cmd)
+	addOutputFlag(cmd)
+
+	return cmd
+}
+
+// addVulnDBFlag registers --db, the URL or directory of the vulnerability database.
+func addVulnDBFlag(cmd *cobra.Command) {
+	cmd.Flags().String("db", "", "vulnerability database URL or local directory (default $GOVULNDB or "+vulndb.DefaultURL+")")
+	if err := cmd.MarkFlagDirname("db"); err != nil {
+		panic(err)
+	}
+}
+
+// newVulnDBFromFlags returns the vulnerability database selected by --db or $GOVULNDB.
+func newVulnDBFromFlags(cmd *cobra.Command) (*vulndb.Client, error) {
+	dbURL, err := getFlagString(cmd, "db")
+	if err != nil {
+		return nil, err
+	}
+	if dbURL == "" {
+		dbURL = os.Getenv(vulnDBEnv)
+	}
+	return vulndb.New(vulndb.Config{URL: dbURL})
+}
+
+func vuln(cmd *cobra.Command, args []string) int {
+	report, err := openReportWriter(cmd, "vuln")
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	return report.close(runVuln(cmd, args, report))
+}
+
+func runVuln(cmd *cobra.Command, args []string, report *reportWriter) int {
+	if err := ensureGoCommandAvailable(); err != nil {
+		print.Err(err)
+		return 1
+	}
+
+	db, err := newVulnDBFromFlags(cmd)
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+
+	verCache, err := latestVerCacheFromFlags(cmd)
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	if err := setupOfflineMode(cmd, verCache); err != nil {
+		print.Err(err)
+		return 1
+	}
+
+	pkgs, err := getPackageInfoByTargets(args)
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	pkgs = extractUserSpecifyPkg(pkgs, args)
+	if len(pkgs) == 0 {
+		print.Err("unable to scan package: no package information")
+		return 1
+	}
+
+	confPath := config.ResolveImportFilePath("")
+	confPkgs, err := readConfFileIfExists(confPath)
+	if err != nil {
+		print.Warn(fmt.Sprintf("failed to read %s: %s (continuing without config)", confPath, err))
+		confPkgs = []goutil.Package{}
+	}
+	channelMap, err := resolveUpdateChannels(pkgs, confPkgs, nil, nil, nil, nil, nil)
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	pkgs = applyToolchains(pkgs, confPkgs)
+
+	ctx, cancel, signals := newSignalCancelContext()
+	defer stopSignalCancelContext(cancel, signals)
+	return doVuln(ctx, db, pkgs, updateOptions{
+		channelMap: channelMap,
+		rules:      resolveVersionRules(pkgs, confPkgs, "", cooldownOption{}, false),
+		verCache:   verCache,
+		report:     report,
+	})
+}
+
+func doVuln(ctx context.Context, db *vulndb.Client, pkgs []goutil.Package, opts updateOptions) int {
+	scanner, err := newVulnScanner(ctx, db)
+	if err != nil {
+		print.Err(err)
+		return 1
+	}
+	verCache := opts.verCache
+	if verCache == nil {
+		verCache = newLatestVerCache()
+	}
+
+	total, fixable := 0, 0
+	fixableNames := []string{}
+	affected := 0
+	for _, p := range pkgs {
+		findings, err := scanner.scan(ctx, p)
+		if err != nil {
+			print.Err(err)
+			return 1
+		}
+		if len(findings) == 0 {
+			opts.report.add(newPackageReport(p, statusInstalled))
+			continue
+		}
+		// resolveUpdate records the latest version in p.Version.
+		p.Version = &goutil.Version{Current: p.Version.Current}
+		r := resolveUpdate(ctx, p, opts, verCache)
+		setVulnFixStatuses(ctx, findings, p, r)
+		vulnReport := newPackageReport(p, statusVulnerable)
+		vulnReport.Channel = string(r.channel)
+		vulnReport.Vulns = newVulnReports(findings)
+		opts.report.add(vulnReport)
+
+		affected++
+		fixedAll := true
+		for _, f := range findings {
+			print.Info(fmt.Sprintf("[%s] %s: %s", f.id, p.Name, f))
+			total++
+			if f.fix == vulnFixedByUpdate {
+				fixable++
+			} else {
+				fixedAll = false
+			}
+		}
+		if fixedAll {
+			fixableNames = append(fixableNames, p.Name)
+		}
+	}
+
+	if total == 0 {
+		print.Info("no known vulnerabilities in the installed binaries")
+		return 0
+	}
+	print.Err(fmt.Sprintf("%d vulnerabilities in %d binaries, %d of them fixed by 'gup update'", total, affected, fixable))
+	if len(fixableNames) != 0 {
+		const indentSpaces = 11
+		print.Info("")
+		print.Info("If you want to update the binaries that 'gup update' fixes, run the following command.\n" +
+			strings.Repeat(" ", indentSpaces) +
+			"$ gup update " + strings.Join(fixableNames, " "))
+	}
+	return 1
+}
+
+// vulnFixStatus tells whether 'gup update' installs a build without a vulnerability.
+// The value appears in the JSON output of 'gup check'.
+type vulnFixStatus string
+
+const (
+	vulnFixedByUpdate vulnFixStatus = "fixed"
+	vulnNotFixed      vulnFixStatus = "not-fixed"
+	vulnFixUnknown    vulnFixStatus = "unknown"
+)
+
+// String returns the human-readable fix status.
+func (s vulnFixStatus) String() string {
+	switch s {
+	case vulnFixedByUpdate:
+		return "fixed by 'gup update'"
+	case vulnNotFixed:
+		return "not fixed by 'gup update'"
+	default:
+		return "unknown whether 'gup update' fixes it"
+	}
+}
+
+// vulnFinding is a vulnerability that affects a module in a binary.
+type vulnFinding struct {
+	id      string
+	summary string
+	// modulePath is the affected module, or vulndb.Stdlib.
+	modulePath string
+	version    string
+	// fixedIn is the lowest version without the vulnerability. Empty means no fix.
+	fixedIn string
+	fix     vulnFixStatus
+	entry   *vulndb.Entry
+}
+
+// String returns "<module>@<version> (fixed in <version>, <fix status>): <summary>".
+func (f vulnFinding) String() string {
+	fixedIn := "no fixed version"
+	if f.fixedIn != "" {
+		fixedIn = "fixed in " + f.fixedIn
+	}
+	s := fmt.Sprintf("%s@%s (%s, %s)", f.modulePath, f.version, fixedIn, f.fix)
+	if f.summary != "" {
+		s += ": " + f.summary
+	}
+	return s
+}
+
+// vulnModule is a module version that a binary is built with.
+type vulnModule struct {
+	path    string
+	version string
+}
+
+// binaryModules returns the main module, the dependencies and the standard
+// library that p is built with. A dependency replaced by another module is
+// reported as the replacement; a dependency replaced by a directory is skipped.
+func binaryModules(p goutil.Package) []vulnModule {
+	mods := []vulnModule{}
+	if p.ModulePath != "" && p.Version != nil && p.Version.Current != "" && p.Version.Current != "(devel)" {
+		mods = append(mods, vulnModule{path: p.ModulePath, version: p.Version.Current})
+	}
+	for _, dep := range p.Deps {
+		if dep == nil {
+			continue
+		}
+		switch {
+		case dep.Replace == nil:
+			mods = append(mods, vulnModule{path: dep.Path, version: dep.Version})
+		case dep.Replace.Version != "":
+			mods = append(mods, vulnModule{path: dep.Replace.Path, version: dep.Replace.Version})
+		}
+	}
+	if p.GoVersion != nil && vulndb.GoVersionToSemver(p.GoVersion.Current) != "" {
+		mods = append(mods, vulnModule{path: vulndb.Stdlib, version: p.GoVersion.Current})
+	}
+	return mods
+}
+
+// vulnScanner matches binaries against the vulnerability database. It reads
+// the module index once and each OSV entry at most once.
+type vulnScanner struct {
+	db      *vulndb.Client
+	index   map[string]vulndb.ModuleVulns
+	mu      sync.Mutex
+	entries map[string]*vulndb.Entry
+}
+
+func newVulnScanner(ctx context.Context, db *vulndb.Client) (*vulnScanner, error) {
+	modules, err := db.Modules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't read the vulnerability database: %w", err)
+	}
+	index := make(map[string]vulndb.ModuleVulns, len(modules))
+	for _, m := range modules {
+		index[m.Path] = m
+	}
+	return &vulnScanner{db: db, index: index, entries: map[string]*vulndb.Entry{}}, nil
+}
+
+func (s *vulnScanner) entry(ctx context.Context, id string) (*vulndb.Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[id]; ok {
+		return e, nil
+	}
+	e, err := s.db.Entry(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("can't read the vulnerability database: %w", err)
+	}
+	s.entries[id] = e
+	return e, nil
+}
+
+// scan returns the vulnerabilities that affect p, ordered by ID. Their fix
+// status is unknown until setVulnFixStatuses.
+func (s *vulnScanner) scan(ctx context.Context, p goutil.Package) ([]vulnFinding, error) {
+	findings := []vulnFinding{}
+	for _, mod := range binaryModules(p) {
+		m, ok := s.index[mod.path]
+		if !ok {
+			continue
+		}
+		for _, candidate := range m.Candidates(mod.version) {
+			e, err := s.entry(ctx, candidate.ID)
+			if err != nil {
+				return nil, err
+			}
+			if !e.Affects(mod.path, mod.version) {
+				continue
+			}
+			findings = append(findings, vulnFinding{
+				id:         e.ID,
+				summary:    e.Summary,
+				modulePath: mod.path,
+				version:    mod.version,
+				fixedIn:    e.FixedVersion(mod.path, mod.version),
+				fix:        vulnFixUnknown,
+				entry:      e,
+			})
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].id < findings[j].id })
+	return findings, nil
+}
+
+// setVulnFixStatuses decides whether the update r of the installed package p
+// fixes each finding. The update rebuilds the binary with the installed Go,
+// and with the dependency versions required by the go.mod of the target version.
+func setVulnFixStatuses(ctx context.Context, findings []vulnFinding, p goutil.Package, r resolvedUpdate) {
+	target := r.target.version
+	if target == "" && r.pkg.Version != nil {
+		target = r.pkg.Version.Latest
+	}
+
+	var reqs map[string]string
+	var reqsErr error
+	reqsRead := false
+	for i := range findings {
+		f := &findings[i]
+		switch {
+		case r.err != nil:
+			f.fix = vulnFixUnknown
+		case !r.shouldUpdate:
+			f.fix = vulnNotFixed
+		case f.modulePath == vulndb.Stdlib:
+			f.fix = fixStatusOf(f, p.GoVersion.Latest)
+		case r.modulePathChanged || target == "":
+			f.fix = vulnFixUnknown
+		case f.modulePath == p.ModulePath:
+			f.fix = fixStatusOf(f, target)
+		case target == p.Version.Current:
+			// The dependencies do not change when only the Go toolchain is updated.
+			f.fix = vulnNotFixed
+		default:
+			if !reqsRead {
+				reqs, reqsErr = getModuleRequirementsCtx(ctx, r.pkg.ModulePath, target)
+				reqsRead = true
+			}
+			ver, ok := reqs[f.modulePath]
+			if reqsErr != nil || !ok {
+				f.fix = vulnFixUnknown
+				continue
+			}
+			f.fix = fixStatusOf(f, ver)
+		}
+	}
+}
+
+// fixStatusOf returns whether the module of f at ver is free of the vulnerability.
+func fixStatusOf(f *vulnFinding, ver string) vulnFixStatus {
+	if f.modulePath == vulndb.Stdlib && vulndb.GoVersionToSemver(ver) == "" ||
+		f.modulePath != vulndb.Stdlib && !strings.HasPrefix(ver, "v") {
+		return vulnFixUnknown
+	}
+	if f.entry.Affects(f.modulePath, ver) {
+		return vulnNotFixed
+	}
+	return vulnFixedByUpdate
+}
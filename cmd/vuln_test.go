@@ -0,0 +1,203 @@
+//nolint:paralleltest // tests mutate global variables
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/vulndb"
+)
+
+// writeTestVulnDB writes a vulnerability database with a golang.org/x/net
+// vulnerability fixed in v0.17.0 and a standard library vulnerability fixed
+// in go1.22.4, and returns its directory.
+func writeTestVulnDB(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "index", "modules.json"), `[
+		{"path":"golang.org/x/net","vulns":[{"id":"GO-2023-2102","modified":"2023-10-11T00:00:00Z","fixed":"0.17.0"}]},
+		{"path":"stdlib","vulns":[{"id":"GO-2024-2887","modified":"2024-06-04T00:00:00Z","fixed":"1.22.4"}]}
+	]`)
+	writeTestFile(t, filepath.Join(dir, "ID", "GO-2023-2102.json"), `{
+		"id":"GO-2023-2102","summary":"HTTP/2 rapid reset can cause excessive work in net/http",
+		"affected":[{"package":{"name":"golang.org/x/net"},
+			"ranges":[{"type":"SEMVER","events":[{"introduced":"0"},{"fixed":"0.17.0"}]}]}]
+	}`)
+	writeTestFile(t, filepath.Join(dir, "ID", "GO-2024-2887.json"), `{
+		"id":"GO-2024-2887","summary":"Unexpected behavior from Is methods for IPv4-mapped IPv6 addresses in net/netip",
+		"affected":[{"package":{"name":"stdlib"},
+			"ranges":[{"type":"SEMVER","events":[{"introduced":"0"},{"fixed":"1.21.11"},{"introduced":"1.22.0-0"},{"fixed":"1.22.4"}]}]}]
+	}`)
+	return dir
+}
+
+func newTestVulnDB(t *testing.T) *vulndb.Client {
+	t.Helper()
+	db, err := vulndb.New(vulndb.Config{URL: writeTestVulnDB(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// stubVulnUpdate makes example.com/fixed v1.1.0 require golang.org/x/net v0.17.0,
+// and every other module up to date.
+func stubVulnUpdate(t *testing.T) {
+	t.Helper()
+	origGetLatest := getLatestVerCtx
+	origGetReqs := getModuleRequirementsCtx
+	t.Cleanup(func() {
+		getLatestVerCtx = origGetLatest
+		getModuleRequirementsCtx = origGetReqs
+	})
+	getLatestVerCtx = func(_ context.Context, modulePath string) (string, error) {
+		switch modulePath {
+		case "example.com/fixed":
+			return "v1.1.0", nil
+		case "example.com/broken":
+			return "", errors.New("not found")
+		}
+		return "v1.0.0", nil
+	}
+	getModuleRequirementsCtx = func(_ context.Context, modulePath, ver string) (map[string]string, error) {
+		if modulePath == "example.com/fixed" && ver == "v1.1.0" {
+			return map[string]string{"golang.org/x/net": "v0.17.0"}, nil
+		}
+		return nil, errors.New("unexpected requirement lookup of " + modulePath + "@" + ver)
+	}
+}
+
+func newVulnTestPackage(name, goVer string, deps ...*debug.Module) goutil.Package {
+	return goutil.Package{
+		Name:       name,
+		ImportPath: "example.com/" + name,
+		ModulePath: "example.com/" + name,
+		Version:    &goutil.Version{Current: "v1.0.0"},
+		GoVersion:  &goutil.Version{Current: goVer, Latest: "go1.22.5"},
+		Deps:       deps,
+	}
+}
+
+func Test_binaryModules(t *testing.T) {
+	p := newVulnTestPackage("tool", "go1.22.3",
+		&debug.Module{Path: "golang.org/x/net", Version: "v0.15.0"},
+		&debug.Module{Path: "golang.org/x/text", Version: "v0.3.0", Replace: &debug.Module{Path: "example.com/text", Version: "v0.4.0"}},
+		&debug.Module{Path: "golang.org/x/sys", Version: "v0.1.0", Replace: &debug.Module{Path: "../sys"}},
+	)
+	want := []vulnModule{
+		{path: "example.com/tool", version: "v1.0.0"},
+		{path: "golang.org/x/net", version: "v0.15.0"},
+		{path: "example.com/text", version: "v0.4.0"},
+		{path: vulndb.Stdlib, version: "go1.22.3"},
+	}
+	if diff := cmp.Diff(want, binaryModules(p), cmp.AllowUnexported(vulnModule{})); diff != "" {
+		t.Errorf("binaryModules() mismatch (-want +got):\n%s", diff)
+	}
+
+	p.Version.Current = "(devel)"
+	p.GoVersion.Current = "unknown"
+	p.Deps = nil
+	if got := binaryModules(p); len(got) != 0 {
+		t.Errorf("binaryModules() of a devel build = %v, want none", got)
+	}
+}
+
+func Test_doVuln(t *testing.T) {
+	stubVulnUpdate(t)
+	out := captureStdout(t)
+
+	net := &debug.Module{Path: "golang.org/x/net", Version: "v0.15.0"}
+	pkgs := []goutil.Package{
+		newVulnTestPackage("fixed", "go1.22.5", net),
+		newVulnTestPackage("stale", "go1.22.3", net),
+		newVulnTestPackage("clean", "go1.22.5", &debug.Module{Path: "golang.org/x/net", Version: "v0.17.0"}),
+		newVulnTestPackage("broken", "go1.22.5", net),
+	}
+	if got := doVuln(context.Background(), newTestVulnDB(t), pkgs, updateOptions{}); got != 1 {
+		t.Errorf("doVuln() = %d, want 1", got)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	want := []string{
+		"[GO-2023-2102] fixed: golang.org/x/net@v0.15.0 (fixed in v0.17.0, fixed by 'gup update'): " +
+			"HTTP/2 rapid reset can cause excessive work in net/http",
+		"[GO-2023-2102] stale: golang.org/x/net@v0.15.0 (fixed in v0.17.0, not fixed by 'gup update'): " +
+			"HTTP/2 rapid reset can cause excessive work in net/http",
+		"[GO-2024-2887] stale: stdlib@go1.22.3 (fixed in go1.22.4, fixed by 'gup update'): " +
+			"Unexpected behavior from Is methods for IPv4-mapped IPv6 addresses in net/netip",
+		"[GO-2023-2102] broken: golang.org/x/net@v0.15.0 (fixed in v0.17.0, unknown whether 'gup update' fixes it): " +
+			"HTTP/2 rapid reset can cause excessive work in net/http",
+		"",
+		"If you want to update the binaries that 'gup update' fixes, run the following command.",
+		"           $ gup update fixed",
+	}
+	if diff := cmp.Diff(want, lines); diff != "" {
+		t.Errorf("doVuln() output mismatch (-want +got):\n%s", diff)
+	}
+	if pkgs[0].Version.Latest != "" {
+		t.Errorf("doVuln() changed the version of the package to %q", pkgs[0].Version.Latest)
+	}
+}
+
+func Test_doVuln_output(t *testing.T) {
+	stubVulnUpdate(t)
+	out := captureStdout(t)
+
+	w, err := openReportWriter(newOutputTestCmd(t, outputFormatJSON), "vuln")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkgs := []goutil.Package{
+		newVulnTestPackage("fixed", "go1.22.5", &debug.Module{Path: "golang.org/x/net", Version: "v0.15.0"}),
+		newVulnTestPackage("clean", "go1.22.5", &debug.Module{Path: "golang.org/x/net", Version: "v0.17.0"}),
+	}
+	if got := w.close(doVuln(context.Background(), newTestVulnDB(t), pkgs, updateOptions{report: w})); got != 1 {
+		t.Errorf("doVuln() = %d, want 1", got)
+	}
+
+	var doc outputDocument
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out.String())
+	}
+	if doc.Command != "vuln" || doc.ExitCode != 1 || len(doc.Packages) != 2 {
+		t.Fatalf("output = %+v, want both binaries of the vuln command", doc)
+	}
+	fixed, clean := doc.Packages[0], doc.Packages[1]
+	if fixed.Status != statusVulnerable || len(fixed.Vulns) != 1 ||
+		fixed.Vulns[0].ID != "GO-2023-2102" || fixed.Vulns[0].Fix != vulnFixedByUpdate {
+		t.Errorf("fixed = %+v, want GO-2023-2102 fixed by 'gup update'", fixed)
+	}
+	if clean.Status != statusInstalled || len(clean.Vulns) != 0 {
+		t.Errorf("clean = %+v, want no vulnerabilities", clean)
+	}
+}
+
+func Test_doVuln_noVulnerability(t *testing.T) {
+	stubVulnUpdate(t)
+	out := captureStdout(t)
+
+	pkgs := []goutil.Package{newVulnTestPackage("clean", "go1.22.5")}
+	if got := doVuln(context.Background(), newTestVulnDB(t), pkgs, updateOptions{}); got != 0 {
+		t.Errorf("doVuln() = %d, want 0", got)
+	}
+	if got := out.String(); !strings.Contains(got, "no known vulnerabilities") {
+		t.Errorf("doVuln() output = %q", got)
+	}
+}
+
+func Test_doVuln_missingDB(t *testing.T) {
+	db, err := vulndb.New(vulndb.Config{URL: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := doVuln(context.Background(), db, []goutil.Package{newVulnTestPackage("clean", "go1.22.5")}, updateOptions{}); got != 1 {
+		t.Errorf("doVuln() = %d, want 1", got)
+	}
+}
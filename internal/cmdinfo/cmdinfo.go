@@ -14,6 +14,12 @@ const Name = "gup"
 // GetVersion return gup command version.
 // Version global variable is set by ldflags.
 func GetVersion() string {
+	return fmt.Sprintf("%s version %s (under Apache License version 2.0)", Name, GetVersionNumber())
+}
+
+// GetVersionNumber return gup command version without the command name,
+// such as "v1.0.0" or "(devel)".
+func GetVersionNumber() string {
 	version := "(devel)"
 	if Version != "" {
 		version = Version
@@ -22,5 +28,5 @@ func GetVersion() string {
 			version = buildInfo.Main.Version
 		}
 	}
-	return fmt.Sprintf("%s version %s (under Apache License version 2.0)", Name, version)
+	return version
 }
@@ -27,5 +27,8 @@ func TestGetVersion(t *testing.T) {
 		if !strings.Contains(got, "1.2.3") {
 			t.Errorf("GetVersion() = %q, should contain version 1.2.3", got)
 		}
+		if got := GetVersionNumber(); got != "1.2.3" {
+			t.Errorf("GetVersionNumber() = %q, want 1.2.3", got)
+		}
 	})
 }
@@ -22,15 +22,66 @@ const ConfigFileName = "gup.json"
 const configSchemaVersion = 1
 
 type configFile struct {
-	SchemaVersion int             `json:"schema_version"`
-	Packages      []configPackage `json:"packages"`
+	SchemaVersion int `json:"schema_version"`
+	// CooldownDays is the default release cooldown of every package.
+	CooldownDays *int            `json:"cooldown_days,omitempty"`
+	Packages     []configPackage `json:"packages"`
+}
+
+// Settings are the top-level settings of gup.json, which apply to every package.
+type Settings struct {
+	// CooldownDays is the default release cooldown. The cooldown_days of a
+	// package overrides it. Nil means not set.
+	CooldownDays *int
 }
 
 type configPackage struct {
-	Name       string `json:"name"`
-	ImportPath string `json:"import_path"`
-	Version    string `json:"version"`
-	Channel    string `json:"channel"`
+	Name         string       `json:"name"`
+	ImportPath   string       `json:"import_path"`
+	Version      string       `json:"version"`
+	Channel      string       `json:"channel"`
+	Policy       string       `json:"policy,omitempty"`
+	CooldownDays *int         `json:"cooldown_days,omitempty"`
+	Pin          string       `json:"pin,omitempty"`
+	AllowMajor   bool         `json:"allow_major,omitempty"`
+	Build        *configBuild `json:"build,omitempty"`
+	Toolchain    string       `json:"toolchain,omitempty"`
+	Sum          string       `json:"sum,omitempty"`
+}
+
+// configBuild is the "build" object of a package entry.
+type configBuild struct {
+	Ldflags    string            `json:"ldflags,omitempty"`
+	Tags       []string          `json:"tags,omitempty"`
+	CGOEnabled *bool             `json:"cgo_enabled,omitempty"`
+	Trimpath   bool              `json:"trimpath,omitempty"`
+	Env        map[string]string `json:"env,omitempty"`
+}
+
+func (b *configBuild) settings() goutil.BuildSettings {
+	if b == nil {
+		return goutil.BuildSettings{}
+	}
+	return goutil.BuildSettings{
+		Ldflags:    b.Ldflags,
+		Tags:       b.Tags,
+		CGOEnabled: b.CGOEnabled,
+		Trimpath:   b.Trimpath,
+		Env:        b.Env,
+	}
+}
+
+func newConfigBuild(b goutil.BuildSettings) *configBuild {
+	if b.IsZero() {
+		return nil
+	}
+	return &configBuild{
+		Ldflags:    b.Ldflags,
+		Tags:       b.Tags,
+		CGOEnabled: b.CGOEnabled,
+		Trimpath:   b.Trimpath,
+		Env:        b.Env,
+	}
 }
 
 // FilePath return configuration-file path.
@@ -49,6 +100,19 @@ func DirPath() string {
 	return filepath.Join(xdg.ConfigHome, cmdinfo.Name)
 }
 
+// CacheDirPath return directory path that store cached data, such as the
+// latest module versions shared between gup processes.
+// Default path is $HOME/.config/gup/cache.
+func CacheDirPath() string {
+	return filepath.Join(DirPath(), "cache")
+}
+
+// BackupDirPath return directory path that store backups of binaries replaced by 'gup update'.
+// Default path is $HOME/.local/share/gup/backup.
+func BackupDirPath() string {
+	return filepath.Join(xdg.DataHome, cmdinfo.Name, "backup")
+}
+
 // ResolveImportFilePath resolves config file path for import.
 // Priority: explicit path > default config path (if exists) > ./gup.json (if exists) > default config path.
 func ResolveImportFilePath(explicitPath string) string {
@@ -79,22 +143,44 @@ func ResolveExportFilePath(explicitPath string) string {
 	return FilePath()
 }
 
-// ReadConfFile return contents of configuration-file (package information)
-func ReadConfFile(path string) ([]goutil.Package, error) {
+// readConfigFile reads and checks the top level of the configuration-file.
+// An empty file has no settings and no packages.
+func readConfigFile(path string) (configFile, error) {
 	raw, err := os.ReadFile(filepath.Clean(path))
 	if err != nil {
-		return nil, fmt.Errorf("can't read %s: %w", path, err)
+		return configFile{}, fmt.Errorf("can't read %s: %w", path, err)
 	}
 	if len(bytes.TrimSpace(raw)) == 0 {
-		return []goutil.Package{}, nil
+		return configFile{SchemaVersion: configSchemaVersion}, nil
 	}
 
 	conf := configFile{}
 	if err := json.Unmarshal(raw, &conf); err != nil {
-		return nil, fmt.Errorf("%s is not valid JSON: %w", path, err)
+		return configFile{}, fmt.Errorf("%s is not valid JSON: %w", path, err)
 	}
 	if conf.SchemaVersion != configSchemaVersion {
-		return nil, fmt.Errorf("%s has unsupported schema_version: %d", path, conf.SchemaVersion)
+		return configFile{}, fmt.Errorf("%s has unsupported schema_version: %d", path, conf.SchemaVersion)
+	}
+	if conf.CooldownDays != nil && *conf.CooldownDays < 0 {
+		return configFile{}, fmt.Errorf("%s contains invalid cooldown_days: must not be negative", path)
+	}
+	return conf, nil
+}
+
+// ReadSettings returns the top-level settings of the configuration-file.
+func ReadSettings(path string) (Settings, error) {
+	conf, err := readConfigFile(path)
+	if err != nil {
+		return Settings{}, err
+	}
+	return Settings{CooldownDays: conf.CooldownDays}, nil
+}
+
+// ReadConfFile return contents of configuration-file (package information)
+func ReadConfFile(path string) ([]goutil.Package, error) {
+	conf, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
 	}
 
 	pkgs := make([]goutil.Package, 0, len(conf.Packages))
@@ -105,6 +191,35 @@ func ReadConfFile(path string) ([]goutil.Package, error) {
 		if name == "" || importPath == "" || version == "" {
 			return nil, fmt.Errorf("%s contains invalid package entry at index %d", path, i)
 		}
+		policy, err := goutil.ParseUpdatePolicy(v.Policy)
+		if err != nil {
+			return nil, fmt.Errorf("%s contains invalid package entry at index %d: %w", path, i, err)
+		}
+		if v.CooldownDays != nil && *v.CooldownDays < 0 {
+			return nil, fmt.Errorf("%s contains invalid package entry at index %d: cooldown_days must not be negative", path, i)
+		}
+		build := v.Build.settings()
+		if err := build.Validate(); err != nil {
+			return nil, fmt.Errorf("%s contains invalid package entry at index %d: %w", path, i, err)
+		}
+		toolchain := strings.TrimSpace(v.Toolchain)
+		if toolchain != "" {
+			if err := goutil.ValidateToolchain(toolchain); err != nil {
+				return nil, fmt.Errorf("%s contains invalid package entry at index %d: %w", path, i, err)
+			}
+		}
+		pin := strings.TrimSpace(v.Pin)
+		if pin != "" {
+			if err := goutil.ValidatePin(pin); err != nil {
+				return nil, fmt.Errorf("%s contains invalid package entry at index %d: pin of %s: %w", path, i, name, err)
+			}
+		}
+		sum := strings.TrimSpace(v.Sum)
+		if sum != "" {
+			if err := goutil.ValidateSum(sum); err != nil {
+				return nil, fmt.Errorf("%s contains invalid package entry at index %d: %w", path, i, err)
+			}
+		}
 
 		binVer := goutil.Version{Current: version, Latest: ""}
 		goVer := goutil.Version{Current: "<from gup.json>", Latest: ""}
@@ -114,6 +229,13 @@ func ReadConfFile(path string) ([]goutil.Package, error) {
 			Version:       pointer.Ptr(binVer),
 			GoVersion:     pointer.Ptr(goVer),
 			UpdateChannel: goutil.NormalizeUpdateChannel(v.Channel),
+			Policy:        policy,
+			CooldownDays:  v.CooldownDays,
+			Pin:           pin,
+			AllowMajor:    v.AllowMajor,
+			Build:         build,
+			Toolchain:     toolchain,
+			Sum:           sum,
 		})
 	}
 
@@ -122,8 +244,15 @@ func ReadConfFile(path string) ([]goutil.Package, error) {
 
 // WriteConfFile write package information at configuration-file.
 func WriteConfFile(file io.Writer, pkgs []goutil.Package) error {
+	return WriteConfFileWithSettings(file, pkgs, Settings{})
+}
+
+// WriteConfFileWithSettings writes the top-level settings and package
+// information at configuration-file.
+func WriteConfFileWithSettings(file io.Writer, pkgs []goutil.Package, settings Settings) error {
 	conf := configFile{
 		SchemaVersion: configSchemaVersion,
+		CooldownDays:  settings.CooldownDays,
 		Packages:      make([]configPackage, 0, len(pkgs)),
 	}
 
@@ -134,10 +263,17 @@ func WriteConfFile(file io.Writer, pkgs []goutil.Package) error {
 		}
 		channel := goutil.NormalizeUpdateChannel(string(v.UpdateChannel))
 		conf.Packages = append(conf.Packages, configPackage{
-			Name:       v.Name,
-			ImportPath: v.ImportPath,
-			Version:    version,
-			Channel:    string(channel),
+			Name:         v.Name,
+			ImportPath:   v.ImportPath,
+			Version:      version,
+			Channel:      string(channel),
+			Policy:       string(v.Policy),
+			CooldownDays: v.CooldownDays,
+			Pin:          v.Pin,
+			AllowMajor:   v.AllowMajor,
+			Build:        newConfigBuild(v.Build),
+			Toolchain:    v.Toolchain,
+			Sum:          v.Sum,
 		})
 	}
 
@@ -4,10 +4,13 @@ import (
 	"bytes"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/adrg/xdg"
 	"github.com/nao1215/gup/internal/goutil"
+	"github.com/shogo82148/pointer"
 )
 
 func withTempXDG(t *testing.T) func() {
@@ -46,6 +49,14 @@ func TestDirAndFilePath(t *testing.T) { //nolint:paralleltest // modifies xdg gl
 	if got := FilePath(); got != filepath.Join(xdg.ConfigHome, "gup", ConfigFileName) {
 		t.Fatalf("FilePath() = %s, want %s", got, filepath.Join(xdg.ConfigHome, "gup", ConfigFileName))
 	}
+
+	if got := CacheDirPath(); got != filepath.Join(xdg.ConfigHome, "gup", "cache") {
+		t.Fatalf("CacheDirPath() = %s, want %s", got, filepath.Join(xdg.ConfigHome, "gup", "cache"))
+	}
+
+	if got := BackupDirPath(); got != filepath.Join(xdg.DataHome, "gup", "backup") {
+		t.Fatalf("BackupDirPath() = %s, want %s", got, filepath.Join(xdg.DataHome, "gup", "backup"))
+	}
 }
 
 func TestWriteConfFile(t *testing.T) {
@@ -58,15 +69,27 @@ func TestWriteConfFile(t *testing.T) {
 			ImportPath:    "example.com/foo",
 			Version:       &goutil.Version{Current: "v1.2.3"},
 			UpdateChannel: goutil.UpdateChannelMain,
+			Pin:           "v1.2.3",
 		},
 		{
-			Name:       "bar",
-			ImportPath: "example.com/bar",
+			Name:         "bar",
+			ImportPath:   "example.com/bar",
+			Policy:       goutil.UpdatePolicyPatch,
+			CooldownDays: pointer.Ptr(3),
 		},
 		{
 			Name:       "baz",
 			ImportPath: "example.com/baz",
 			Version:    &goutil.Version{Current: "(devel)"},
+			Build: goutil.BuildSettings{
+				Ldflags:    "-s -w",
+				Tags:       []string{"netgo"},
+				CGOEnabled: pointer.Ptr(false),
+				Trimpath:   true,
+				Env:        map[string]string{"GOAMD64": "v3"},
+			},
+			Toolchain: "go1.22.4",
+			Sum:       "h1:2Gc3kKmyDWOwkmQHMK4ABp5Cdx0BXsXN3p8jRrfi1rM=",
 		},
 	}
 
@@ -81,19 +104,35 @@ func TestWriteConfFile(t *testing.T) {
       "name": "foo",
       "import_path": "example.com/foo",
       "version": "v1.2.3",
-      "channel": "main"
+      "channel": "main",
+      "pin": "v1.2.3"
     },
     {
       "name": "bar",
       "import_path": "example.com/bar",
       "version": "latest",
-      "channel": "latest"
+      "channel": "latest",
+      "policy": "patch",
+      "cooldown_days": 3
     },
     {
       "name": "baz",
       "import_path": "example.com/baz",
       "version": "latest",
-      "channel": "latest"
+      "channel": "latest",
+      "build": {
+        "ldflags": "-s -w",
+        "tags": [
+          "netgo"
+        ],
+        "cgo_enabled": false,
+        "trimpath": true,
+        "env": {
+          "GOAMD64": "v3"
+        }
+      },
+      "toolchain": "go1.22.4",
+      "sum": "h1:2Gc3kKmyDWOwkmQHMK4ABp5Cdx0BXsXN3p8jRrfi1rM="
     }
   ]
 }
@@ -115,13 +154,26 @@ func TestReadConfFile(t *testing.T) { //nolint:paralleltest // modifies xdg glob
       "name": "foo",
       "import_path": "example.com/foo",
       "version": "v1.2.3",
-      "channel": "main"
+      "channel": "main",
+      "pin": "v1.2.3"
     },
     {
       "name": "bar",
       "import_path": "example.com/bar",
       "version": "v4.5.6",
-      "channel": "master"
+      "channel": "master",
+      "policy": "Minor",
+      "cooldown_days": 7
+    },
+    {
+      "name": "baz",
+      "import_path": "example.com/baz",
+      "version": "v0.3.1-0.20260304050607-4f2c1a9b8e7d",
+      "channel": "ref:release-2.x",
+      "allow_major": true,
+      "build": {"tags": ["netgo"], "cgo_enabled": false, "env": {"GOAMD64": "v3"}},
+      "toolchain": "go1.22.4",
+      "sum": "h1:2Gc3kKmyDWOwkmQHMK4ABp5Cdx0BXsXN3p8jRrfi1rM="
     }
   ]
 }`
@@ -133,8 +185,8 @@ func TestReadConfFile(t *testing.T) { //nolint:paralleltest // modifies xdg glob
 	if err != nil {
 		t.Fatalf("ReadConfFile() error = %v", err)
 	}
-	if len(pkgs) != 2 {
-		t.Fatalf("ReadConfFile() len = %d, want 2", len(pkgs))
+	if len(pkgs) != 3 {
+		t.Fatalf("ReadConfFile() len = %d, want 3", len(pkgs))
 	}
 	if pkgs[0].Name != "foo" || pkgs[0].ImportPath != "example.com/foo" {
 		t.Fatalf("first pkg mismatch: %+v", pkgs[0])
@@ -154,6 +206,35 @@ func TestReadConfFile(t *testing.T) { //nolint:paralleltest // modifies xdg glob
 	if pkgs[1].UpdateChannel != goutil.UpdateChannelMaster {
 		t.Fatalf("second pkg channel mismatch: %s", pkgs[1].UpdateChannel)
 	}
+	if pkgs[0].Policy != "" || pkgs[1].Policy != goutil.UpdatePolicyMinor {
+		t.Fatalf("policy mismatch: %q, %q", pkgs[0].Policy, pkgs[1].Policy)
+	}
+	if pkgs[0].Pin != "v1.2.3" || pkgs[1].Pin != "" {
+		t.Fatalf("pin mismatch: %q, %q", pkgs[0].Pin, pkgs[1].Pin)
+	}
+	if pkgs[0].CooldownDays != nil || pkgs[1].CooldownDays == nil || *pkgs[1].CooldownDays != 7 {
+		t.Fatalf("cooldown_days mismatch: %v, %v", pkgs[0].CooldownDays, pkgs[1].CooldownDays)
+	}
+	if pkgs[2].UpdateChannel != goutil.RefUpdateChannel("release-2.x") {
+		t.Fatalf("third pkg channel mismatch: %s", pkgs[2].UpdateChannel)
+	}
+	if pkgs[0].AllowMajor || !pkgs[2].AllowMajor {
+		t.Fatalf("allow_major mismatch: %v, %v", pkgs[0].AllowMajor, pkgs[2].AllowMajor)
+	}
+	wantBuild := goutil.BuildSettings{
+		Tags:       []string{"netgo"},
+		CGOEnabled: pointer.Ptr(false),
+		Env:        map[string]string{"GOAMD64": "v3"},
+	}
+	if !pkgs[0].Build.IsZero() || !reflect.DeepEqual(pkgs[2].Build, wantBuild) {
+		t.Fatalf("build mismatch: %+v, %+v", pkgs[0].Build, pkgs[2].Build)
+	}
+	if pkgs[0].Toolchain != "" || pkgs[2].Toolchain != "go1.22.4" {
+		t.Fatalf("toolchain mismatch: %q, %q", pkgs[0].Toolchain, pkgs[2].Toolchain)
+	}
+	if pkgs[0].Sum != "" || pkgs[2].Sum != "h1:2Gc3kKmyDWOwkmQHMK4ABp5Cdx0BXsXN3p8jRrfi1rM=" {
+		t.Fatalf("sum mismatch: %q, %q", pkgs[0].Sum, pkgs[2].Sum)
+	}
 }
 
 func TestReadConfFile_Empty(t *testing.T) {
@@ -200,6 +281,81 @@ func TestReadConfFile_InvalidFormat(t *testing.T) {
       "channel": "latest"
     }
   ]
+}`,
+		},
+		{
+			name: "invalid policy",
+			content: `{
+  "schema_version": 1,
+  "packages": [
+    {
+      "name": "foo",
+      "import_path": "example.com/foo",
+      "version": "v1.2.3",
+      "channel": "latest",
+      "policy": "newest"
+    }
+  ]
+}`,
+		},
+		{
+			name: "negative cooldown",
+			content: `{
+  "schema_version": 1,
+  "packages": [
+    {
+      "name": "foo",
+      "import_path": "example.com/foo",
+      "version": "v1.2.3",
+      "channel": "latest",
+      "cooldown_days": -1
+    }
+  ]
+}`,
+		},
+		{
+			name: "build sets GOBIN",
+			content: `{
+  "schema_version": 1,
+  "packages": [
+    {
+      "name": "foo",
+      "import_path": "example.com/foo",
+      "version": "v1.2.3",
+      "channel": "latest",
+      "build": {"env": {"GOBIN": "/tmp"}}
+    }
+  ]
+}`,
+		},
+		{
+			name: "invalid toolchain",
+			content: `{
+  "schema_version": 1,
+  "packages": [
+    {
+      "name": "foo",
+      "import_path": "example.com/foo",
+      "version": "v1.2.3",
+      "channel": "latest",
+      "toolchain": "1.22"
+    }
+  ]
+}`,
+		},
+		{
+			name: "invalid sum",
+			content: `{
+  "schema_version": 1,
+  "packages": [
+    {
+      "name": "foo",
+      "import_path": "example.com/foo",
+      "version": "v1.2.3",
+      "channel": "latest",
+      "sum": "2Gc3kKmyDWOwkmQHMK4ABp5Cdx0BXsXN3p8jRrfi1rM="
+    }
+  ]
 }`,
 		},
 	}
@@ -221,6 +377,87 @@ func TestReadConfFile_InvalidFormat(t *testing.T) {
 	}
 }
 
+func TestReadConfFile_InvalidPin(t *testing.T) {
+	t.Parallel()
+
+	for _, pin := range []string{"1.2.3", "v1.2", "latest", "v1.2.3 "} {
+		t.Run(pin, func(t *testing.T) {
+			t.Parallel()
+
+			content := `{
+  "schema_version": 1,
+  "packages": [
+    {
+      "name": "foo",
+      "import_path": "example.com/foo",
+      "version": "v1.2.3",
+      "channel": "latest",
+      "pin": "` + pin + `"
+    }
+  ]
+}`
+			confPath := filepath.Join(t.TempDir(), "gup.json")
+			if err := os.WriteFile(confPath, []byte(content), 0o600); err != nil {
+				t.Fatalf("failed to write temp conf file: %v", err)
+			}
+
+			_, err := ReadConfFile(confPath)
+			if pin == "v1.2.3 " {
+				// Surrounding spaces are trimmed like the other fields.
+				if err != nil {
+					t.Fatalf("ReadConfFile() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("ReadConfFile() should return error for pin %q", pin)
+			}
+			if !strings.Contains(err.Error(), "invalid package entry at index 0: pin of foo") {
+				t.Errorf("ReadConfFile() error = %v, want the index and the package name", err)
+			}
+		})
+	}
+}
+
+func TestReadSettings(t *testing.T) {
+	t.Parallel()
+
+	confPath := filepath.Join(t.TempDir(), "gup.json")
+	var buf bytes.Buffer
+	pkgs := []goutil.Package{{Name: "foo", ImportPath: "example.com/foo", Version: &goutil.Version{Current: "v1.2.3"}}}
+	if err := WriteConfFileWithSettings(&buf, pkgs, Settings{CooldownDays: pointer.Ptr(7)}); err != nil {
+		t.Fatalf("WriteConfFileWithSettings() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "\n  \"cooldown_days\": 7,\n") {
+		t.Errorf("WriteConfFileWithSettings() output = %s, want the top-level cooldown_days", buf.String())
+	}
+	if err := os.WriteFile(confPath, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	settings, err := ReadSettings(confPath)
+	if err != nil {
+		t.Fatalf("ReadSettings() error = %v", err)
+	}
+	if settings.CooldownDays == nil || *settings.CooldownDays != 7 {
+		t.Errorf("ReadSettings() cooldown_days = %v, want 7", settings.CooldownDays)
+	}
+	got, err := ReadConfFile(confPath)
+	if err != nil || len(got) != 1 || got[0].CooldownDays != nil {
+		t.Errorf("ReadConfFile() = %+v, %v; want foo without its own cooldown_days", got, err)
+	}
+
+	if err := os.WriteFile(confPath, []byte(`{"schema_version": 1, "cooldown_days": -1, "packages": []}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadSettings(confPath); err == nil {
+		t.Error("ReadSettings() error = nil, want error for a negative cooldown_days")
+	}
+	if _, err := ReadConfFile(confPath); err == nil {
+		t.Error("ReadConfFile() error = nil, want error for a negative cooldown_days")
+	}
+}
+
 func TestResolveImportFilePath(t *testing.T) { //nolint:paralleltest // changes working dir
 	cleanup := withTempXDG(t)
 	defer cleanup()
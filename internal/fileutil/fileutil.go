@@ -2,9 +2,15 @@
 package fileutil
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 )
 
@@ -34,3 +40,85 @@ func IsHiddenFile(filePath string) bool {
 	_, name := filepath.Split(filePath)
 	return IsFile(filePath) && strings.HasPrefix(name, ".")
 }
+
+// SHA256 returns the hex-encoded SHA-256 digest of the file at path.
+func SHA256(path string) (string, error) {
+	//nolint:gosec // The caller controls the path; helper intentionally accepts relative paths.
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close() //nolint:errcheck // read-only file
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("can't read %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// RenameWithReplace renames src to dst, replacing dst if it exists.
+// On Windows, where os.Rename can not overwrite an existing file, dst is
+// moved aside first and restored if the rename fails.
+func RenameWithReplace(src, dst string) error {
+	//nolint:gosec // src/dst are created by this process and not user-controlled.
+	if err := os.Rename(src, dst); err != nil {
+		// Windows cannot overwrite an existing file with os.Rename.
+		// Retry via destination backup swap when the destination likely exists.
+		if !shouldRetryRenameWithReplace(err, dst) {
+			return err
+		}
+		return renameWithBackupSwap(src, dst)
+	}
+	return nil
+}
+
+func renameWithBackupSwap(src, dst string) error {
+	backupPath, err := prepareBackupPath(dst)
+	if err != nil {
+		return err
+	}
+
+	if err = os.Rename(dst, backupPath); err != nil {
+		return err
+	}
+	//nolint:gosec // src/dst are created by this process and not user-controlled.
+	if err = os.Rename(src, dst); err != nil {
+		if restoreErr := os.Rename(backupPath, dst); restoreErr != nil {
+			return errors.Join(err, fmt.Errorf("can't restore original file %s after failed update: %w", dst, restoreErr))
+		}
+		return err
+	}
+
+	_ = os.Remove(backupPath)
+	return nil
+}
+
+func prepareBackupPath(dst string) (string, error) {
+	backupFile, err := os.CreateTemp(filepath.Dir(dst), filepath.Base(dst)+".bak-*")
+	if err != nil {
+		return "", err
+	}
+	backupPath := backupFile.Name()
+	if err := backupFile.Close(); err != nil {
+		//nolint:gosec // backupPath is created by os.CreateTemp in this function.
+		_ = os.Remove(backupPath)
+		return "", err
+	}
+	//nolint:gosec // backupPath is created by os.CreateTemp in this function.
+	if err := os.Remove(backupPath); err != nil {
+		return "", err
+	}
+	return backupPath, nil
+}
+
+func shouldRetryRenameWithReplace(renameErr error, dst string) bool {
+	if os.IsExist(renameErr) {
+		return true
+	}
+	if runtime.GOOS != "windows" {
+		return false
+	}
+	_, err := os.Stat(dst)
+	return err == nil
+}
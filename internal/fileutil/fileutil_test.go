@@ -3,6 +3,7 @@ package fileutil
 import (
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
 )
 
@@ -37,6 +38,26 @@ func TestIsFile(t *testing.T) {
 	})
 }
 
+func TestSHA256(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, []byte("gup\n"), FileModeCreatingFile); err != nil {
+		t.Fatal(err)
+	}
+	got, err := SHA256(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "b8e397118985184b1a5699791f55d1d9915dca294b0b92c2ce740b825ca16d03"; got != want {
+		t.Errorf("SHA256() = %q, want %q", got, want)
+	}
+
+	if _, err := SHA256(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("SHA256() of a missing file returns no error")
+	}
+}
+
 func TestIsDir(t *testing.T) {
 	t.Parallel()
 
@@ -114,3 +135,97 @@ func TestIsHiddenFile(t *testing.T) {
 		}
 	})
 }
+
+func TestRenameWithBackupSwap_Success(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "gup.json.tmp")
+	dst := filepath.Join(dir, "gup.json")
+
+	if err := os.WriteFile(src, []byte("new"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dst, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := renameWithBackupSwap(src, dst); err != nil {
+		t.Fatalf("renameWithBackupSwap() error = %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Clean(dst))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "new" {
+		t.Fatalf("updated content = %q, want %q", string(got), "new")
+	}
+
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Fatalf("src file should be moved, stat err = %v", err)
+	}
+}
+
+func TestRenameWithBackupSwap_RestoreOnFailure(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "missing.tmp")
+	dst := filepath.Join(dir, "gup.json")
+
+	if err := os.WriteFile(dst, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := renameWithBackupSwap(src, dst); err == nil {
+		t.Fatal("renameWithBackupSwap() should return error")
+	}
+
+	got, err := os.ReadFile(filepath.Clean(dst))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "old" {
+		t.Fatalf("restored content = %q, want %q", string(got), "old")
+	}
+}
+
+func Test_shouldRetryRenameWithReplace(t *testing.T) {
+	t.Parallel()
+
+	dst := filepath.Join(t.TempDir(), "gup.json")
+	if err := os.WriteFile(dst, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if !shouldRetryRenameWithReplace(os.ErrExist, dst) {
+		t.Fatal("shouldRetryRenameWithReplace() should return true for os.ErrExist")
+	}
+
+	got := shouldRetryRenameWithReplace(os.ErrNotExist, dst)
+	if runtime.GOOS == "windows" {
+		if !got {
+			t.Fatal("shouldRetryRenameWithReplace() should return true on Windows when dst exists")
+		}
+		return
+	}
+	if got {
+		t.Fatal("shouldRetryRenameWithReplace() should return false on non-Windows for non-exist error")
+	}
+}
+
+func TestRenameWithReplace_errorWhenSrcMissing(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "missing.tmp")
+	dst := filepath.Join(dir, "gup.json")
+	if err := os.WriteFile(dst, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RenameWithReplace(src, dst); err == nil {
+		t.Fatal("RenameWithReplace() should return error when source file does not exist")
+	}
+}
@@ -0,0 +1,561 @@
+// Package goproxy implements a client for the Go module proxy protocol.
+// https://go.dev/ref/mod#goproxy-protocol
+package goproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hashicorp/go-version"
+)
+
+// DefaultProxy is the GOPROXY value used by the go command when GOPROXY is unset.
+const DefaultProxy = "https://proxy.golang.org,direct"
+
+// maxResponseSize limits the size of proxy responses read into memory.
+const maxResponseSize = 8 << 20
+
+var (
+	// ErrDirect is returned when the module must be fetched from its origin
+	// (GOPROXY "direct" or GOPRIVATE/GONOPROXY). Callers should fall back to the go command.
+	ErrDirect = errors.New("module must be fetched directly from its origin")
+	// ErrOff is returned when GOPROXY disables module downloads.
+	ErrOff = errors.New("module lookup disabled by GOPROXY=off")
+	// ErrNotFound is returned when no proxy serves the requested module or version.
+	ErrNotFound = errors.New("not found")
+	// ErrProxyNotFound is returned together with ErrDirect when every proxy
+	// before "direct" answered that the module or version does not exist.
+	ErrProxyNotFound = errors.New("not found by the proxy")
+)
+
+// Info is the version metadata served by the proxy ($GOPROXY/<module>/@v/<version>.info).
+type Info struct {
+	// Version is the canonical version.
+	Version string `json:"Version"`
+	// Time is the commit time of the version.
+	Time time.Time `json:"Time"`
+}
+
+// Config is the configuration of Client. Empty values are treated like the go command does.
+type Config struct {
+	// Proxy is the GOPROXY value.
+	Proxy string
+	// Private is the GOPRIVATE value.
+	Private string
+	// NoProxy is the GONOPROXY value. When empty, Private is used.
+	NoProxy string
+	// HTTPClient is used for http(s) proxies. When nil, a client with a timeout is used.
+	HTTPClient *http.Client
+}
+
+// Client queries module versions through the proxies listed in GOPROXY.
+type Client struct {
+	proxies    []proxySpec
+	noProxy    string
+	httpClient *http.Client
+}
+
+// proxySpec is a single GOPROXY list element.
+type proxySpec struct {
+	// url is a proxy URL, "direct" or "off".
+	url string
+	// fallBackOnError reports whether any error (not only "not found")
+	// moves on to the next element. It is true when the element is followed by '|'.
+	fallBackOnError bool
+}
+
+const (
+	proxyDirect = "direct"
+	proxyOff    = "off"
+)
+
+// New returns a Client configured by cfg.
+func New(cfg Config) *Client {
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	noProxy := cfg.NoProxy
+	if noProxy == "" {
+		noProxy = cfg.Private
+	}
+	return &Client{
+		proxies:    parseProxyList(cfg.Proxy),
+		noProxy:    noProxy,
+		httpClient: httpClient,
+	}
+}
+
+// parseProxyList parses GOPROXY in the same way as the go command.
+func parseProxyList(goproxy string) []proxySpec {
+	goproxy = strings.TrimSpace(goproxy)
+	if goproxy == "" {
+		goproxy = DefaultProxy
+	}
+
+	proxies := []proxySpec{}
+	for goproxy != "" {
+		var raw string
+		fallBackOnError := false
+		if i := strings.IndexAny(goproxy, ",|"); i >= 0 {
+			raw = goproxy[:i]
+			fallBackOnError = goproxy[i] == '|'
+			goproxy = goproxy[i+1:]
+		} else {
+			raw = goproxy
+			goproxy = ""
+		}
+
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		if raw == proxyOff || raw == proxyDirect {
+			proxies = append(proxies, proxySpec{url: raw})
+			if raw == proxyOff {
+				// Elements after "off" can never be reached.
+				break
+			}
+			continue
+		}
+		// Anything without a scheme is an https URL, as with the go command.
+		if strings.ContainsAny(raw, ".:/") && !strings.Contains(raw, ":/") && !filepath.IsAbs(raw) && !path.IsAbs(raw) {
+			raw = "https://" + raw
+		}
+		proxies = append(proxies, proxySpec{url: strings.TrimSuffix(raw, "/"), fallBackOnError: fallBackOnError})
+	}
+	return proxies
+}
+
+// Latest returns the version served by "$GOPROXY/<module>/@latest".
+// The proxy usually serves a pseudo-version here; use LatestVersion for the
+// version that "go install <module>@latest" selects.
+func (c *Client) Latest(ctx context.Context, modulePath string) (Info, error) {
+	raw, err := c.fetch(ctx, modulePath, "@latest")
+	if err != nil {
+		return Info{}, err
+	}
+	return decodeInfo(modulePath, raw)
+}
+
+// Versions returns the tagged versions served by "$GOPROXY/<module>/@v/list".
+// Pseudo-versions are not included.
+func (c *Client) Versions(ctx context.Context, modulePath string) ([]string, error) {
+	raw, err := c.fetch(ctx, modulePath, "@v/list")
+	if err != nil {
+		return nil, err
+	}
+
+	versions := []string{}
+	for _, line := range strings.Split(string(raw), "\n") {
+		// Each line is "<version>" optionally followed by metadata.
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		versions = append(versions, fields[0])
+	}
+	return versions, nil
+}
+
+// Info returns the metadata served by "$GOPROXY/<module>/@v/<version>.info".
+func (c *Client) Info(ctx context.Context, modulePath, ver string) (Info, error) {
+	escapedVer, err := EscapeVersion(ver)
+	if err != nil {
+		return Info{}, err
+	}
+	raw, err := c.fetch(ctx, modulePath, "@v/"+escapedVer+".info")
+	if err != nil {
+		return Info{}, err
+	}
+	return decodeInfo(modulePath, raw)
+}
+
+// GoMod returns the go.mod file served by "$GOPROXY/<module>/@v/<version>.mod".
+func (c *Client) GoMod(ctx context.Context, modulePath, ver string) ([]byte, error) {
+	escapedVer, err := EscapeVersion(ver)
+	if err != nil {
+		return nil, err
+	}
+	return c.fetch(ctx, modulePath, "@v/"+escapedVer+".mod")
+}
+
+// LatestVersion returns the version that "go install <module>@latest" selects:
+// the highest release version, else the highest prerelease version,
+// else the version served by @latest.
+//
+// Like the go command, versions retracted by the go.mod of the highest version
+// are skipped. When every tagged version is retracted, the version served by
+// @latest is returned. A proxy without the go.mod file is treated as having no
+// retractions.
+func (c *Client) LatestVersion(ctx context.Context, modulePath string) (string, error) {
+	versions, err := c.Versions(ctx, modulePath)
+	if err != nil {
+		return "", err
+	}
+	if highest := highestTaggedVersion(versions); highest != "" {
+		retracted, err := c.retractions(ctx, modulePath, highest)
+		if err != nil {
+			return "", err
+		}
+		allowed := make([]string, 0, len(versions))
+		for _, v := range versions {
+			if !isRetracted(v, retracted) {
+				allowed = append(allowed, v)
+			}
+		}
+		if v := highestTaggedVersion(allowed); v != "" {
+			return v, nil
+		}
+	}
+
+	info, err := c.Latest(ctx, modulePath)
+	if err != nil {
+		return "", err
+	}
+	return info.Version, nil
+}
+
+// highestTaggedVersion returns the highest release version in versions,
+// else the highest prerelease version.
+func highestTaggedVersion(versions []string) string {
+	if v := HighestVersion(versions, false); v != "" {
+		return v
+	}
+	return HighestVersion(versions, true)
+}
+
+// retractions returns the versions retracted by the go.mod of modulePath@ver.
+func (c *Client) retractions(ctx context.Context, modulePath, ver string) ([]versionRange, error) {
+	gomod, err := c.GoMod(ctx, modulePath, ver)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrProxyNotFound) {
+			return []versionRange{}, nil
+		}
+		return nil, err
+	}
+	return parseRetractions(gomod), nil
+}
+
+// versionRange is an inclusive range of versions. A single version has low == high.
+type versionRange struct {
+	low  string
+	high string
+}
+
+// parseRetractions returns the versions retracted by the retract directives
+// of a go.mod file: "retract v1.0.0", "retract [v1.0.0, v1.2.0]" and blocks
+// of them in "retract ( ... )".
+func parseRetractions(gomod []byte) []versionRange {
+	ranges := []versionRange{}
+	inBlock := false
+	for _, line := range strings.Split(string(gomod), "\n") {
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if inBlock {
+			if line == ")" {
+				inBlock = false
+				continue
+			}
+		} else {
+			rest, ok := strings.CutPrefix(line, "retract")
+			if !ok || rest == "" || !strings.ContainsAny(rest[:1], " \t[(") {
+				continue
+			}
+			line = strings.TrimSpace(rest)
+			if line == "(" {
+				inBlock = true
+				continue
+			}
+		}
+		if r, ok := parseVersionRange(line); ok {
+			ranges = append(ranges, r)
+		}
+	}
+	return ranges
+}
+
+// parseVersionRange parses "v1.0.0" or "[v1.0.0, v1.2.0]".
+func parseVersionRange(s string) (versionRange, bool) {
+	unquote := func(v string) string { return strings.Trim(strings.TrimSpace(v), `"`) }
+	if inner, ok := strings.CutPrefix(s, "["); ok {
+		inner, ok = strings.CutSuffix(inner, "]")
+		if !ok {
+			return versionRange{}, false
+		}
+		low, high, ok := strings.Cut(inner, ",")
+		if !ok {
+			return versionRange{}, false
+		}
+		return versionRange{low: unquote(low), high: unquote(high)}, true
+	}
+	if v := unquote(s); v != "" {
+		return versionRange{low: v, high: v}, true
+	}
+	return versionRange{}, false
+}
+
+// isRetracted reports whether ver is in one of the retracted ranges.
+func isRetracted(ver string, retracted []versionRange) bool {
+	v, err := version.NewVersion(ver)
+	for _, r := range retracted {
+		if ver == r.low || ver == r.high {
+			return true
+		}
+		if err != nil {
+			continue
+		}
+		low, lowErr := version.NewVersion(r.low)
+		high, highErr := version.NewVersion(r.high)
+		if lowErr == nil && highErr == nil && !v.LessThan(low) && !v.GreaterThan(high) {
+			return true
+		}
+	}
+	return false
+}
+
+// HighestVersion returns the highest valid version in versions.
+// Prerelease versions are skipped unless allowPrerelease is true.
+// "+incompatible" versions are only chosen when no compatible version qualifies.
+func HighestVersion(versions []string, allowPrerelease bool) string {
+	pick := func(allowIncompatible bool) string {
+		var best *version.Version
+		bestRaw := ""
+		for _, raw := range versions {
+			if !allowIncompatible && strings.HasSuffix(raw, "+incompatible") {
+				continue
+			}
+			v, err := version.NewVersion(raw)
+			if err != nil {
+				continue
+			}
+			if !allowPrerelease && v.Prerelease() != "" {
+				continue
+			}
+			if best == nil || v.GreaterThan(best) {
+				best = v
+				bestRaw = raw
+			}
+		}
+		return bestRaw
+	}
+
+	if v := pick(false); v != "" {
+		return v
+	}
+	return pick(true)
+}
+
+func decodeInfo(modulePath string, raw []byte) (Info, error) {
+	info := Info{}
+	if err := json.Unmarshal(raw, &info); err != nil {
+		return Info{}, fmt.Errorf("invalid version info for %s: %w", modulePath, err)
+	}
+	if info.Version == "" {
+		return Info{}, fmt.Errorf("invalid version info for %s: version is empty", modulePath)
+	}
+	return info, nil
+}
+
+// fetch walks the GOPROXY list and returns the first successful response for
+// "<proxy>/<escaped module>/<suffix>".
+func (c *Client) fetch(ctx context.Context, modulePath, suffix string) ([]byte, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	escaped, err := EscapePath(modulePath)
+	if err != nil {
+		return nil, err
+	}
+	if MatchPrefixPatterns(c.noProxy, modulePath) {
+		return nil, ErrDirect
+	}
+
+	var lastErr error
+	for _, p := range c.proxies {
+		switch p.url {
+		case proxyDirect:
+			if errors.Is(lastErr, ErrNotFound) {
+				return nil, fmt.Errorf("%w: %w", ErrDirect, ErrProxyNotFound)
+			}
+			return nil, ErrDirect
+		case proxyOff:
+			return nil, ErrOff
+		}
+
+		raw, err := c.get(ctx, p.url+"/"+escaped+"/"+suffix)
+		if err == nil {
+			return raw, nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		lastErr = err
+		if !errors.Is(err, ErrNotFound) && !p.fallBackOnError {
+			return nil, err
+		}
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("%s: %w", modulePath, ErrNotFound)
+	}
+	return nil, lastErr
+}
+
+// get reads target from an http(s) or file proxy.
+func (c *Client) get(ctx context.Context, target string) ([]byte, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL %s: %w", target, err)
+	}
+
+	switch u.Scheme {
+	case "file":
+		filePath := u.Path
+		if runtime.GOOS == "windows" {
+			// file:///C:/proxy has the path "/C:/proxy".
+			filePath = strings.TrimPrefix(filePath, "/")
+		}
+		raw, err := os.ReadFile(filepath.FromSlash(filePath))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("%s: %w", target, ErrNotFound)
+			}
+			return nil, err
+		}
+		return raw, nil
+	case "http", "https":
+	default:
+		return nil, fmt.Errorf("invalid proxy URL %s: unsupported scheme %q", target, u.Scheme)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() //nolint:errcheck // read-only body
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("can't read response of %s: %w", u.Redacted(), err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return body, nil
+	case resp.StatusCode == http.StatusNotFound, resp.StatusCode == http.StatusGone:
+		// The body explains why, e.g. "module declares its path as: ...".
+		msg := strings.TrimSpace(string(bytes.ToValidUTF8(body, nil)))
+		if msg == "" {
+			msg = resp.Status
+		}
+		return nil, fmt.Errorf("%s: %s: %w", u.Redacted(), msg, ErrNotFound)
+	default:
+		return nil, fmt.Errorf("%s: unexpected status %s", u.Redacted(), resp.Status)
+	}
+}
+
+// EscapePath returns the module path escaped for use in proxy URLs:
+// each upper-case letter is replaced by '!' followed by its lower-case form.
+func EscapePath(modulePath string) (string, error) {
+	if err := checkPath(modulePath); err != nil {
+		return "", err
+	}
+	return escapeString(modulePath)
+}
+
+// EscapeVersion returns the version escaped for use in proxy URLs.
+func EscapeVersion(ver string) (string, error) {
+	if ver == "" || strings.ContainsAny(ver, "/\\") || ver == "." || ver == ".." {
+		return "", fmt.Errorf("invalid version %q", ver)
+	}
+	return escapeString(ver)
+}
+
+func escapeString(s string) (string, error) {
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		switch {
+		case r == '!' || r >= utf8.RuneSelf:
+			return "", fmt.Errorf("invalid character %q in %q", r, s)
+		case 'A' <= r && r <= 'Z':
+			b.WriteByte('!')
+			b.WriteRune(r + ('a' - 'A'))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String(), nil
+}
+
+// checkPath performs a light validation of module paths before they are used in URLs.
+func checkPath(modulePath string) error {
+	if modulePath == "" {
+		return errors.New("malformed module path: empty string")
+	}
+	first, _, _ := strings.Cut(modulePath, "/")
+	if !strings.Contains(first, ".") {
+		return fmt.Errorf("malformed module path %q: missing dot in first path element", modulePath)
+	}
+	for _, elem := range strings.Split(modulePath, "/") {
+		if elem == "" || elem == "." || elem == ".." {
+			return fmt.Errorf("malformed module path %q: invalid path element %q", modulePath, elem)
+		}
+	}
+	if strings.ContainsAny(modulePath, "\\:@ ") {
+		return fmt.Errorf("malformed module path %q: invalid character", modulePath)
+	}
+	return nil
+}
+
+// MatchPrefixPatterns reports whether any path prefix of target matches one of
+// the comma-separated glob patterns, with the same syntax as GOPRIVATE.
+func MatchPrefixPatterns(globs, target string) bool {
+	for _, glob := range strings.Split(globs, ",") {
+		glob = strings.TrimSuffix(strings.TrimSpace(glob), "/")
+		if glob == "" {
+			continue
+		}
+
+		// Cut target to as many path elements as the pattern has.
+		n := strings.Count(glob, "/")
+		prefix := target
+		for i := 0; i < len(target); i++ {
+			if target[i] != '/' {
+				continue
+			}
+			if n == 0 {
+				prefix = target[:i]
+				break
+			}
+			n--
+		}
+		if n > 0 {
+			continue
+		}
+		if matched, _ := path.Match(glob, prefix); matched {
+			return true
+		}
+	}
+	return false
+}
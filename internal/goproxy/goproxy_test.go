@@ -0,0 +1,384 @@
+package goproxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// newTestProxy serves files as a module proxy. Keys are paths below the proxy root.
+func newTestProxy(t *testing.T, files map[string]string) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		body, ok := files[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.Error(w, "not found: "+r.URL.Path, http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func TestClient_LatestVersion(t *testing.T) {
+	t.Parallel()
+
+	srv, _ := newTestProxy(t, map[string]string{
+		"example.com/tool/@v/list":         "v1.0.0\nv1.2.0\nv1.10.0\nv1.11.0-rc.1\nv2.0.0+incompatible\n",
+		"example.com/pre/@v/list":          "v0.1.0-alpha\nv0.1.0-beta\n",
+		"example.com/untagged/@v/list":     "",
+		"example.com/untagged/@latest":     `{"Version":"v0.0.0-20260101000000-abcdefabcdef","Time":"2026-01-01T00:00:00Z"}`,
+		"example.com/!upper/!case/@v/list": "v0.3.0\n",
+	})
+	client := New(Config{Proxy: srv.URL})
+
+	tests := []struct {
+		modulePath string
+		want       string
+	}{
+		{modulePath: "example.com/tool", want: "v1.10.0"},
+		{modulePath: "example.com/pre", want: "v0.1.0-beta"},
+		{modulePath: "example.com/untagged", want: "v0.0.0-20260101000000-abcdefabcdef"},
+		{modulePath: "example.com/Upper/Case", want: "v0.3.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.modulePath, func(t *testing.T) {
+			t.Parallel()
+			got, err := client.LatestVersion(context.Background(), tt.modulePath)
+			if err != nil {
+				t.Fatalf("LatestVersion() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("LatestVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_Info(t *testing.T) {
+	t.Parallel()
+
+	srv, _ := newTestProxy(t, map[string]string{
+		"example.com/tool/@v/v1.2.0.info": `{"Version":"v1.2.0","Time":"2026-03-04T05:06:07Z"}`,
+	})
+	client := New(Config{Proxy: srv.URL})
+
+	got, err := client.Info(context.Background(), "example.com/tool", "v1.2.0")
+	if err != nil {
+		t.Fatalf("Info() error = %v", err)
+	}
+	want := Info{Version: "v1.2.0", Time: time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Info() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := client.Info(context.Background(), "example.com/tool", "v9.9.9"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Info() for unknown version error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestClient_proxyList(t *testing.T) {
+	t.Parallel()
+
+	missing, _ := newTestProxy(t, map[string]string{})
+	serving, _ := newTestProxy(t, map[string]string{
+		"example.com/tool/@v/list": "v1.0.0\n",
+	})
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	t.Cleanup(broken.Close)
+
+	tests := []struct {
+		name    string
+		proxy   string
+		want    string
+		wantErr error
+	}{
+		{name: "not found falls through comma", proxy: missing.URL + "," + serving.URL, want: "v1.0.0"},
+		{name: "not found then direct", proxy: missing.URL + ",direct", wantErr: ErrProxyNotFound},
+		{name: "error stops at comma", proxy: broken.URL + "," + serving.URL},
+		{name: "error falls through pipe", proxy: broken.URL + "|" + serving.URL, want: "v1.0.0"},
+		{name: "off", proxy: "off", wantErr: ErrOff},
+		{name: "direct", proxy: "direct", wantErr: ErrDirect},
+		{name: "only not found", proxy: missing.URL, wantErr: ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := New(Config{Proxy: tt.proxy}).LatestVersion(context.Background(), "example.com/tool")
+			if tt.want != "" {
+				if err != nil {
+					t.Fatalf("LatestVersion() error = %v", err)
+				}
+				if got != tt.want {
+					t.Errorf("LatestVersion() = %q, want %q", got, tt.want)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("LatestVersion() = %q, want error", got)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("LatestVersion() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClient_noProxy(t *testing.T) {
+	t.Parallel()
+
+	srv, hits := newTestProxy(t, map[string]string{
+		"example.com/public/@v/list": "v1.0.0\n",
+	})
+
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{name: "GOPRIVATE", cfg: Config{Proxy: srv.URL, Private: "corp.example.com"}},
+		{name: "GONOPROXY", cfg: Config{Proxy: srv.URL, Private: "other.example.com", NoProxy: "*.example.com/private"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			client := New(tt.cfg)
+			modulePath := "corp.example.com/private/tool"
+			if _, err := client.LatestVersion(context.Background(), modulePath); !errors.Is(err, ErrDirect) {
+				t.Errorf("LatestVersion(%s) error = %v, want %v", modulePath, err, ErrDirect)
+			}
+		})
+	}
+
+	if _, err := New(Config{Proxy: srv.URL, Private: "corp.example.com"}).LatestVersion(context.Background(), "example.com/public"); err != nil {
+		t.Fatalf("LatestVersion() of public module error = %v", err)
+	}
+	// The list and the go.mod of the public module.
+	if got := atomic.LoadInt32(hits); got != 2 {
+		t.Errorf("proxy hits = %d, want 2 (private modules must not reach the proxy)", got)
+	}
+}
+
+func TestClient_LatestVersion_retract(t *testing.T) {
+	t.Parallel()
+
+	srv, _ := newTestProxy(t, map[string]string{
+		"example.com/tool/@v/list": "v1.0.0\nv1.1.0\nv1.2.0\nv1.3.0\nv1.4.0\n",
+		// Retractions are read from the go.mod of the highest version, which may retract itself.
+		"example.com/tool/@v/v1.4.0.mod": `module example.com/tool
+
+go 1.22
+
+retract (
+	v1.4.0 // published by mistake
+	[v1.2.0, v1.3.0] // broken builds
+)
+`,
+		"example.com/single/@v/list":         "v0.1.0\nv0.2.0\n",
+		"example.com/single/@v/v0.2.0.mod":   "module example.com/single\n\nretract v0.2.0\n",
+		"example.com/all/@v/list":            "v1.0.0\n",
+		"example.com/all/@v/v1.0.0.mod":      "module example.com/all\nretract [v0.0.0, v1.0.0]\n",
+		"example.com/all/@latest":            `{"Version":"v1.0.1-0.20260101000000-abcdefabcdef","Time":"2026-01-01T00:00:00Z"}`,
+		"example.com/pre/@v/list":            "v1.0.0-rc.1\nv1.0.0-rc.2\n",
+		"example.com/pre/@v/v1.0.0-rc.2.mod": "module example.com/pre\nretract v1.0.0-rc.2 // regression\n",
+		// A proxy without the go.mod file is treated as having no retractions.
+		"example.com/nomod/@v/list": "v1.0.0\nv1.1.0\n",
+	})
+	client := New(Config{Proxy: srv.URL})
+
+	tests := []struct {
+		modulePath string
+		want       string
+	}{
+		{modulePath: "example.com/tool", want: "v1.1.0"},
+		{modulePath: "example.com/single", want: "v0.1.0"},
+		{modulePath: "example.com/all", want: "v1.0.1-0.20260101000000-abcdefabcdef"},
+		{modulePath: "example.com/pre", want: "v1.0.0-rc.1"},
+		{modulePath: "example.com/nomod", want: "v1.1.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.modulePath, func(t *testing.T) {
+			t.Parallel()
+			got, err := client.LatestVersion(context.Background(), tt.modulePath)
+			if err != nil {
+				t.Fatalf("LatestVersion() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("LatestVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRetractions(t *testing.T) {
+	t.Parallel()
+
+	gomod := `module example.com/tool // retract v9.9.9 in a comment
+
+go 1.22
+
+require example.com/retract v1.0.0
+
+retract v1.0.0
+retract [v1.1.0, v1.1.5] // rationale
+retract(
+	"v1.2.0"
+
+	[v1.3.0,v1.3.9]
+)
+retracted v2.0.0
+`
+	want := []versionRange{
+		{low: "v1.0.0", high: "v1.0.0"},
+		{low: "v1.1.0", high: "v1.1.5"},
+		{low: "v1.2.0", high: "v1.2.0"},
+		{low: "v1.3.0", high: "v1.3.9"},
+	}
+	if diff := cmp.Diff(want, parseRetractions([]byte(gomod)), cmp.AllowUnexported(versionRange{})); diff != "" {
+		t.Errorf("parseRetractions() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestClient_notFoundKeepsReason(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "module declares its path as: example.com/new\n\tbut was required as: example.com/old", http.StatusGone)
+	}))
+	t.Cleanup(srv.Close)
+
+	_, err := New(Config{Proxy: srv.URL}).LatestVersion(context.Background(), "example.com/old")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("LatestVersion() error = %v, want %v", err, ErrNotFound)
+	}
+	if !strings.Contains(err.Error(), "module declares its path as: example.com/new") {
+		t.Errorf("LatestVersion() error = %v, want the proxy's reason", err)
+	}
+}
+
+func TestClient_fileProxy(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	listPath := filepath.Join(dir, "example.com", "tool", "@v", "list")
+	if err := os.MkdirAll(filepath.Dir(listPath), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(listPath, []byte("v0.1.0\nv0.2.0\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	client := New(Config{Proxy: "file://" + filepath.ToSlash(dir)})
+	got, err := client.LatestVersion(context.Background(), "example.com/tool")
+	if err != nil {
+		t.Fatalf("LatestVersion() error = %v", err)
+	}
+	if got != "v0.2.0" {
+		t.Errorf("LatestVersion() = %q, want %q", got, "v0.2.0")
+	}
+	if _, err := client.LatestVersion(context.Background(), "example.com/missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("LatestVersion() error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestParseProxyList(t *testing.T) {
+	t.Parallel()
+
+	got := parseProxyList("proxy.example.com|https://other.example.com/,direct,off,https://never.example.com")
+	want := []proxySpec{
+		{url: "https://proxy.example.com", fallBackOnError: true},
+		{url: "https://other.example.com"},
+		{url: "direct"},
+		{url: "off"},
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(proxySpec{})); diff != "" {
+		t.Errorf("parseProxyList() mismatch (-want +got):\n%s", diff)
+	}
+
+	if got := parseProxyList(""); len(got) != 2 || got[0].url != "https://proxy.golang.org" || got[1].url != "direct" {
+		t.Errorf("parseProxyList(\"\") = %+v, want the default proxy list", got)
+	}
+}
+
+func TestEscapePath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "github.com/BurntSushi/toml", want: "github.com/!burnt!sushi/toml"},
+		{in: "example.com/tool/v2", want: "example.com/tool/v2"},
+		{in: ".", wantErr: true},
+		{in: "", wantErr: true},
+		{in: "tool", wantErr: true},
+		{in: "example.com/../tool", wantErr: true},
+		{in: "example.com/a!b", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := EscapePath(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("EscapePath(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("EscapePath(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMatchPrefixPatterns(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		globs  string
+		target string
+		want   bool
+	}{
+		{globs: "corp.example.com", target: "corp.example.com/tool", want: true},
+		{globs: "*.example.com", target: "corp.example.com/tool", want: true},
+		{globs: "example.com/private", target: "example.com/private/tool", want: true},
+		{globs: "example.com/private", target: "example.com/public/tool", want: false},
+		{globs: "example.com/a/b/c", target: "example.com/a", want: false},
+		{globs: " , other.com,corp.example.com/", target: "corp.example.com", want: true},
+		{globs: "", target: "example.com", want: false},
+	}
+	for _, tt := range tests {
+		if got := MatchPrefixPatterns(tt.globs, tt.target); got != tt.want {
+			t.Errorf("MatchPrefixPatterns(%q, %q) = %v, want %v", tt.globs, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestHighestVersion(t *testing.T) {
+	t.Parallel()
+
+	versions := []string{"v1.9.0", "v1.10.0-rc.1", "v2.0.0+incompatible", "not-a-version"}
+	if got := HighestVersion(versions, false); got != "v1.9.0" {
+		t.Errorf("HighestVersion(release) = %q, want v1.9.0", got)
+	}
+	if got := HighestVersion(versions, true); got != "v1.10.0-rc.1" {
+		t.Errorf("HighestVersion(prerelease) = %q, want v1.10.0-rc.1", got)
+	}
+	if got := HighestVersion([]string{"v2.0.0+incompatible", "v3.0.0+incompatible"}, false); got != "v3.0.0+incompatible" {
+		t.Errorf("HighestVersion(incompatible only) = %q, want v3.0.0+incompatible", got)
+	}
+	if got := HighestVersion(nil, true); got != "" {
+		t.Errorf("HighestVersion(nil) = %q, want empty", got)
+	}
+}
@@ -4,23 +4,37 @@ import (
 	"bytes"
 	"context"
 	"debug/buildinfo"
+	"encoding/json"
 	"fmt"
 	"go/build"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"runtime/debug"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/hashicorp/go-version"
+	"github.com/nao1215/gup/internal/fileutil"
+	"github.com/nao1215/gup/internal/goproxy"
 	"github.com/nao1215/gup/internal/print"
 	"github.com/pkg/errors"
 )
 
-const unknown = "unknown"
+const (
+	unknown = "unknown"
+	// stagingDirPrefix is the name prefix of the directories in $GOBIN
+	// that binaries are built into before they replace the installed ones.
+	stagingDirPrefix = ".gup-staging-"
+)
 
 // UpdateChannel is the update source channel for go install.
 type UpdateChannel string
@@ -32,8 +46,34 @@ const (
 	UpdateChannelMain UpdateChannel = "main"
 	// UpdateChannelMaster updates by @master.
 	UpdateChannelMaster UpdateChannel = "master"
+	// UpdateChannelPrerelease updates to the highest version in the module's
+	// version list, including prereleases such as -rc and -beta tags.
+	UpdateChannelPrerelease UpdateChannel = "prerelease"
+	// updateChannelRefPrefix is the prefix of channels that update by an
+	// arbitrary branch, tag or commit, such as "ref:develop".
+	updateChannelRefPrefix = "ref:"
 )
 
+// RefUpdateChannel returns the channel that updates by @ref.
+func RefUpdateChannel(ref string) UpdateChannel {
+	return UpdateChannel(updateChannelRefPrefix + ref)
+}
+
+// Ref returns the branch, tag or commit of a "ref:<ref>" channel.
+// ok is false for the other channels.
+func (c UpdateChannel) Ref() (ref string, ok bool) {
+	ref, ok = strings.CutPrefix(string(c), updateChannelRefPrefix)
+	if !ok || !isValidRef(ref) {
+		return "", false
+	}
+	return ref, true
+}
+
+// isValidRef reports whether ref can be used as the version query of "go install".
+func isValidRef(ref string) bool {
+	return ref != "" && !strings.ContainsAny(ref, "@ \t\r\n")
+}
+
 // Internal variables to mock/monkey-patch behaviors in tests.
 var (
 	// goExe is the executable name for the go command.
@@ -44,8 +84,104 @@ var (
 	keyGoPath = "GOPATH" //nolint:gochecknoglobals
 	// osMkdirTemp is a copy of os.MkdirTemp to ease testing.
 	osMkdirTemp = os.MkdirTemp //nolint:gochecknoglobals
+	// proxyClient returns the module proxy client. It is built once from "go env".
+	proxyClient = sync.OnceValue(newProxyClient) //nolint:gochecknoglobals
+)
+
+// UpdatePolicy limits how far 'gup update' moves a package from its current version.
+type UpdatePolicy string
+
+const (
+	// UpdatePolicyPatch only allows updates within the current minor version (v1.2.x).
+	UpdatePolicyPatch UpdatePolicy = "patch"
+	// UpdatePolicyMinor only allows updates within the current major version (v1.x.y).
+	UpdatePolicyMinor UpdatePolicy = "minor"
+	// UpdatePolicyMajor allows any update. It is the default.
+	UpdatePolicyMajor UpdatePolicy = "major"
 )
 
+// ParseUpdatePolicy parses a user/config value into an update policy.
+// A blank value returns an empty policy, which means "not set".
+func ParseUpdatePolicy(policy string) (UpdatePolicy, error) {
+	switch p := UpdatePolicy(strings.ToLower(strings.TrimSpace(policy))); p {
+	case "", UpdatePolicyPatch, UpdatePolicyMinor, UpdatePolicyMajor:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid update policy %q (valid values: patch, minor, major)", policy)
+	}
+}
+
+// IsRestricted reports whether the policy rejects some newer versions.
+func (p UpdatePolicy) IsRestricted() bool {
+	return p == UpdatePolicyPatch || p == UpdatePolicyMinor
+}
+
+// HighestAllowedVersion returns the highest release in versions that the policy
+// allows as an update from current. It returns current when no newer release
+// is allowed, and an empty string when current is not a valid version.
+func HighestAllowedVersion(current string, versions []string, policy UpdatePolicy) string {
+	if _, err := version.NewVersion(current); err != nil {
+		return ""
+	}
+	if allowed := AllowedVersions(current, versions, policy); len(allowed) > 0 {
+		return allowed[0]
+	}
+	return current
+}
+
+// AllowedVersions returns the releases in versions that are newer than current
+// and allowed by the policy, in the order they are preferred: highest first,
+// with "+incompatible" versions after compatible ones like "go install" does.
+func AllowedVersions(current string, versions []string, policy UpdatePolicy) []string {
+	currentVer, err := version.NewVersion(current)
+	if err != nil {
+		return []string{}
+	}
+	currentSeg := currentVer.Segments()
+
+	type candidate struct {
+		raw          string
+		ver          *version.Version
+		incompatible bool
+	}
+	allowed := []candidate{}
+	seen := map[string]struct{}{}
+	for _, raw := range versions {
+		v, err := version.NewVersion(raw)
+		if err != nil || v.Prerelease() != "" || v.LessThanOrEqual(currentVer) {
+			continue
+		}
+		if _, ok := seen[raw]; ok {
+			continue
+		}
+		seg := v.Segments()
+		switch policy {
+		case UpdatePolicyPatch:
+			if seg[0] != currentSeg[0] || seg[1] != currentSeg[1] {
+				continue
+			}
+		case UpdatePolicyMinor:
+			if seg[0] != currentSeg[0] {
+				continue
+			}
+		}
+		seen[raw] = struct{}{}
+		allowed = append(allowed, candidate{raw: raw, ver: v, incompatible: strings.HasSuffix(raw, "+incompatible")})
+	}
+
+	sort.SliceStable(allowed, func(i, j int) bool {
+		if allowed[i].incompatible != allowed[j].incompatible {
+			return !allowed[i].incompatible
+		}
+		return allowed[i].ver.GreaterThan(allowed[j].ver)
+	})
+	result := make([]string, 0, len(allowed))
+	for _, c := range allowed {
+		result = append(result, c.raw)
+	}
+	return result
+}
+
 // GoPaths has $GOBIN and $GOPATH
 type GoPaths struct {
 	// GOBIN is $GOBIN
@@ -71,6 +207,301 @@ type Package struct {
 	GoVersion *Version
 	// UpdateChannel stores preferred update channel.
 	UpdateChannel UpdateChannel
+	// Policy limits how far the package is updated. Empty means UpdatePolicyMajor.
+	Policy UpdatePolicy
+	// CooldownDays skips versions published less than this many days ago.
+	// Nil means the global setting.
+	CooldownDays *int
+	// Pin is the version the package is frozen at. Empty means not pinned.
+	Pin string
+	// AllowMajor lets 'gup update' switch to the module path of a new major version.
+	AllowMajor bool
+	// Build holds the build flags and environment variables of the binary.
+	// GetPackageInformation recovers them from the build information of the binary.
+	Build BuildSettings
+	// Toolchain is the Go toolchain (GOTOOLCHAIN) the binary is built with.
+	// Empty means the installed Go toolchain.
+	Toolchain string
+	// Sum is the "h1:" checksum of the main module at Version.Current.
+	Sum string
+	// Deps are the modules the binary is built with, taken from its build information.
+	Deps []*debug.Module
+	// Settings are the build settings recorded in the binary, such as -ldflags and vcs.revision.
+	Settings []debug.BuildSetting
+}
+
+// BuildSettings are the build flags and environment variables that
+// "go install" runs with.
+type BuildSettings struct {
+	// Ldflags is passed as -ldflags.
+	Ldflags string
+	// Tags are passed as -tags.
+	Tags []string
+	// CGOEnabled sets CGO_ENABLED. Nil keeps the setting of the environment.
+	CGOEnabled *bool
+	// Trimpath adds -trimpath.
+	Trimpath bool
+	// Env holds additional environment variables.
+	Env map[string]string
+	// Toolchain sets GOTOOLCHAIN.
+	Toolchain string
+}
+
+// IsZero reports whether b leaves "go install" as is.
+func (b BuildSettings) IsZero() bool {
+	return b.Ldflags == "" && len(b.Tags) == 0 && b.CGOEnabled == nil && !b.Trimpath && len(b.Env) == 0 &&
+		b.Toolchain == ""
+}
+
+// Validate returns an error if b can not be applied to "go install".
+func (b BuildSettings) Validate() error {
+	for _, tag := range b.Tags {
+		if tag == "" || strings.ContainsAny(tag, ", \t\r\n") {
+			return fmt.Errorf("invalid build tag %q", tag)
+		}
+	}
+	for key := range b.Env {
+		if key == "" || strings.ContainsAny(key, "= \t\r\n") {
+			return fmt.Errorf("invalid environment variable name %q", key)
+		}
+		if key == keyGoBin {
+			return fmt.Errorf("%s can not be set because gup installs binaries into it", keyGoBin)
+		}
+		if key == keyGoToolchain {
+			return fmt.Errorf("%s can not be set in env, use toolchain instead", keyGoToolchain)
+		}
+	}
+	if b.Toolchain != "" {
+		return ValidateToolchain(b.Toolchain)
+	}
+	return nil
+}
+
+// Args returns the flags that b passes to "go install".
+func (b BuildSettings) Args() []string {
+	args := []string{}
+	if b.Trimpath {
+		args = append(args, "-trimpath")
+	}
+	if len(b.Tags) > 0 {
+		args = append(args, "-tags="+strings.Join(b.Tags, ","))
+	}
+	if b.Ldflags != "" {
+		args = append(args, "-ldflags="+b.Ldflags)
+	}
+	return args
+}
+
+// Environ returns the environment variables of b as KEY=VALUE, sorted by key.
+func (b BuildSettings) Environ() []string {
+	env := make([]string, 0, len(b.Env)+1)
+	for key, value := range b.Env {
+		env = append(env, key+"="+value)
+	}
+	if b.CGOEnabled != nil {
+		cgo := "0"
+		if *b.CGOEnabled {
+			cgo = "1"
+		}
+		env = append(env, "CGO_ENABLED="+cgo)
+	}
+	if b.Toolchain != "" {
+		env = append(env, keyGoToolchain+"="+b.Toolchain)
+	}
+	sort.Strings(env)
+	return env
+}
+
+// microarchDefaults maps the microarchitecture settings recorded in binaries
+// to their default values, which are not recovered.
+var microarchDefaults = map[string]string{ //nolint:gochecknoglobals
+	"GOAMD64": "v1",
+	"GOARM64": "v8.0",
+	"GO386":   "sse2",
+}
+
+// BuildSettingsFromBuildInfo recovers the build settings that "go install"
+// recorded in a binary: -tags, -ldflags, -trimpath, CGO_ENABLED=0 and
+// non-default microarchitecture levels such as GOAMD64=v3. CGO_ENABLED=1 is
+// the default wherever a C compiler is available, so it is not recovered.
+// The -X flags in -ldflags are dropped: they usually set the version of the
+// old build (e.g. -X main.version=v1.2.3), which must not leak into a rebuild
+// of another version. Set ldflags in gup.json to keep them.
+func BuildSettingsFromBuildInfo(settings []debug.BuildSetting) BuildSettings {
+	b := BuildSettings{}
+	for _, s := range settings {
+		switch s.Key {
+		case "-tags":
+			for _, tag := range strings.Split(s.Value, ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					b.Tags = append(b.Tags, tag)
+				}
+			}
+		case "-ldflags":
+			b.Ldflags = dropLinkerVars(s.Value)
+		case "-trimpath":
+			b.Trimpath = s.Value == "true"
+		case "CGO_ENABLED":
+			if s.Value == "0" {
+				disabled := false
+				b.CGOEnabled = &disabled
+			}
+		default:
+			if def, ok := microarchDefaults[s.Key]; ok && s.Value != "" && s.Value != def {
+				if b.Env == nil {
+					b.Env = map[string]string{}
+				}
+				b.Env[s.Key] = s.Value
+			}
+		}
+	}
+	return b
+}
+
+// dropLinkerVars returns ldflags without the -X flags that set string variables.
+func dropLinkerVars(ldflags string) string {
+	kept := []string{}
+	skipNext := false
+	for _, f := range splitQuotedFlags(ldflags) {
+		switch {
+		case skipNext:
+			skipNext = false
+		case f == "-X" || f == "--X":
+			skipNext = true
+		case strings.HasPrefix(f, "-X=") || strings.HasPrefix(f, "--X="):
+		default:
+			kept = append(kept, f)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+// splitQuotedFlags splits flags at spaces outside single or double quotes,
+// like the go command splits -ldflags. The quotes are kept in the fields.
+func splitQuotedFlags(flags string) []string {
+	fields := []string{}
+	start := -1
+	var quote rune
+	for i, r := range flags {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			if start < 0 {
+				start = i
+			}
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if start >= 0 {
+				fields = append(fields, flags[start:i])
+				start = -1
+			}
+		case start < 0:
+			start = i
+		}
+	}
+	if start >= 0 {
+		fields = append(fields, flags[start:])
+	}
+	return fields
+}
+
+// keyGoToolchain is the environment variable that selects the Go toolchain.
+const keyGoToolchain = "GOTOOLCHAIN"
+
+// toolchainPattern matches Go toolchain names such as go1.22.4, go1.23rc1 and
+// go1.22.4+auto. The submatches are the minor version, the patch version and
+// the prerelease suffix.
+var toolchainPattern = regexp.MustCompile(`^go1\.([0-9]+)(\.[0-9]+)?((?:rc|beta)[0-9]+)?(?:\+(?:auto|path))?$`)
+
+// firstFullReleaseMinor is the first Go minor version whose releases are named
+// with a patch version: go1.21.0 rather than go1.21.
+const firstFullReleaseMinor = 21
+
+// ValidateToolchain returns an error if toolchain is neither "local" nor a Go
+// toolchain name that GOTOOLCHAIN accepts. From Go 1.21 on, a release needs a
+// patch version (go1.22.0, not go1.22); prereleases such as go1.23rc1 do not.
+func ValidateToolchain(toolchain string) error {
+	if toolchain == "local" {
+		return nil
+	}
+	m := toolchainPattern.FindStringSubmatch(toolchain)
+	if m == nil {
+		return fmt.Errorf("invalid toolchain %q (want e.g. go1.22.4 or local)", toolchain)
+	}
+	minor, err := strconv.Atoi(m[1])
+	if err != nil {
+		return fmt.Errorf("invalid toolchain %q (want e.g. go1.22.4 or local)", toolchain)
+	}
+	if minor >= firstFullReleaseMinor && m[2] == "" && m[3] == "" {
+		return fmt.Errorf("invalid toolchain %q (Go 1.%d and later need a patch version, e.g. go1.%d.0)", toolchain, firstFullReleaseMinor, minor)
+	}
+	return nil
+}
+
+// ToolchainGoVersion returns the Go version that the toolchain selects, such as
+// "go1.22.4" for "go1.22.4+auto". ok is false for an empty or "local" toolchain,
+// which use the installed Go toolchain.
+func ToolchainGoVersion(toolchain string) (string, bool) {
+	ver, _, _ := strings.Cut(toolchain, "+")
+	if ver == "" || ver == "local" {
+		return "", false
+	}
+	return ver, true
+}
+
+// pinPattern matches the semantic versions that "go install" accepts, including
+// prerelease versions, pseudo-versions and +incompatible versions.
+var pinPattern = regexp.MustCompile(`^v(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+
+// ValidatePin returns an error if version is not a full semantic version with
+// the "v" prefix, such as v1.2.3 or v0.0.0-20240101000000-abcdef123456.
+func ValidatePin(version string) error {
+	if pinPattern.MatchString(version) {
+		return nil
+	}
+	return fmt.Errorf("invalid pinned version %q (want e.g. v1.2.3)", version)
+}
+
+// ErrChecksumMismatch is returned when an installed module does not match its recorded checksum.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// ValidateSum returns an error if sum is not a "h1:" module checksum.
+func ValidateSum(sum string) error {
+	if !strings.HasPrefix(sum, "h1:") || len(sum) == len("h1:") {
+		return fmt.Errorf("invalid sum %q (want a h1: checksum)", sum)
+	}
+	return nil
+}
+
+type buildSettingsKey struct{}
+
+type moduleSumKey struct{}
+
+// WithModuleSum returns a context that makes InstallWithContext verify that the
+// main module of the built binary has the checksum sum before installing it.
+func WithModuleSum(ctx context.Context, sum string) context.Context {
+	return context.WithValue(ctx, moduleSumKey{}, sum)
+}
+
+// ModuleSumFromContext returns the checksum attached to ctx by WithModuleSum.
+func ModuleSumFromContext(ctx context.Context) string {
+	sum, _ := ctx.Value(moduleSumKey{}).(string)
+	return sum
+}
+
+// WithBuildSettings returns a context that makes InstallWithContext and the
+// functions built on it run "go install" with b.
+func WithBuildSettings(ctx context.Context, b BuildSettings) context.Context {
+	return context.WithValue(ctx, buildSettingsKey{}, b)
+}
+
+// BuildSettingsFromContext returns the build settings attached to ctx by WithBuildSettings.
+func BuildSettingsFromContext(ctx context.Context) BuildSettings {
+	b, _ := ctx.Value(buildSettingsKey{}).(BuildSettings)
+	return b
 }
 
 // Version is package version information.
@@ -90,13 +521,23 @@ func NewVersion() *Version {
 }
 
 // NormalizeUpdateChannel normalizes a user/config value into a valid channel.
-// Unknown or blank values are treated as "latest".
+// "ref:<ref>" keeps the ref as is. Unknown or blank values are treated as "latest".
 func NormalizeUpdateChannel(channel string) UpdateChannel {
-	switch strings.ToLower(strings.TrimSpace(channel)) {
+	channel = strings.TrimSpace(channel)
+	if len(channel) >= len(updateChannelRefPrefix) && strings.EqualFold(channel[:len(updateChannelRefPrefix)], updateChannelRefPrefix) {
+		ref := strings.TrimSpace(channel[len(updateChannelRefPrefix):])
+		if isValidRef(ref) {
+			return RefUpdateChannel(ref)
+		}
+		return UpdateChannelLatest
+	}
+	switch strings.ToLower(channel) {
 	case string(UpdateChannelMain):
 		return UpdateChannelMain
 	case string(UpdateChannelMaster):
 		return UpdateChannelMaster
+	case string(UpdateChannelPrerelease):
+		return UpdateChannelPrerelease
 	case string(UpdateChannelLatest):
 		return UpdateChannelLatest
 	default:
@@ -175,8 +616,12 @@ func colorVersionPair(current, latest, prefix string) (string, string) {
 }
 
 // IsPackageUpToDate checks if the Package (set by the package author) version is up to date.
-// Returns true if current >= available.
+// Returns true if current >= available. With a "ref:<ref>" channel the ref can
+// move to any commit, so it returns true only if current == available.
 func (p *Package) IsPackageUpToDate() bool {
+	if _, ok := p.UpdateChannel.Ref(); ok {
+		return p.Version.Current == p.Version.Latest
+	}
 	return versionUpToDate(
 		strings.TrimPrefix(p.Version.Current, "v"),
 		strings.TrimPrefix(p.Version.Latest, "v"),
@@ -185,7 +630,11 @@ func (p *Package) IsPackageUpToDate() bool {
 
 // IsGoUpToDate checks if the Golang runtime version is up to date.
 // Returns true if current >= available.
+// A binary with a pinned toolchain is up to date only if it was built by that toolchain.
 func (p *Package) IsGoUpToDate() bool {
+	if ver, ok := ToolchainGoVersion(p.Toolchain); ok {
+		return goVersionMatches(strings.TrimPrefix(p.GoVersion.Current, "go"), strings.TrimPrefix(ver, "go"))
+	}
 	return goVersionUpToDate(
 		strings.TrimPrefix(p.GoVersion.Current, "go"),
 		strings.TrimPrefix(p.GoVersion.Latest, "go"),
@@ -212,6 +661,18 @@ func goVersionUpToDate(current, available string) bool {
 	)
 }
 
+// goVersionMatches reports whether current is the Go version want.
+// A want without patch version, such as "1.22", matches any 1.22.x release.
+func goVersionMatches(current, want string) bool {
+	current = strings.TrimSpace(current)
+	if !strings.HasPrefix(current, want) {
+		return false
+	}
+	rest := current[len(want):]
+	// "1.22" must not match "1.220", and "1.22.4" must not match "1.22.40".
+	return rest == "" || rest[0] < '0' || rest[0] > '9'
+}
+
 func normalizeGoVersionForCompare(ver string) string {
 	ver = strings.TrimSpace(ver)
 	if ver == "" {
@@ -383,7 +844,10 @@ func Install(importPath, version string) error {
 	return InstallWithContext(context.Background(), importPath, version)
 }
 
-// InstallWithContext executes "$ go install <importPath>@<version>".
+// InstallWithContext executes "$ go install <importPath>@<version>" with the
+// build settings attached to ctx by WithBuildSettings. The binary is built into a staging directory inside $GOBIN, checked with
+// debug/buildinfo, and then renamed into place. An interrupted or failed
+// build never replaces the installed binary.
 func InstallWithContext(ctx context.Context, importPath, version string) error {
 	if importPath == "command-line-arguments" {
 		return errors.New("is devel-binary copied from local environment")
@@ -392,31 +856,226 @@ func InstallWithContext(ctx context.Context, importPath, version string) error {
 		ctx = context.Background()
 	}
 
+	goBin, err := GoBin()
+	if err != nil {
+		return fmt.Errorf("can't install %s: %w", importPath, err)
+	}
+	if err := os.MkdirAll(goBin, fileutil.FileModeCreatingDir); err != nil {
+		return fmt.Errorf("can't install %s: %w", importPath, err)
+	}
+	// The staging directory is hidden and lives in $GOBIN so that the final
+	// rename never crosses file systems.
+	stagingDir, err := os.MkdirTemp(goBin, stagingDirPrefix)
+	if err != nil {
+		return fmt.Errorf("can't install %s: %w", importPath, err)
+	}
+	defer os.RemoveAll(stagingDir) //nolint:errcheck // best effort cleanup
+
+	build := BuildSettingsFromContext(ctx)
+	args := append([]string{"install"}, build.Args()...)
+	args = append(args, fmt.Sprintf("%s@%s", importPath, version))
+
 	var stderr bytes.Buffer
-	cmd := exec.CommandContext(ctx, goExe, "install", fmt.Sprintf("%s@%s", importPath, version)) //#nosec
+	cmd := exec.CommandContext(ctx, goExe, args...) //#nosec
+	// GOBIN comes last so that the build settings can not move the binary out of staging.
+	cmd.Env = append(append(os.Environ(), build.Environ()...), keyGoBin+"="+stagingDir)
 	cmd.Stderr = &stderr
 
-	err := cmd.Run()
-	if err != nil {
+	if err := cmd.Run(); err != nil {
 		if ctxErr := ctx.Err(); ctxErr != nil {
 			return fmt.Errorf("install of %s cancelled: %w", importPath, ctxErr)
 		}
 		return fmt.Errorf("can't install %s:\n%s", importPath, stderr.String())
 	}
+
+	staged, err := stagedBinary(stagingDir, importPath)
+	if err != nil {
+		return fmt.Errorf("can't install %s: %w", importPath, err)
+	}
+	if want := ModuleSumFromContext(ctx); want != "" {
+		if err := verifyModuleSum(staged, want); err != nil {
+			return fmt.Errorf("can't install %s: %w", importPath, err)
+		}
+	}
+	if err := fileutil.RenameWithReplace(staged, filepath.Join(goBin, filepath.Base(staged))); err != nil {
+		return fmt.Errorf("can't install %s: %w", importPath, err)
+	}
+	return nil
+}
+
+// DownloadWithContext downloads the modules that "$ go install <importPath>@<version>"
+// needs into the module cache modCacheDir. The build settings in ctx apply, so
+// that modules needed only with build tags or a pinned toolchain are included.
+// The built binary is discarded.
+func DownloadWithContext(ctx context.Context, importPath, version, modCacheDir string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	binDir, err := osMkdirTemp("", "gup-download-")
+	if err != nil {
+		return fmt.Errorf("can't download %s: %w", importPath, err)
+	}
+	defer os.RemoveAll(binDir) //nolint:errcheck // best effort cleanup
+
+	build := BuildSettingsFromContext(ctx)
+	args := append([]string{"install"}, build.Args()...)
+	args = append(args, fmt.Sprintf("%s@%s", importPath, version))
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, goExe, args...) //#nosec
+	// -modcacherw keeps the module cache removable after it is archived.
+	// It is appended to GOFLAGS of the build settings or else of the user.
+	goflags, ok := build.Env["GOFLAGS"]
+	if !ok {
+		goflags = os.Getenv("GOFLAGS")
+	}
+	cmd.Env = append(append(os.Environ(), build.Environ()...),
+		keyGoBin+"="+binDir, "GOMODCACHE="+modCacheDir, "GOFLAGS="+appendGoFlags(goflags, "-modcacherw"))
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("download of %s cancelled: %w", importPath, ctxErr)
+		}
+		return fmt.Errorf("can't download %s:\n%s", importPath, stderr.String())
+	}
 	return nil
 }
 
+// verifyModuleSum returns ErrChecksumMismatch unless the main module of the
+// binary at binPath has the checksum want.
+func verifyModuleSum(binPath, want string) error {
+	info, err := buildinfo.ReadFile(binPath)
+	if err != nil {
+		return err
+	}
+	if info.Main.Sum != want {
+		got := info.Main.Sum
+		if got == "" {
+			got = "no checksum"
+		}
+		return fmt.Errorf("%w for %s@%s: want %s, got %s", ErrChecksumMismatch, info.Main.Path, info.Main.Version, want, got)
+	}
+	return nil
+}
+
+// stagedBinary returns the binary that "go install" built into dir.
+// It fails unless dir holds exactly one binary built from importPath.
+func stagedBinary(dir, importPath string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	bins := []string{}
+	for _, e := range entries {
+		if !e.IsDir() {
+			bins = append(bins, filepath.Join(dir, e.Name()))
+		}
+	}
+	if len(bins) != 1 {
+		return "", fmt.Errorf("go install produced %d binaries, want 1", len(bins))
+	}
+
+	info, err := buildinfo.ReadFile(bins[0])
+	if err != nil {
+		return "", fmt.Errorf("can't read build info of the built binary: %w", err)
+	}
+	if info.Path != importPath {
+		return "", fmt.Errorf("built binary is %s, want %s", info.Path, importPath)
+	}
+	return bins[0], nil
+}
+
+// MajorVersionSuffix returns N when elem is a major version suffix "vN" (N >= 2)
+// of a module path, such as the "v2" in "example.com/tool/v2".
+func MajorVersionSuffix(elem string) (int, bool) {
+	num, ok := strings.CutPrefix(elem, "v")
+	if !ok || num == "" || num[0] == '0' {
+		return 0, false
+	}
+	for _, r := range num {
+		if r < '0' || r > '9' {
+			return 0, false
+		}
+	}
+	n, err := strconv.Atoi(num)
+	if err != nil || n < 2 {
+		return 0, false
+	}
+	return n, true
+}
+
+// NextMajorModulePath returns the module path of the next major version:
+// "example.com/tool" becomes "example.com/tool/v2" and "example.com/tool/v2"
+// becomes "example.com/tool/v3". gopkg.in paths, which encode the major
+// version differently, are not supported.
+func NextMajorModulePath(modulePath string) (string, bool) {
+	if modulePath == "" || strings.HasPrefix(modulePath, "gopkg.in/") {
+		return "", false
+	}
+	dir, elem := path.Split(modulePath)
+	if n, ok := MajorVersionSuffix(elem); ok && dir != "" {
+		return dir + "v" + strconv.Itoa(n+1), true
+	}
+	return modulePath + "/v2", true
+}
+
 // GetLatestVer execute "$ go list -m -f {{.Version}} <importPath>@latest"
 func GetLatestVer(modulePath string) (string, error) {
 	return GetLatestVerWithContext(context.Background(), modulePath)
 }
 
-// GetLatestVerWithContext execute "$ go list -m -f {{.Version}} <importPath>@latest"
-// with context cancellation support.
+// GetLatestVerWithContext returns the version that "$ go install <modulePath>@latest" selects.
+// The version is resolved with the module proxy protocol. Modules that must be
+// fetched from their origin (GOPROXY=direct, GOPRIVATE, GONOPROXY) and proxies
+// that can not be queried fall back to "$ go list -m -f {{.Version}} <modulePath>@latest".
 func GetLatestVerWithContext(ctx context.Context, modulePath string) (string, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
+
+	ver, err := proxyClient().LatestVersion(ctx, modulePath)
+	switch {
+	case err == nil:
+		return ver, nil
+	case ctx.Err() != nil:
+		return "", fmt.Errorf("version check of %s cancelled: %w", modulePath, ctx.Err())
+	case errors.Is(err, goproxy.ErrNotFound):
+		return "", fmt.Errorf("can't check %s:\n%w", modulePath, err)
+	default:
+		return getLatestVerByGoList(ctx, modulePath)
+	}
+}
+
+// ErrModuleNotFound is returned by ProbeLatestVerWithContext when the module does not exist.
+var ErrModuleNotFound = errors.New("module not found")
+
+// ProbeLatestVerWithContext is GetLatestVerWithContext for module paths that
+// may not exist, such as the next major version of a module. A proxy that
+// answers "not found" is final: the go command is not run even when GOPROXY
+// falls back to "direct", so probing does not cost a "go list" per module.
+// A module that does not exist is reported with ErrModuleNotFound.
+func ProbeLatestVerWithContext(ctx context.Context, modulePath string) (string, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	ver, err := proxyClient().LatestVersion(ctx, modulePath)
+	switch {
+	case err == nil:
+		return ver, nil
+	case ctx.Err() != nil:
+		return "", fmt.Errorf("version check of %s cancelled: %w", modulePath, ctx.Err())
+	case errors.Is(err, goproxy.ErrNotFound), errors.Is(err, goproxy.ErrProxyNotFound):
+		return "", fmt.Errorf("can't check %s: %w: %w", modulePath, ErrModuleNotFound, err)
+	default:
+		return getLatestVerByGoList(ctx, modulePath)
+	}
+}
+
+// getLatestVerByGoList execute "$ go list -m -f {{.Version}} <importPath>@latest"
+// with context cancellation support.
+func getLatestVerByGoList(ctx context.Context, modulePath string) (string, error) {
 	var stderr bytes.Buffer
 	cmd := exec.CommandContext(ctx, goExe, "list", "-m", "-f", "{{.Version}}", modulePath+"@latest") //#nosec
 	cmd.Stderr = &stderr
@@ -430,6 +1089,366 @@ func GetLatestVerWithContext(ctx context.Context, modulePath string) (string, er
 	return strings.TrimRight(string(out), "\n"), nil
 }
 
+// ResolveRefWithContext returns the version that "$ go install <modulePath>@<ref>"
+// selects, which is the pseudo-version of a branch or commit. The ref is resolved
+// with the module proxy's .info file and falls back to
+// "$ go list -m -f {{.Version}} <modulePath>@<ref>" like GetLatestVerWithContext.
+func ResolveRefWithContext(ctx context.Context, modulePath, ref string) (string, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	info, err := proxyClient().Info(ctx, modulePath, ref)
+	switch {
+	case err == nil:
+		return info.Version, nil
+	case ctx.Err() != nil:
+		return "", fmt.Errorf("version check of %s cancelled: %w", modulePath, ctx.Err())
+	case errors.Is(err, goproxy.ErrNotFound):
+		return "", fmt.Errorf("can't check %s@%s:\n%w", modulePath, ref, err)
+	default:
+		return getRefVerByGoList(ctx, modulePath, ref)
+	}
+}
+
+// getRefVerByGoList execute "$ go list -m -f {{.Version}} <modulePath>@<ref>".
+func getRefVerByGoList(ctx context.Context, modulePath, ref string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, goExe, "list", "-m", "-f", "{{.Version}}", modulePath+"@"+ref) //#nosec
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", fmt.Errorf("version check of %s cancelled: %w", modulePath, ctxErr)
+		}
+		return "", fmt.Errorf("can't check %s@%s:\n%s", modulePath, ref, stderr.String())
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+// GetVersionListWithContext returns the tagged versions of the module.
+// The list is read with the module proxy protocol and falls back to
+// "$ go list -m -versions <modulePath>" like GetLatestVerWithContext.
+func GetVersionListWithContext(ctx context.Context, modulePath string) ([]string, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	versions, err := proxyClient().Versions(ctx, modulePath)
+	switch {
+	case err == nil:
+		return versions, nil
+	case ctx.Err() != nil:
+		return nil, fmt.Errorf("version check of %s cancelled: %w", modulePath, ctx.Err())
+	case errors.Is(err, goproxy.ErrNotFound):
+		return nil, fmt.Errorf("can't check %s:\n%w", modulePath, err)
+	default:
+		return getVersionListByGoList(ctx, modulePath)
+	}
+}
+
+// getVersionListByGoList execute "$ go list -m -versions -json <modulePath>".
+func getVersionListByGoList(ctx context.Context, modulePath string) ([]string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, goExe, "list", "-m", "-versions", "-json", modulePath) //#nosec
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, fmt.Errorf("version check of %s cancelled: %w", modulePath, ctxErr)
+		}
+		return nil, fmt.Errorf("can't check %s:\n%s", modulePath, stderr.String())
+	}
+
+	mod := struct {
+		Versions []string `json:"Versions"`
+	}{}
+	if err := json.Unmarshal(out, &mod); err != nil {
+		return nil, fmt.Errorf("can't check %s: %w", modulePath, err)
+	}
+	if mod.Versions == nil {
+		return []string{}, nil
+	}
+	return mod.Versions, nil
+}
+
+// GetModuleRequirementsWithContext returns the module versions required by
+// the go.mod of modulePath at ver, keyed by module path. "go install
+// <module>@<version>" builds the main module with exactly these versions.
+// The go.mod is read from the module proxy and falls back to
+// "$ go mod download -json <modulePath>@<version>" like GetLatestVerWithContext.
+func GetModuleRequirementsWithContext(ctx context.Context, modulePath, ver string) (map[string]string, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	gomod, err := proxyClient().GoMod(ctx, modulePath, ver)
+	switch {
+	case err == nil:
+		return parseGoModRequirements(gomod), nil
+	case ctx.Err() != nil:
+		return nil, fmt.Errorf("version check of %s cancelled: %w", modulePath, ctx.Err())
+	case errors.Is(err, goproxy.ErrNotFound):
+		return nil, fmt.Errorf("can't read go.mod of %s@%s:\n%w", modulePath, ver, err)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, goExe, "mod", "download", "-json", modulePath+"@"+ver) //#nosec
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("can't read go.mod of %s@%s:\n%s", modulePath, ver, stderr.String())
+	}
+	mod := struct {
+		GoMod string `json:"GoMod"`
+	}{}
+	if err := json.Unmarshal(out, &mod); err != nil || mod.GoMod == "" {
+		return nil, fmt.Errorf("can't read go.mod of %s@%s: unexpected output of go mod download", modulePath, ver)
+	}
+	gomod, err = os.ReadFile(mod.GoMod)
+	if err != nil {
+		return nil, fmt.Errorf("can't read go.mod of %s@%s: %w", modulePath, ver, err)
+	}
+	return parseGoModRequirements(gomod), nil
+}
+
+// parseGoModRequirements returns the require directives of a go.mod file.
+func parseGoModRequirements(gomod []byte) map[string]string {
+	reqs := map[string]string{}
+	inBlock := false
+	for _, line := range strings.Split(string(gomod), "\n") {
+		line, _, _ = strings.Cut(line, "//")
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+			continue
+		case inBlock && fields[0] == ")":
+			inBlock = false
+			continue
+		case !inBlock && fields[0] == "require":
+			fields = fields[1:]
+			if len(fields) == 1 && fields[0] == "(" {
+				inBlock = true
+				continue
+			}
+		case !inBlock:
+			continue
+		}
+		if len(fields) == 2 {
+			reqs[strings.Trim(fields[0], `"`)] = strings.Trim(fields[1], `"`)
+		}
+	}
+	return reqs
+}
+
+// HighestVersion returns the highest valid version in versions, or "" if there is none.
+// Prerelease versions are skipped unless allowPrerelease is true.
+func HighestVersion(versions []string, allowPrerelease bool) string {
+	return goproxy.HighestVersion(versions, allowPrerelease)
+}
+
+// GetVersionTimeWithContext returns the time the version of the module was published.
+// The time is read from the module proxy's .info file and falls back to
+// "$ go list -m -json <modulePath>@<version>" like GetLatestVerWithContext.
+func GetVersionTimeWithContext(ctx context.Context, modulePath, ver string) (time.Time, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	info, err := proxyClient().Info(ctx, modulePath, ver)
+	switch {
+	case err == nil:
+		return info.Time, nil
+	case ctx.Err() != nil:
+		return time.Time{}, fmt.Errorf("version check of %s cancelled: %w", modulePath, ctx.Err())
+	case errors.Is(err, goproxy.ErrNotFound):
+		return time.Time{}, fmt.Errorf("can't check %s@%s:\n%w", modulePath, ver, err)
+	default:
+		return getVersionTimeByGoList(ctx, modulePath, ver)
+	}
+}
+
+// getVersionTimeByGoList execute "$ go list -m -json <modulePath>@<version>".
+func getVersionTimeByGoList(ctx context.Context, modulePath, ver string) (time.Time, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, goExe, "list", "-m", "-json", modulePath+"@"+ver) //#nosec
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return time.Time{}, fmt.Errorf("version check of %s cancelled: %w", modulePath, ctxErr)
+		}
+		return time.Time{}, fmt.Errorf("can't check %s@%s:\n%s", modulePath, ver, stderr.String())
+	}
+
+	mod := struct {
+		Time *time.Time `json:"Time"`
+	}{}
+	if err := json.Unmarshal(out, &mod); err != nil {
+		return time.Time{}, fmt.Errorf("can't check %s@%s: %w", modulePath, ver, err)
+	}
+	if mod.Time == nil {
+		return time.Time{}, fmt.Errorf("can't check %s@%s: publish time is unknown", modulePath, ver)
+	}
+	return *mod.Time, nil
+}
+
+// ErrNotInModCache is returned when the module cache has no versions of a module.
+var ErrNotInModCache = errors.New("not found in the module cache")
+
+// ModCacheDir returns the module cache directory ($GOMODCACHE).
+func ModCacheDir() string {
+	if dir := goEnv("GOMODCACHE")["GOMODCACHE"]; dir != "" {
+		return dir
+	}
+	gopath := filepath.SplitList(goPath())
+	if len(gopath) == 0 {
+		return ""
+	}
+	return filepath.Join(gopath[0], "pkg", "mod")
+}
+
+// ModCacheVersions returns the versions of the module listed in
+// <modCacheDir>/cache/download/<module>/@v/list, which the go command
+// records for every version it has downloaded.
+func ModCacheVersions(modCacheDir, modulePath string) ([]string, error) {
+	escaped, err := goproxy.EscapePath(modulePath)
+	if err != nil {
+		return nil, fmt.Errorf("can't check %s: %w", modulePath, err)
+	}
+	//nolint:gosec // the path is built from the module cache directory and an escaped module path.
+	raw, err := os.ReadFile(filepath.Join(modCacheDir, "cache", "download", filepath.FromSlash(escaped), "@v", "list"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("can't check %s: %w", modulePath, ErrNotInModCache)
+		}
+		return nil, fmt.Errorf("can't check %s: %w", modulePath, err)
+	}
+	versions := []string{}
+	for _, line := range strings.Split(string(raw), "\n") {
+		if v := strings.TrimSpace(line); v != "" {
+			versions = append(versions, v)
+		}
+	}
+	return versions, nil
+}
+
+// ModCacheLatestVersion returns the highest release version of the module in
+// the module cache, or the highest prerelease if no release is cached.
+func ModCacheLatestVersion(modCacheDir, modulePath string) (string, error) {
+	versions, err := ModCacheVersions(modCacheDir, modulePath)
+	if err != nil {
+		return "", err
+	}
+	if v := HighestVersion(versions, false); v != "" {
+		return v, nil
+	}
+	if v := HighestVersion(versions, true); v != "" {
+		return v, nil
+	}
+	return "", fmt.Errorf("can't check %s: %w", modulePath, ErrNotInModCache)
+}
+
+// ModCacheVersionTime returns the publish time of the module version recorded
+// in the .info file of the module cache.
+func ModCacheVersionTime(modCacheDir, modulePath, ver string) (time.Time, error) {
+	escaped, err := goproxy.EscapePath(modulePath)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("can't check %s@%s: %w", modulePath, ver, err)
+	}
+	escapedVer, err := goproxy.EscapeVersion(ver)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("can't check %s@%s: %w", modulePath, ver, err)
+	}
+	//nolint:gosec // the path is built from the module cache directory and an escaped module version.
+	raw, err := os.ReadFile(filepath.Join(modCacheDir, "cache", "download", filepath.FromSlash(escaped), "@v", escapedVer+".info"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return time.Time{}, fmt.Errorf("can't check %s@%s: %w", modulePath, ver, ErrNotInModCache)
+		}
+		return time.Time{}, fmt.Errorf("can't check %s@%s: %w", modulePath, ver, err)
+	}
+	info := goproxy.Info{}
+	if err := json.Unmarshal(raw, &info); err != nil {
+		return time.Time{}, fmt.Errorf("can't check %s@%s: %w", modulePath, ver, err)
+	}
+	return info.Time, nil
+}
+
+// EnableOfflineMode makes the go commands run by gup use only the module cache:
+// GOPROXY=off disables downloads and -mod=mod, appended to the user's GOFLAGS,
+// lets the go command resolve modules from the cache.
+func EnableOfflineMode() error {
+	if err := os.Setenv("GOPROXY", "off"); err != nil {
+		return errors.Wrap(err, "failed to set GOPROXY to env variable")
+	}
+	if err := os.Setenv("GOFLAGS", appendGoFlags(os.Getenv("GOFLAGS"), "-mod=mod")); err != nil {
+		return errors.Wrap(err, "failed to set GOFLAGS to env variable")
+	}
+	return nil
+}
+
+// appendGoFlags returns the GOFLAGS value goflags with flags appended.
+// Flags already present are not repeated; for conflicting flags such as
+// -mod=readonly and -mod=mod, the go command uses the last one.
+func appendGoFlags(goflags string, flags ...string) string {
+	fields := strings.Fields(goflags)
+	for _, f := range flags {
+		if !slices.Contains(fields, f) {
+			fields = append(fields, f)
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+// UseModuleProxyDir makes the go commands run by gup download modules only
+// from dir, a directory in the GOPROXY layout such as $GOMODCACHE/cache/download.
+// The checksum database can not be reached from such a host, so it is disabled.
+func UseModuleProxyDir(dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return errors.Wrap(err, "failed to resolve module proxy directory")
+	}
+	proxyPath := filepath.ToSlash(abs)
+	if !strings.HasPrefix(proxyPath, "/") {
+		// Windows paths such as C:/bundle need an empty host: file:///C:/bundle
+		proxyPath = "/" + proxyPath
+	}
+	if err := os.Setenv("GOPROXY", "file://"+proxyPath); err != nil {
+		return errors.Wrap(err, "failed to set GOPROXY to env variable")
+	}
+	if err := os.Setenv("GOSUMDB", "off"); err != nil {
+		return errors.Wrap(err, "failed to set GOSUMDB to env variable")
+	}
+	return nil
+}
+
+// newProxyClient returns the module proxy client configured by "go env".
+func newProxyClient() *goproxy.Client {
+	env := goEnv("GOPROXY", "GOPRIVATE", "GONOPROXY")
+	return goproxy.New(goproxy.Config{
+		Proxy:   env["GOPROXY"],
+		Private: env["GOPRIVATE"],
+		NoProxy: env["GONOPROXY"],
+	})
+}
+
+// goEnv returns the values of "$ go env" for keys.
+// Keys that the go command can not report fall back to the process environment.
+func goEnv(keys ...string) map[string]string {
+	env := make(map[string]string, len(keys))
+	out, err := exec.CommandContext(context.Background(), goExe, append([]string{"env", "-json"}, keys...)...).Output() //#nosec
+	if err == nil {
+		_ = json.Unmarshal(out, &env)
+	}
+	for _, k := range keys {
+		if _, ok := env[k]; !ok {
+			env[k] = os.Getenv(k)
+		}
+	}
+	return env
+}
+
 // goPath return GOPATH environment variable.
 func goPath() string {
 	gopath := os.Getenv(keyGoPath)
@@ -542,6 +1561,10 @@ func GetPackageInformation(binList []string) []Package {
 					GoVersion:  NewVersion(),
 				}
 				pkg.Version.Current = info.Main.Version
+				pkg.Sum = info.Main.Sum
+				pkg.Deps = info.Deps
+				pkg.Settings = info.Settings
+				pkg.Build = BuildSettingsFromBuildInfo(info.Settings)
 				pkg.GoVersion.Current, _, _ = strings.Cut(info.GoVersion, " ")
 				pkg.GoVersion.Latest = goVer
 				results[i] = indexedPkg{pkg: pkg, ok: true}
@@ -577,6 +1600,20 @@ func GetPackageVersion(cmdName string) string {
 	return info.Main.Version
 }
 
+// GetPackageSum returns the checksum of the main module of the binary,
+// or "" if it is unknown.
+func GetPackageSum(cmdName string) string {
+	goBin, err := GoBin()
+	if err != nil {
+		return ""
+	}
+	info, err := buildinfo.ReadFile(filepath.Join(goBin, cmdName))
+	if err != nil {
+		return ""
+	}
+	return info.Main.Sum
+}
+
 var goVersionRegex = regexp.MustCompile(`(^|\s)(go[1-9]\S+)`)
 var moduleDeclaresPathRegex = regexp.MustCompile(`(?m)module declares its path as:\s*(\S+)`)
 var requiredAsPathRegex = regexp.MustCompile(`(?m)but was required as:\s*(\S+)`)
@@ -4,17 +4,25 @@ package goutil
 import (
 	"bytes"
 	"context"
+	"debug/buildinfo"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"runtime/debug"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/google/go-cmp/cmp"
+	"github.com/nao1215/gup/internal/fileutil"
+	"github.com/nao1215/gup/internal/goproxy"
 	"github.com/nao1215/gup/internal/print"
 )
 
@@ -73,6 +81,298 @@ func TestGetLatestVer_unknown_module(t *testing.T) {
 	}
 }
 
+func TestGetLatestVerWithContext_moduleProxy(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/example.com/tool/@v/list" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("v1.0.0\nv1.2.0\n"))
+	}))
+	defer srv.Close()
+
+	oldProxyClient := proxyClient
+	oldGoExe := goExe
+	defer func() {
+		proxyClient = oldProxyClient
+		goExe = oldGoExe
+	}()
+	// The go command must not be needed when the proxy answers.
+	goExe = "false"
+
+	proxyClient = func() *goproxy.Client { return goproxy.New(goproxy.Config{Proxy: srv.URL}) }
+	got, err := GetLatestVerWithContext(context.Background(), "example.com/tool")
+	if err != nil {
+		t.Fatalf("GetLatestVerWithContext() error = %v", err)
+	}
+	if got != "v1.2.0" {
+		t.Errorf("GetLatestVerWithContext() = %q, want %q", got, "v1.2.0")
+	}
+
+	// Not found without a "direct" fallback is reported as is.
+	_, err = GetLatestVerWithContext(context.Background(), "example.com/missing")
+	if err == nil || !strings.Contains(err.Error(), "can't check example.com/missing") {
+		t.Errorf("GetLatestVerWithContext() error = %v, want not found error", err)
+	}
+}
+
+func TestGetVersionListWithContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/example.com/tool/@v/list" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("v1.0.0\nv1.2.0\n"))
+	}))
+	defer srv.Close()
+
+	oldProxyClient := proxyClient
+	oldGoExe := goExe
+	defer func() {
+		proxyClient = oldProxyClient
+		goExe = oldGoExe
+	}()
+	goExe = "false"
+
+	proxyClient = func() *goproxy.Client { return goproxy.New(goproxy.Config{Proxy: srv.URL}) }
+	got, err := GetVersionListWithContext(context.Background(), "example.com/tool")
+	if err != nil {
+		t.Fatalf("GetVersionListWithContext() error = %v", err)
+	}
+	if diff := cmp.Diff([]string{"v1.0.0", "v1.2.0"}, got); diff != "" {
+		t.Errorf("GetVersionListWithContext() mismatch (-want +got):\n%s", diff)
+	}
+
+	// Modules fetched directly use "go list -m -versions".
+	proxyClient = func() *goproxy.Client { return goproxy.New(goproxy.Config{Proxy: "direct"}) }
+	if _, err := GetVersionListWithContext(context.Background(), "example.com/tool"); err == nil ||
+		!strings.Contains(err.Error(), "can't check example.com/tool") {
+		t.Errorf("GetVersionListWithContext() error = %v, want go list error", err)
+	}
+}
+
+func TestGetVersionTimeWithContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/example.com/tool/@v/v1.2.0.info" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"Version":"v1.2.0","Time":"2026-03-04T05:06:07Z"}`))
+	}))
+	defer srv.Close()
+
+	oldProxyClient := proxyClient
+	oldGoExe := goExe
+	defer func() {
+		proxyClient = oldProxyClient
+		goExe = oldGoExe
+	}()
+	goExe = "false"
+
+	proxyClient = func() *goproxy.Client { return goproxy.New(goproxy.Config{Proxy: srv.URL}) }
+	got, err := GetVersionTimeWithContext(context.Background(), "example.com/tool", "v1.2.0")
+	if err != nil {
+		t.Fatalf("GetVersionTimeWithContext() error = %v", err)
+	}
+	if want := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("GetVersionTimeWithContext() = %v, want %v", got, want)
+	}
+
+	if _, err := GetVersionTimeWithContext(context.Background(), "example.com/tool", "v9.9.9"); err == nil ||
+		!strings.Contains(err.Error(), "can't check example.com/tool@v9.9.9") {
+		t.Errorf("GetVersionTimeWithContext() error = %v, want not found error", err)
+	}
+}
+
+func TestGetModuleRequirementsWithContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/example.com/tool/@v/v1.2.0.mod" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`module example.com/tool
+
+go 1.22
+
+require golang.org/x/text v0.14.0
+
+require (
+	golang.org/x/net v0.17.0
+	// a comment line
+	golang.org/x/sys v0.13.0 // indirect
+)
+
+replace golang.org/x/net => golang.org/x/net v0.20.0
+`))
+	}))
+	defer srv.Close()
+
+	oldProxyClient := proxyClient
+	oldGoExe := goExe
+	defer func() {
+		proxyClient = oldProxyClient
+		goExe = oldGoExe
+	}()
+	goExe = "false"
+
+	proxyClient = func() *goproxy.Client { return goproxy.New(goproxy.Config{Proxy: srv.URL}) }
+	got, err := GetModuleRequirementsWithContext(context.Background(), "example.com/tool", "v1.2.0")
+	if err != nil {
+		t.Fatalf("GetModuleRequirementsWithContext() error = %v", err)
+	}
+	want := map[string]string{
+		"golang.org/x/text": "v0.14.0",
+		"golang.org/x/net":  "v0.17.0",
+		"golang.org/x/sys":  "v0.13.0",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GetModuleRequirementsWithContext() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := GetModuleRequirementsWithContext(context.Background(), "example.com/tool", "v9.9.9"); err == nil ||
+		!strings.Contains(err.Error(), "can't read go.mod of example.com/tool@v9.9.9") {
+		t.Errorf("GetModuleRequirementsWithContext() error = %v, want not found error", err)
+	}
+}
+
+func TestResolveRefWithContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/example.com/tool/@v/develop.info" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"Version":"v1.3.0-0.20260304050607-4f2c1a9b8e7d","Time":"2026-03-04T05:06:07Z"}`))
+	}))
+	defer srv.Close()
+
+	oldProxyClient := proxyClient
+	oldGoExe := goExe
+	defer func() {
+		proxyClient = oldProxyClient
+		goExe = oldGoExe
+	}()
+	goExe = "false"
+
+	proxyClient = func() *goproxy.Client { return goproxy.New(goproxy.Config{Proxy: srv.URL}) }
+	got, err := ResolveRefWithContext(context.Background(), "example.com/tool", "develop")
+	if err != nil {
+		t.Fatalf("ResolveRefWithContext() error = %v", err)
+	}
+	if want := "v1.3.0-0.20260304050607-4f2c1a9b8e7d"; got != want {
+		t.Errorf("ResolveRefWithContext() = %q, want %q", got, want)
+	}
+
+	if _, err := ResolveRefWithContext(context.Background(), "example.com/tool", "missing"); err == nil ||
+		!strings.Contains(err.Error(), "can't check example.com/tool@missing") {
+		t.Errorf("ResolveRefWithContext() error = %v, want not found error", err)
+	}
+
+	// Refs that can not be escaped for the proxy are resolved by the go command.
+	goExe = "echo"
+	got, err = ResolveRefWithContext(context.Background(), "example.com/tool", "feature/x")
+	if err != nil {
+		t.Fatalf("ResolveRefWithContext() error = %v", err)
+	}
+	if want := "list -m -f {{.Version}} example.com/tool@feature/x"; got != want {
+		t.Errorf("ResolveRefWithContext() = %q, want %q", got, want)
+	}
+}
+
+func TestNextMajorModulePath(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   string
+		wantOK bool
+	}{
+		{in: "example.com/tool", want: "example.com/tool/v2", wantOK: true},
+		{in: "example.com/tool/v2", want: "example.com/tool/v3", wantOK: true},
+		{in: "example.com/tool/v19", want: "example.com/tool/v20", wantOK: true},
+		{in: "example.com/tool/v1", want: "example.com/tool/v1/v2", wantOK: true},
+		{in: "example.com/tool/v02", want: "example.com/tool/v02/v2", wantOK: true},
+		{in: "gopkg.in/yaml.v3", want: "", wantOK: false},
+		{in: "", want: "", wantOK: false},
+	}
+	for _, tt := range tests {
+		got, ok := NextMajorModulePath(tt.in)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("NextMajorModulePath(%q) = (%q, %v), want (%q, %v)", tt.in, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestUpdateChannel_Ref(t *testing.T) {
+	if ref, ok := RefUpdateChannel("develop").Ref(); !ok || ref != "develop" {
+		t.Errorf("Ref() = (%q, %v), want (develop, true)", ref, ok)
+	}
+	if ref, ok := UpdateChannelMain.Ref(); ok || ref != "" {
+		t.Errorf("Ref() = (%q, %v), want (\"\", false)", ref, ok)
+	}
+}
+
+func TestGetLatestVerWithContext_fallbackToGoList(t *testing.T) {
+	oldProxyClient := proxyClient
+	oldGoExe := goExe
+	defer func() {
+		proxyClient = oldProxyClient
+		goExe = oldGoExe
+	}()
+	// "echo" prints the arguments, which proves the go command was used.
+	goExe = "echo"
+
+	proxyClient = func() *goproxy.Client { return goproxy.New(goproxy.Config{Proxy: "direct"}) }
+	got, err := GetLatestVerWithContext(context.Background(), "example.com/tool")
+	if err != nil {
+		t.Fatalf("GetLatestVerWithContext() error = %v", err)
+	}
+	if !strings.Contains(got, "example.com/tool@latest") {
+		t.Errorf("GetLatestVerWithContext() = %q, want output of go list", got)
+	}
+}
+
+func TestProbeLatestVerWithContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/example.com/tool/v2/@v/list" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("v2.0.0\nv2.1.0\n"))
+	}))
+	defer srv.Close()
+
+	oldProxyClient := proxyClient
+	oldGoExe := goExe
+	defer func() {
+		proxyClient = oldProxyClient
+		goExe = oldGoExe
+	}()
+	// "echo" succeeds, so any use of the go command would hide the not found error.
+	goExe = "echo"
+
+	proxyClient = func() *goproxy.Client { return goproxy.New(goproxy.Config{Proxy: srv.URL + ",direct"}) }
+	got, err := ProbeLatestVerWithContext(context.Background(), "example.com/tool/v2")
+	if err != nil {
+		t.Fatalf("ProbeLatestVerWithContext() error = %v", err)
+	}
+	if got != "v2.1.0" {
+		t.Errorf("ProbeLatestVerWithContext() = %q, want %q", got, "v2.1.0")
+	}
+
+	// A proxy 404 is final even though GOPROXY falls back to "direct".
+	if _, err := ProbeLatestVerWithContext(context.Background(), "example.com/tool/v3"); !errors.Is(err, ErrModuleNotFound) {
+		t.Errorf("ProbeLatestVerWithContext() error = %v, want %v", err, ErrModuleNotFound)
+	}
+
+	// Modules that are only fetched directly still use the go command.
+	proxyClient = func() *goproxy.Client { return goproxy.New(goproxy.Config{Proxy: "direct"}) }
+	got, err = ProbeLatestVerWithContext(context.Background(), "example.com/tool/v3")
+	if err != nil {
+		t.Fatalf("ProbeLatestVerWithContext() error = %v", err)
+	}
+	if !strings.Contains(got, "example.com/tool/v3@latest") {
+		t.Errorf("ProbeLatestVerWithContext() = %q, want output of go list", got)
+	}
+}
+
 func TestDetectModulePathMismatch(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -305,57 +605,437 @@ func TestInstall_arg_is_command_line_arguments(t *testing.T) {
 	}
 }
 
-func TestInstallLatest_golden(t *testing.T) {
-	// Backup and defer restore
-	OldGoExe := goExe
-	defer func() {
-		goExe = OldGoExe
-	}()
+// fakeGoInstall replaces the go command with a script that installs the test
+// binary into $GOBIN as name, and points $GOBIN to a temporary directory.
+// It returns $GOBIN and the import path recorded in the test binary.
+// The script records its arguments, CGO_ENABLED and GOFLAGS in "invocation" next to $GOBIN.
+func fakeGoInstall(t *testing.T, name string) (string, string) {
+	t.Helper()
 
-	// Mock the `go` to `echo` command to print instead of executing go.
-	//
-	// This will succeed executing via `exec.Command` and will not execute the
-	// actual `go install <package>` command but `echo install <package>`.
-	goExe = "echo"
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake go command is a shell script")
+	}
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := buildinfo.ReadFile(self)
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	err := InstallLatest("github.com/nao1215/gup")
+	dir := t.TempDir()
+	script := filepath.Join(dir, "go")
+	content := fmt.Sprintf("#!/bin/sh\nprintf '%%s\\n' \"$*\" \"CGO_ENABLED=$CGO_ENABLED\" \"GOFLAGS=$GOFLAGS\" > %q\ncp %q \"$GOBIN/%s\"\n",
+		filepath.Join(dir, "invocation"), self, name)
+	if err := os.WriteFile(script, []byte(content), 0o700); err != nil { //nolint:gosec // test script must be executable
+		t.Fatal(err)
+	}
+
+	oldGoExe := goExe
+	t.Cleanup(func() { goExe = oldGoExe })
+	goExe = script
+
+	goBin := filepath.Join(dir, "bin")
+	t.Setenv(keyGoBin, goBin)
+	t.Setenv("GOFLAGS", "")
+	return goBin, info.Path
+}
+
+// assertNoStagingDir fails if a staging directory remains in goBin.
+func assertNoStagingDir(t *testing.T, goBin string) {
+	t.Helper()
+
+	matches, err := filepath.Glob(filepath.Join(goBin, stagingDirPrefix+"*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 0 {
+		t.Errorf("staging directories remain: %v", matches)
+	}
+}
+
+func TestInstallLatest_golden(t *testing.T) {
+	goBin, importPath := fakeGoInstall(t, "tool")
+
+	err := InstallLatest(importPath)
 
 	// Require to be no error
 	if err != nil {
 		t.Fatalf("it should not return error. got: %v", err)
 	}
+	if !fileutil.IsFile(filepath.Join(goBin, "tool")) {
+		t.Errorf("binary was not installed into %s", goBin)
+	}
+	assertNoStagingDir(t, goBin)
 }
 
 func TestInstall_specificVersion_golden(t *testing.T) {
-	// Backup and defer restore
-	oldGoExe := goExe
-	defer func() {
-		goExe = oldGoExe
-	}()
+	goBin, importPath := fakeGoInstall(t, "tool")
 
-	// Mock the `go` to `echo` command to print instead of executing go.
-	goExe = "echo"
-
-	err := Install("github.com/nao1215/gup", "v1.0.0")
+	err := Install(importPath, "v1.0.0")
 	if err != nil {
 		t.Fatalf("it should not return error. got: %v", err)
 	}
+	if !fileutil.IsFile(filepath.Join(goBin, "tool")) {
+		t.Errorf("binary was not installed into %s", goBin)
+	}
 }
 
-func TestInstallMaster_golden(t *testing.T) {
-	// Backup and defer restore
-	OldGoExe := goExe
-	defer func() {
-		goExe = OldGoExe
-	}()
+func TestInstallWithContext_buildSettings(t *testing.T) {
+	goBin, importPath := fakeGoInstall(t, "tool")
+	t.Setenv("CGO_ENABLED", "1")
 
-	// Mock the `go` to `echo` command to print instead of executing go.
-	//
-	// This will succeed executing via `exec.Command` and will not execute the
-	// actual `go install <package>` command but `echo install <package>`.
-	goExe = "echo"
+	cgo := false
+	ctx := WithBuildSettings(context.Background(), BuildSettings{
+		Ldflags:    "-s -w",
+		Tags:       []string{"netgo", "osusergo"},
+		CGOEnabled: &cgo,
+		Trimpath:   true,
+		Env:        map[string]string{keyGoBin: "/must/not/win"},
+	})
+	if err := InstallWithContext(ctx, importPath, "v1.0.0"); err != nil {
+		t.Fatalf("InstallWithContext() error = %v", err)
+	}
+	if !fileutil.IsFile(filepath.Join(goBin, "tool")) {
+		t.Errorf("binary was not installed into %s", goBin)
+	}
+
+	got, err := os.ReadFile(filepath.Join(filepath.Dir(goBin), "invocation"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "install -trimpath -tags=netgo,osusergo -ldflags=-s -w " + importPath + "@v1.0.0\nCGO_ENABLED=0\nGOFLAGS=\n"
+	if string(got) != want {
+		t.Errorf("go command invocation = %q, want %q", got, want)
+	}
+}
+
+func TestInstallWithContext_moduleSum(t *testing.T) {
+	goBin, importPath := fakeGoInstall(t, "tool")
+
+	// The test binary is built from the main module without a checksum.
+	ctx := WithModuleSum(context.Background(), "h1:2Gc3kKmyDWOwkmQHMK4ABp5Cdx0BXsXN3p8jRrfi1rM=")
+	err := InstallWithContext(ctx, importPath, "v1.0.0")
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("InstallWithContext() error = %v, want ErrChecksumMismatch", err)
+	}
+	if fileutil.IsFile(filepath.Join(goBin, "tool")) {
+		t.Error("a binary with a mismatched checksum must not be installed")
+	}
+}
+
+func TestValidatePin(t *testing.T) {
+	tests := []struct {
+		version string
+		wantErr bool
+	}{
+		{version: "v1.2.3"},
+		{version: "v0.0.1"},
+		{version: "v2.0.0-rc.1"},
+		{version: "v0.0.0-20240101000000-abcdef123456"},
+		{version: "v2.0.0+incompatible"},
+		{version: "1.2.3", wantErr: true},
+		{version: "v1.2", wantErr: true},
+		{version: "v1", wantErr: true},
+		{version: "v01.2.3", wantErr: true},
+		{version: "latest", wantErr: true},
+		{version: "(devel)", wantErr: true},
+		{version: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := ValidatePin(tt.version); (err != nil) != tt.wantErr {
+			t.Errorf("ValidatePin(%q) error = %v, wantErr %v", tt.version, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateSum(t *testing.T) {
+	if err := ValidateSum("h1:2Gc3kKmyDWOwkmQHMK4ABp5Cdx0BXsXN3p8jRrfi1rM="); err != nil {
+		t.Errorf("ValidateSum() error = %v", err)
+	}
+	for _, invalid := range []string{"", "h1:", "2Gc3kKmyDWOwkmQHMK4ABp5Cdx0BXsXN3p8jRrfi1rM="} {
+		if err := ValidateSum(invalid); err == nil {
+			t.Errorf("ValidateSum(%q) should return error", invalid)
+		}
+	}
+}
+
+func TestEnableOfflineMode(t *testing.T) {
+	t.Setenv("GOPROXY", "https://proxy.example.com")
+	t.Setenv("GOFLAGS", "-trimpath -tags=netgo")
+
+	if err := EnableOfflineMode(); err != nil {
+		t.Fatalf("EnableOfflineMode() error = %v", err)
+	}
+	if got := os.Getenv("GOPROXY"); got != "off" {
+		t.Errorf("GOPROXY = %q, want off", got)
+	}
+	// The user's GOFLAGS survive.
+	if got, want := os.Getenv("GOFLAGS"), "-trimpath -tags=netgo -mod=mod"; got != want {
+		t.Errorf("GOFLAGS = %q, want %q", got, want)
+	}
+}
+
+func Test_appendGoFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		goflags string
+		flags   []string
+		want    string
+	}{
+		{name: "empty", goflags: "", flags: []string{"-mod=mod"}, want: "-mod=mod"},
+		{name: "append", goflags: "-trimpath", flags: []string{"-mod=mod"}, want: "-trimpath -mod=mod"},
+		{name: "already present", goflags: "-mod=mod -trimpath", flags: []string{"-mod=mod"}, want: "-mod=mod -trimpath"},
+		{name: "extra spaces", goflags: "  -trimpath   -buildvcs=false ", flags: []string{"-modcacherw"}, want: "-trimpath -buildvcs=false -modcacherw"},
+		{name: "conflicting flag is appended last", goflags: "-mod=readonly", flags: []string{"-mod=mod"}, want: "-mod=readonly -mod=mod"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := appendGoFlags(tt.goflags, tt.flags...); got != tt.want {
+				t.Errorf("appendGoFlags() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDownloadWithContext(t *testing.T) {
+	goBin, importPath := fakeGoInstall(t, "tool")
+	modCacheDir := t.TempDir()
+	t.Setenv("GOFLAGS", "-trimpath")
+
+	ctx := WithBuildSettings(context.Background(), BuildSettings{Tags: []string{"netgo"}})
+	if err := DownloadWithContext(ctx, importPath, "v1.0.0", modCacheDir); err != nil {
+		t.Fatalf("DownloadWithContext() error = %v", err)
+	}
+	if fileutil.IsFile(filepath.Join(goBin, "tool")) {
+		t.Error("the binary built for downloading modules must not be installed")
+	}
+
+	got, err := os.ReadFile(filepath.Join(filepath.Dir(goBin), "invocation"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "install -tags=netgo " + importPath + "@v1.0.0\n"; !strings.HasPrefix(string(got), want) {
+		t.Errorf("go command invocation = %q, want %q", got, want)
+	}
+	// -modcacherw is appended to the user's GOFLAGS.
+	if want := "\nGOFLAGS=-trimpath -modcacherw\n"; !strings.HasSuffix(string(got), want) {
+		t.Errorf("go command invocation = %q, want suffix %q", got, want)
+	}
+}
 
-	err := InstallMainOrMaster("github.com/nao1215/gup")
+func TestBuildSettings(t *testing.T) {
+	cgo := true
+	b := BuildSettings{
+		Tags:       []string{"netgo"},
+		CGOEnabled: &cgo,
+		Env:        map[string]string{"GOAMD64": "v3", "GOFLAGS": "-mod=mod"},
+	}
+	if b.IsZero() || !(BuildSettings{}).IsZero() {
+		t.Error("IsZero() reports wrong result")
+	}
+	if diff := cmp.Diff([]string{"-tags=netgo"}, b.Args()); diff != "" {
+		t.Errorf("Args() mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"CGO_ENABLED=1", "GOAMD64=v3", "GOFLAGS=-mod=mod"}, b.Environ()); diff != "" {
+		t.Errorf("Environ() mismatch (-want +got):\n%s", diff)
+	}
+	if err := b.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	for _, invalid := range []BuildSettings{
+		{Tags: []string{"a,b"}},
+		{Tags: []string{""}},
+		{Env: map[string]string{"A=B": "c"}},
+		{Env: map[string]string{keyGoBin: "/tmp"}},
+		{Env: map[string]string{keyGoToolchain: "go1.22.4"}},
+		{Toolchain: "1.22"},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("Validate(%+v) should return error", invalid)
+		}
+	}
+}
+
+func TestBuildSettings_toolchain(t *testing.T) {
+	b := BuildSettings{Toolchain: "go1.22.4"}
+	if b.IsZero() {
+		t.Error("IsZero() = true, want false")
+	}
+	if diff := cmp.Diff([]string{"GOTOOLCHAIN=go1.22.4"}, b.Environ()); diff != "" {
+		t.Errorf("Environ() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestValidateToolchain(t *testing.T) {
+	tests := []struct {
+		toolchain string
+		wantErr   bool
+	}{
+		{toolchain: "local"},
+		{toolchain: "go1.22.4"},
+		{toolchain: "go1.22.0"},
+		{toolchain: "go1.23rc1"},
+		{toolchain: "go1.21beta1"},
+		{toolchain: "go1.22.4+auto"},
+		{toolchain: "go1.21.0+path"},
+		// Before Go 1.21, the first release of a minor version had no patch version.
+		{toolchain: "go1.20"},
+		{toolchain: "go1.20.14"},
+		// From Go 1.21 on, GOTOOLCHAIN needs a full release name.
+		{toolchain: "go1.22", wantErr: true},
+		{toolchain: "go1.21", wantErr: true},
+		{toolchain: "go1.22+auto", wantErr: true},
+		{toolchain: "go1.21+path", wantErr: true},
+		{toolchain: "", wantErr: true},
+		{toolchain: "auto", wantErr: true},
+		{toolchain: "1.22.4", wantErr: true},
+		{toolchain: "go1", wantErr: true},
+		{toolchain: "go1.22.4+foo", wantErr: true},
+		{toolchain: "go1.22 ", wantErr: true},
+		{toolchain: "go1.22.4.1", wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := ValidateToolchain(tt.toolchain); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateToolchain(%q) error = %v, wantErr %v", tt.toolchain, err, tt.wantErr)
+		}
+	}
+}
+
+func TestToolchainGoVersion(t *testing.T) {
+	tests := []struct {
+		toolchain string
+		want      string
+		wantOK    bool
+	}{
+		{toolchain: "go1.22.4", want: "go1.22.4", wantOK: true},
+		{toolchain: "go1.22.4+auto", want: "go1.22.4", wantOK: true},
+		{toolchain: "local"},
+		{toolchain: ""},
+	}
+	for _, tt := range tests {
+		got, ok := ToolchainGoVersion(tt.toolchain)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("ToolchainGoVersion(%q) = %q, %v, want %q, %v", tt.toolchain, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestPackage_IsGoUpToDate_toolchain(t *testing.T) {
+	tests := []struct {
+		toolchain string
+		current   string
+		want      bool
+	}{
+		{toolchain: "go1.22", current: "go1.22.4", want: true},
+		{toolchain: "go1.22.4", current: "go1.22.4", want: true},
+		{toolchain: "go1.22.4+auto", current: "go1.22.4", want: true},
+		{toolchain: "go1.22", current: "go1.25.0", want: false},
+		{toolchain: "go1.22", current: "go1.220", want: false},
+		{toolchain: "go1.22.4", current: "go1.22.40", want: false},
+	}
+	for _, tt := range tests {
+		p := Package{Toolchain: tt.toolchain, GoVersion: &Version{Current: tt.current, Latest: "go1.25.0"}}
+		if got := p.IsGoUpToDate(); got != tt.want {
+			t.Errorf("IsGoUpToDate() with toolchain %q and %q = %v, want %v", tt.toolchain, tt.current, got, tt.want)
+		}
+	}
+}
+
+func TestModCache(t *testing.T) {
+	dir := t.TempDir()
+	// Upper-case letters are escaped as "!" + lower case in the module cache.
+	vdir := filepath.Join(dir, "cache", "download", "github.com", "!burnt!sushi", "toml", "@v")
+	if err := os.MkdirAll(vdir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(vdir, "list"), []byte("v1.3.0\nv1.4.0\nv1.5.0-rc.1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(vdir, "v1.4.0.info"), []byte(`{"Version":"v1.4.0","Time":"2024-06-01T00:00:00Z"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := ModCacheVersions(dir, "github.com/BurntSushi/toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"v1.3.0", "v1.4.0", "v1.5.0-rc.1"}, versions); diff != "" {
+		t.Errorf("ModCacheVersions() mismatch (-want +got):\n%s", diff)
+	}
+	if got, err := ModCacheLatestVersion(dir, "github.com/BurntSushi/toml"); err != nil || got != "v1.4.0" {
+		t.Errorf("ModCacheLatestVersion() = %q, %v, want v1.4.0", got, err)
+	}
+	published, err := ModCacheVersionTime(dir, "github.com/BurntSushi/toml", "v1.4.0")
+	if err != nil || !published.Equal(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("ModCacheVersionTime() = %v, %v, want 2024-06-01", published, err)
+	}
+
+	if _, err := ModCacheLatestVersion(dir, "example.com/missing"); !errors.Is(err, ErrNotInModCache) {
+		t.Errorf("ModCacheLatestVersion() error = %v, want ErrNotInModCache", err)
+	}
+	if _, err := ModCacheVersionTime(dir, "github.com/BurntSushi/toml", "v1.3.0"); !errors.Is(err, ErrNotInModCache) {
+		t.Errorf("ModCacheVersionTime() error = %v, want ErrNotInModCache", err)
+	}
+}
+
+func TestBuildSettingsFromBuildInfo(t *testing.T) {
+	got := BuildSettingsFromBuildInfo([]debug.BuildSetting{
+		{Key: "-buildmode", Value: "exe"},
+		{Key: "-ldflags", Value: "-s -w -X main.version=v1.0.0"},
+		{Key: "-tags", Value: "netgo,osusergo"},
+		{Key: "-trimpath", Value: "true"},
+		{Key: "CGO_ENABLED", Value: "0"},
+		{Key: "GOARCH", Value: "amd64"},
+		{Key: "GOAMD64", Value: "v3"},
+	})
+	cgo := false
+	want := BuildSettings{
+		Ldflags:    "-s -w",
+		Tags:       []string{"netgo", "osusergo"},
+		CGOEnabled: &cgo,
+		Trimpath:   true,
+		Env:        map[string]string{"GOAMD64": "v3"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("BuildSettingsFromBuildInfo() mismatch (-want +got):\n%s", diff)
+	}
+
+	// CGO_ENABLED=1 and GOAMD64=v1 are the defaults and are not recovered.
+	if got := BuildSettingsFromBuildInfo([]debug.BuildSetting{
+		{Key: "CGO_ENABLED", Value: "1"},
+		{Key: "GOAMD64", Value: "v1"},
+	}); !got.IsZero() {
+		t.Errorf("BuildSettingsFromBuildInfo() = %+v, want zero settings", got)
+	}
+}
+
+func Test_dropLinkerVars(t *testing.T) {
+	tests := []struct {
+		ldflags string
+		want    string
+	}{
+		{ldflags: "-s -w", want: "-s -w"},
+		{ldflags: "-s -w -X main.version=v1.2.3", want: "-s -w"},
+		{ldflags: "-X main.version=v1.2.3 -X=main.commit=abc -s", want: "-s"},
+		{ldflags: "--X main.version=v1.2.3 -w", want: "-w"},
+		{ldflags: "-X 'main.name=my tool' -extldflags \"-static -s\"", want: "-extldflags \"-static -s\""},
+		{ldflags: "-X main.version=v1.2.3", want: ""},
+		{ldflags: "", want: ""},
+	}
+	for _, tt := range tests {
+		if got := dropLinkerVars(tt.ldflags); got != tt.want {
+			t.Errorf("dropLinkerVars(%q) = %q, want %q", tt.ldflags, got, tt.want)
+		}
+	}
+}
+
+func TestInstallMaster_golden(t *testing.T) {
+	_, importPath := fakeGoInstall(t, "tool")
+
+	err := InstallMainOrMaster(importPath)
 
 	// Require to be no error
 	if err != nil {
@@ -363,6 +1043,63 @@ func TestInstallMaster_golden(t *testing.T) {
 	}
 }
 
+func TestInstall_failedBuildKeepsInstalledBinary(t *testing.T) {
+	goBin, importPath := fakeGoInstall(t, "tool")
+	if err := os.MkdirAll(goBin, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	installed := filepath.Join(goBin, "tool")
+	if err := os.WriteFile(installed, []byte("installed"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	goExe = "false"
+	if err := Install(importPath, "v1.0.0"); err == nil {
+		t.Fatal("Install() should return error when go install fails")
+	}
+
+	got, err := os.ReadFile(filepath.Clean(installed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "installed" {
+		t.Errorf("installed binary was replaced: %q", got)
+	}
+	assertNoStagingDir(t, goBin)
+}
+
+func TestInstall_rejectsUnexpectedBinary(t *testing.T) {
+	goBin, _ := fakeGoInstall(t, "tool")
+
+	err := Install("example.com/other/tool", "v1.0.0")
+	if err == nil {
+		t.Fatal("Install() should return error when the built binary has another import path")
+	}
+	if !strings.Contains(err.Error(), "want example.com/other/tool") {
+		t.Errorf("Install() error = %v, want the import path mismatch", err)
+	}
+	if fileutil.IsFile(filepath.Join(goBin, "tool")) {
+		t.Error("unexpected binary was installed")
+	}
+	assertNoStagingDir(t, goBin)
+}
+
+func TestStagedBinary_requiresOneBinary(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := stagedBinary(dir, "example.com/tool"); err == nil {
+		t.Error("stagedBinary() should return error for an empty directory")
+	}
+
+	for _, name := range []string{"a", "b"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := stagedBinary(dir, "example.com/tool"); err == nil {
+		t.Error("stagedBinary() should return error for two binaries")
+	}
+}
+
 func TestIsUpToDate_golden(t *testing.T) {
 	for i, test := range []struct {
 		curr     string
@@ -396,6 +1133,11 @@ func TestIsUpToDate_golden(t *testing.T) {
 		{curr: "v1.9.1", latest: "1.9.0", currGo: "go1.22.4", latestGo: "go1.22.4", expect: true},
 		{curr: "1.9.0", latest: "v1.9.1", currGo: "go1.22.4", latestGo: "go1.22.4", expect: false},
 		{curr: "1.9.1", latest: "v1.9.0", currGo: "go1.22.4", latestGo: "go1.22.4", expect: true},
+		// Prereleases
+		{curr: "v1.2.0-rc.2", latest: "v1.2.0-rc.10", currGo: "go1.22.4", latestGo: "go1.22.4", expect: false},
+		{curr: "v1.2.0-beta.3", latest: "v1.2.0-rc.1", currGo: "go1.22.4", latestGo: "go1.22.4", expect: false},
+		{curr: "v1.2.0-rc.1", latest: "v1.2.0", currGo: "go1.22.4", latestGo: "go1.22.4", expect: false},
+		{curr: "v1.2.0-rc.1", latest: "v1.1.9", currGo: "go1.22.4", latestGo: "go1.22.4", expect: true},
 		// Issue #36
 		{curr: "v1.9.1-0.20220908165354-f7355b5d2afa", latest: "v1.9.0", currGo: "go1.22.4", latestGo: "go1.22.4", expect: true},
 	} {
@@ -422,6 +1164,23 @@ func TestIsUpToDate_golden(t *testing.T) {
 	}
 }
 
+func TestPackage_IsPackageUpToDate_ref(t *testing.T) {
+	pkg := Package{
+		Version: &Version{
+			Current: "v1.3.0",
+			Latest:  "v1.2.1-0.20260304050607-4f2c1a9b8e7d",
+		},
+		UpdateChannel: RefUpdateChannel("develop"),
+	}
+	if pkg.IsPackageUpToDate() {
+		t.Error("IsPackageUpToDate() = true, want false when the ref moved to another commit")
+	}
+	pkg.Version.Current = pkg.Version.Latest
+	if !pkg.IsPackageUpToDate() {
+		t.Error("IsPackageUpToDate() = false, want true at the ref's version")
+	}
+}
+
 func TestVersionUpToDate_golden(t *testing.T) {
 	type args struct {
 		current   string
@@ -602,9 +1361,14 @@ func TestNormalizeUpdateChannel(t *testing.T) {
 		{name: "latest", in: "latest", want: UpdateChannelLatest},
 		{name: "main", in: "main", want: UpdateChannelMain},
 		{name: "master", in: "master", want: UpdateChannelMaster},
+		{name: "prerelease", in: "prerelease", want: UpdateChannelPrerelease},
 		{name: "upper case", in: "MAIN", want: UpdateChannelMain},
 		{name: "blank defaults latest", in: "", want: UpdateChannelLatest},
 		{name: "unknown defaults latest", in: "snapshot", want: UpdateChannelLatest},
+		{name: "ref keeps the ref", in: " REF:Release-2.x ", want: UpdateChannel("ref:Release-2.x")},
+		{name: "commit ref", in: "ref:4f2c1a9", want: RefUpdateChannel("4f2c1a9")},
+		{name: "empty ref defaults latest", in: "ref:", want: UpdateChannelLatest},
+		{name: "invalid ref defaults latest", in: "ref:dev@1", want: UpdateChannelLatest},
 	}
 
 	for _, tt := range tests {
@@ -1226,3 +1990,73 @@ func TestGetPackageInformation_emptyList(t *testing.T) {
 		t.Errorf("expected nil for empty list, got %v", result)
 	}
 }
+
+func TestParseUpdatePolicy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in      string
+		want    UpdatePolicy
+		wantErr bool
+	}{
+		{in: "", want: ""},
+		{in: "patch", want: UpdatePolicyPatch},
+		{in: " Minor ", want: UpdatePolicyMinor},
+		{in: "MAJOR", want: UpdatePolicyMajor},
+		{in: "newest", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseUpdatePolicy(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseUpdatePolicy(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseUpdatePolicy(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestHighestAllowedVersion(t *testing.T) {
+	t.Parallel()
+
+	versions := []string{"v1.2.3", "v1.2.4", "v1.2.10", "v1.3.0", "v1.4.0-rc.1", "v2.0.0+incompatible"}
+	tests := []struct {
+		name    string
+		current string
+		policy  UpdatePolicy
+		want    string
+	}{
+		{name: "patch", current: "v1.2.3", policy: UpdatePolicyPatch, want: "v1.2.10"},
+		{name: "minor", current: "v1.2.3", policy: UpdatePolicyMinor, want: "v1.3.0"},
+		// Like @latest, a compatible version wins over "+incompatible" ones.
+		{name: "major", current: "v1.2.3", policy: UpdatePolicyMajor, want: "v1.3.0"},
+		{name: "no allowed update", current: "v1.3.0", policy: UpdatePolicyPatch, want: "v1.3.0"},
+		{name: "newer than every version", current: "v3.0.0", policy: UpdatePolicyMinor, want: "v3.0.0"},
+		{name: "pseudo-version", current: "v1.2.4-0.20260101000000-abcdefabcdef", policy: UpdatePolicyPatch, want: "v1.2.10"},
+		{name: "invalid current", current: "(devel)", policy: UpdatePolicyPatch, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := HighestAllowedVersion(tt.current, versions, tt.policy); got != tt.want {
+				t.Errorf("HighestAllowedVersion(%q, %q) = %q, want %q", tt.current, tt.policy, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdatePolicy_IsRestricted(t *testing.T) {
+	t.Parallel()
+
+	for policy, want := range map[UpdatePolicy]bool{
+		"":                false,
+		UpdatePolicyPatch: true,
+		UpdatePolicyMinor: true,
+		UpdatePolicyMajor: false,
+	} {
+		if got := policy.IsRestricted(); got != want {
+			t.Errorf("UpdatePolicy(%q).IsRestricted() = %v, want %v", policy, got, want)
+		}
+	}
+}
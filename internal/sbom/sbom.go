@@ -0,0 +1,405 @@
+// Package sbom writes software bills of materials of Go binaries in the
+// CycloneDX 1.5 and SPDX 2.3 JSON formats.
+// https://cyclonedx.org/docs/1.5/json/
+// https://spdx.github.io/spdx-spec/v2.3/
+package sbom
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Format is the format of a document.
+type Format string
+
+const (
+	// CycloneDX is the CycloneDX 1.5 JSON format.
+	CycloneDX Format = "cyclonedx"
+	// SPDX is the SPDX 2.3 JSON format.
+	SPDX Format = "spdx"
+)
+
+// Formats returns the supported formats.
+func Formats() []Format {
+	return []Format{CycloneDX, SPDX}
+}
+
+// Binary is a Go binary described by its build information.
+type Binary struct {
+	// Name is the file name of the binary.
+	Name       string
+	ImportPath string
+	// Main is the main module. Its Sum is the "h1:" checksum of the module.
+	Main      debug.Module
+	GoVersion string
+	Settings  []debug.BuildSetting
+	Deps      []*debug.Module
+}
+
+// Options holds the metadata of a document.
+type Options struct {
+	// ToolName and ToolVersion identify the program that writes the document.
+	ToolName    string
+	ToolVersion string
+	// Created is the creation time of the document.
+	Created time.Time
+}
+
+// Write writes the document of bins in format to w. The binaries are
+// ordered by name, and a module used by several binaries is described once.
+func Write(w io.Writer, format Format, bins []Binary, opts Options) error {
+	bins = append([]Binary{}, bins...)
+	sort.SliceStable(bins, func(i, j int) bool { return bins[i].Name < bins[j].Name })
+
+	var doc any
+	switch format {
+	case CycloneDX:
+		doc = newCycloneDX(bins, opts)
+	case SPDX:
+		doc = newSPDX(bins, opts)
+	default:
+		return fmt.Errorf("unsupported SBOM format %q", format)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// module is a module a binary is built with.
+type module struct {
+	path    string
+	version string
+	sum     string
+	// replaces is "<path>@<version>" of the module replaced by this one.
+	replaces string
+	// dir is the local directory that replaces the module.
+	dir string
+}
+
+func newModule(m *debug.Module) module {
+	switch {
+	case m.Replace == nil:
+		return module{path: m.Path, version: m.Version, sum: m.Sum}
+	case m.Replace.Version == "":
+		return module{path: m.Path, version: m.Version, sum: m.Sum, dir: m.Replace.Path}
+	default:
+		return module{path: m.Replace.Path, version: m.Replace.Version, sum: m.Replace.Sum, replaces: m.Path + "@" + m.Version}
+	}
+}
+
+// key identifies the module in a document.
+func (m module) key() string {
+	if m.dir != "" {
+		return m.purl() + "?replaced_by=" + url.QueryEscape(m.dir)
+	}
+	return m.purl()
+}
+
+// purl returns the package URL of the module, such as "pkg:golang/golang.org/x/net@v0.17.0".
+// https://github.com/package-url/purl-spec
+func (m module) purl() string {
+	return packageURL(m.path, m.version, "")
+}
+
+// packageURL returns the package URL of a Go module. A "(devel)" version is
+// omitted, and subpath is the import path below the module.
+func packageURL(modulePath, ver, subpath string) string {
+	segments := strings.Split(modulePath, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	purl := "pkg:golang/" + strings.Join(segments, "/")
+	if ver != "" && ver != "(devel)" {
+		purl += "@" + url.PathEscape(ver)
+	}
+	if subpath != "" {
+		purl += "#" + subpath
+	}
+	return purl
+}
+
+// binaryPURL returns the package URL of the main package of b, or "" for a
+// binary built outside a module.
+func binaryPURL(b Binary) string {
+	if b.Main.Path == "" {
+		return ""
+	}
+	subpath, _ := strings.CutPrefix(b.ImportPath, b.Main.Path+"/")
+	if subpath == b.ImportPath {
+		subpath = ""
+	}
+	return packageURL(b.Main.Path, b.Main.Version, subpath)
+}
+
+// documentID returns a UUID derived from the binaries and the creation time,
+// so the same input always produces the same document.
+func documentID(bins []Binary, created time.Time) string {
+	h := sha256.New()
+	_, _ = fmt.Fprintln(h, created.UTC().Format(time.RFC3339))
+	for _, b := range bins {
+		_, _ = fmt.Fprintln(h, b.Name, b.ImportPath, b.Main.Version, b.Main.Sum, b.GoVersion)
+		for _, d := range b.Deps {
+			_, _ = fmt.Fprintln(h, newModule(d).key(), d.Sum)
+		}
+	}
+	sum := h.Sum(nil)
+	sum[6] = (sum[6] & 0x0f) | 0x50 // version 5 (name-based with SHA)
+	sum[8] = (sum[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// ---------------------------------------------------------------------------
+// CycloneDX
+
+type cdxBOM struct {
+	BOMFormat    string          `json:"bomFormat"`
+	SpecVersion  string          `json:"specVersion"`
+	SerialNumber string          `json:"serialNumber"`
+	Version      int             `json:"version"`
+	Metadata     cdxMetadata     `json:"metadata"`
+	Components   []cdxComponent  `json:"components"`
+	Dependencies []cdxDependency `json:"dependencies"`
+}
+
+type cdxMetadata struct {
+	Timestamp string   `json:"timestamp"`
+	Tools     cdxTools `json:"tools"`
+}
+
+type cdxTools struct {
+	Components []cdxComponent `json:"components"`
+}
+
+type cdxComponent struct {
+	BOMRef     string        `json:"bom-ref,omitempty"`
+	Type       string        `json:"type"`
+	Name       string        `json:"name"`
+	Version    string        `json:"version,omitempty"`
+	PURL       string        `json:"purl,omitempty"`
+	Properties []cdxProperty `json:"properties,omitempty"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cdxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// newCycloneDX describes each binary as an "application" component that
+// depends on "library" components of its modules. Properties in the "gup:"
+// namespace hold what CycloneDX has no field for.
+func newCycloneDX(bins []Binary, opts Options) cdxBOM {
+	bom := cdxBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + documentID(bins, opts.Created),
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: opts.Created.UTC().Format(time.RFC3339),
+			Tools: cdxTools{Components: []cdxComponent{
+				{Type: "application", Name: opts.ToolName, Version: opts.ToolVersion},
+			}},
+		},
+		Components:   []cdxComponent{},
+		Dependencies: []cdxDependency{},
+	}
+
+	libraries := []cdxComponent{}
+	seen := map[string]bool{}
+	for _, b := range bins {
+		app := cdxComponent{
+			BOMRef:  "binary:" + b.Name,
+			Type:    "application",
+			Name:    b.Name,
+			Version: b.Main.Version,
+			PURL:    binaryPURL(b),
+		}
+		app.Properties = appendProperty(app.Properties, "gup:import_path", b.ImportPath)
+		app.Properties = appendProperty(app.Properties, "gup:module_path", b.Main.Path)
+		app.Properties = appendProperty(app.Properties, "gup:module_sum", b.Main.Sum)
+		app.Properties = appendProperty(app.Properties, "gup:go_version", b.GoVersion)
+		for _, s := range b.Settings {
+			app.Properties = appendProperty(app.Properties, "gup:build:"+s.Key, s.Value)
+		}
+		bom.Components = append(bom.Components, app)
+
+		dependsOn := []string{}
+		for _, d := range b.Deps {
+			m := newModule(d)
+			dependsOn = append(dependsOn, m.key())
+			if seen[m.key()] {
+				continue
+			}
+			seen[m.key()] = true
+			lib := cdxComponent{BOMRef: m.key(), Type: "library", Name: m.path, Version: m.version, PURL: m.purl()}
+			lib.Properties = appendProperty(lib.Properties, "gup:module_sum", m.sum)
+			lib.Properties = appendProperty(lib.Properties, "gup:replaces", m.replaces)
+			lib.Properties = appendProperty(lib.Properties, "gup:replaced_by", m.dir)
+			libraries = append(libraries, lib)
+		}
+		bom.Dependencies = append(bom.Dependencies, cdxDependency{Ref: app.BOMRef, DependsOn: dependsOn})
+	}
+	bom.Components = append(bom.Components, libraries...)
+	return bom
+}
+
+// appendProperty appends a property with a non-empty value.
+func appendProperty(props []cdxProperty, name, value string) []cdxProperty {
+	if value == "" {
+		return props
+	}
+	return append(props, cdxProperty{Name: name, Value: value})
+}
+
+// ---------------------------------------------------------------------------
+// SPDX
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name                  string            `json:"name"`
+	SPDXID                string            `json:"SPDXID"`
+	VersionInfo           string            `json:"versionInfo,omitempty"`
+	DownloadLocation      string            `json:"downloadLocation"`
+	FilesAnalyzed         bool              `json:"filesAnalyzed"`
+	LicenseConcluded      string            `json:"licenseConcluded"`
+	LicenseDeclared       string            `json:"licenseDeclared"`
+	CopyrightText         string            `json:"copyrightText"`
+	PrimaryPackagePurpose string            `json:"primaryPackagePurpose"`
+	ExternalRefs          []spdxExternalRef `json:"externalRefs,omitempty"`
+	// Comment holds the build information that SPDX has no field for.
+	Comment string `json:"comment,omitempty"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+const (
+	spdxDocumentID = "SPDXRef-DOCUMENT"
+	noAssertion    = "NOASSERTION"
+)
+
+// newSPDX describes each binary as an APPLICATION package that the document
+// DESCRIBES and that DEPENDS_ON a LIBRARY package of each of its modules.
+func newSPDX(bins []Binary, opts Options) spdxDocument {
+	id := documentID(bins, opts.Created)
+	doc := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            spdxDocumentID,
+		Name:              opts.ToolName + "-sbom",
+		DocumentNamespace: "https://spdx.org/spdxdocs/" + opts.ToolName + "-sbom-" + id,
+		CreationInfo: spdxCreationInfo{
+			Created:  opts.Created.UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: " + opts.ToolName + "-" + opts.ToolVersion},
+		},
+		Packages:      []spdxPackage{},
+		Relationships: []spdxRelationship{},
+	}
+
+	libraries := []spdxPackage{}
+	libraryIDs := map[string]string{}
+	for i, b := range bins {
+		comment := []string{"import path: " + b.ImportPath}
+		if b.Main.Sum != "" {
+			comment = append(comment, "module sum: "+b.Main.Sum)
+		}
+		comment = append(comment, "go version: "+b.GoVersion)
+		for _, s := range b.Settings {
+			comment = append(comment, "build: "+s.Key+"="+s.Value)
+		}
+		app := newSPDXPackage(fmt.Sprintf("SPDXRef-Application-%d-%s", i+1, spdxIDString(b.Name)),
+			b.Name, b.Main.Version, binaryPURL(b), "APPLICATION")
+		app.Comment = strings.Join(comment, "\n")
+		doc.Packages = append(doc.Packages, app)
+		doc.Relationships = append(doc.Relationships,
+			spdxRelationship{SPDXElementID: spdxDocumentID, RelationshipType: "DESCRIBES", RelatedSPDXElement: app.SPDXID})
+
+		for _, d := range b.Deps {
+			m := newModule(d)
+			libID, ok := libraryIDs[m.key()]
+			if !ok {
+				libID = fmt.Sprintf("SPDXRef-Module-%d-%s", len(libraryIDs)+1, spdxIDString(m.path))
+				libraryIDs[m.key()] = libID
+				lib := newSPDXPackage(libID, m.path, m.version, m.purl(), "LIBRARY")
+				comment := []string{}
+				if m.sum != "" {
+					comment = append(comment, "module sum: "+m.sum)
+				}
+				if m.replaces != "" {
+					comment = append(comment, "replaces: "+m.replaces)
+				}
+				if m.dir != "" {
+					comment = append(comment, "replaced by: "+m.dir)
+				}
+				lib.Comment = strings.Join(comment, "\n")
+				libraries = append(libraries, lib)
+			}
+			doc.Relationships = append(doc.Relationships,
+				spdxRelationship{SPDXElementID: app.SPDXID, RelationshipType: "DEPENDS_ON", RelatedSPDXElement: libID})
+		}
+	}
+	doc.Packages = append(doc.Packages, libraries...)
+	return doc
+}
+
+func newSPDXPackage(id, name, ver, purl, purpose string) spdxPackage {
+	p := spdxPackage{
+		Name:                  name,
+		SPDXID:                id,
+		VersionInfo:           ver,
+		DownloadLocation:      noAssertion,
+		LicenseConcluded:      noAssertion,
+		LicenseDeclared:       noAssertion,
+		CopyrightText:         noAssertion,
+		PrimaryPackagePurpose: purpose,
+	}
+	if purl != "" {
+		p.ExternalRefs = []spdxExternalRef{{ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: "purl", ReferenceLocator: purl}}
+	}
+	return p
+}
+
+// spdxIDString replaces the characters that an SPDX identifier can not hold with "-".
+func spdxIDString(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' {
+			return r
+		}
+		return '-'
+	}, s)
+}
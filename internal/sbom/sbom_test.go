@@ -0,0 +1,166 @@
+package sbom
+
+import (
+	"bytes"
+	"encoding/json"
+	"runtime/debug"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func testBinaries() []Binary {
+	net := &debug.Module{Path: "golang.org/x/net", Version: "v0.17.0", Sum: "h1:net="}
+	return []Binary{
+		{
+			Name:       "stringer",
+			ImportPath: "golang.org/x/tools/cmd/stringer",
+			Main:       debug.Module{Path: "golang.org/x/tools", Version: "v0.20.0", Sum: "h1:tools="},
+			GoVersion:  "go1.22.4",
+			Settings:   []debug.BuildSetting{{Key: "-trimpath", Value: "true"}, {Key: "CGO_ENABLED", Value: "0"}},
+			Deps: []*debug.Module{
+				net,
+				{Path: "golang.org/x/mod", Version: "v0.1.0", Replace: &debug.Module{Path: "example.com/mod", Version: "v0.2.0", Sum: "h1:mod="}},
+			},
+		},
+		{
+			Name:       "gal",
+			ImportPath: "github.com/nao1215/gal/cmd/gal",
+			Main:       debug.Module{Path: "github.com/nao1215/gal", Version: "(devel)"},
+			GoVersion:  "go1.22.4",
+			Deps:       []*debug.Module{net, {Path: "golang.org/x/sys", Version: "v0.1.0", Replace: &debug.Module{Path: "../sys"}}},
+		},
+	}
+}
+
+var testOptions = Options{ //nolint:gochecknoglobals // read-only test data
+	ToolName:    "gup",
+	ToolVersion: "v1.0.0",
+	Created:     time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+}
+
+func TestWrite_cycloneDX(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	if err := Write(&buf, CycloneDX, testBinaries(), testOptions); err != nil {
+		t.Fatal(err)
+	}
+	var bom cdxBOM
+	if err := json.Unmarshal(buf.Bytes(), &bom); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, buf.String())
+	}
+
+	if bom.BOMFormat != "CycloneDX" || bom.SpecVersion != "1.5" || !strings.HasPrefix(bom.SerialNumber, "urn:uuid:") ||
+		bom.Metadata.Timestamp != "2026-01-02T03:04:05Z" || bom.Metadata.Tools.Components[0].Name != "gup" {
+		t.Errorf("header = %+v", bom)
+	}
+
+	gotRefs := []string{}
+	for _, c := range bom.Components {
+		gotRefs = append(gotRefs, c.Type+" "+c.BOMRef+" "+c.PURL)
+	}
+	wantRefs := []string{
+		"application binary:gal pkg:golang/github.com/nao1215/gal#cmd/gal",
+		"application binary:stringer pkg:golang/golang.org/x/tools@v0.20.0#cmd/stringer",
+		"library pkg:golang/golang.org/x/net@v0.17.0 pkg:golang/golang.org/x/net@v0.17.0",
+		"library pkg:golang/golang.org/x/sys@v0.1.0?replaced_by=..%2Fsys pkg:golang/golang.org/x/sys@v0.1.0",
+		"library pkg:golang/example.com/mod@v0.2.0 pkg:golang/example.com/mod@v0.2.0",
+	}
+	if diff := cmp.Diff(wantRefs, gotRefs); diff != "" {
+		t.Errorf("components mismatch (-want +got):\n%s", diff)
+	}
+
+	wantProps := []cdxProperty{
+		{Name: "gup:import_path", Value: "golang.org/x/tools/cmd/stringer"},
+		{Name: "gup:module_path", Value: "golang.org/x/tools"},
+		{Name: "gup:module_sum", Value: "h1:tools="},
+		{Name: "gup:go_version", Value: "go1.22.4"},
+		{Name: "gup:build:-trimpath", Value: "true"},
+		{Name: "gup:build:CGO_ENABLED", Value: "0"},
+	}
+	if diff := cmp.Diff(wantProps, bom.Components[1].Properties); diff != "" {
+		t.Errorf("properties mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]cdxProperty{{Name: "gup:module_sum", Value: "h1:mod="}, {Name: "gup:replaces", Value: "golang.org/x/mod@v0.1.0"}},
+		bom.Components[4].Properties); diff != "" {
+		t.Errorf("replaced module properties mismatch (-want +got):\n%s", diff)
+	}
+
+	wantDeps := []cdxDependency{
+		{Ref: "binary:gal", DependsOn: []string{"pkg:golang/golang.org/x/net@v0.17.0", "pkg:golang/golang.org/x/sys@v0.1.0?replaced_by=..%2Fsys"}},
+		{Ref: "binary:stringer", DependsOn: []string{"pkg:golang/golang.org/x/net@v0.17.0", "pkg:golang/example.com/mod@v0.2.0"}},
+	}
+	if diff := cmp.Diff(wantDeps, bom.Dependencies); diff != "" {
+		t.Errorf("dependencies mismatch (-want +got):\n%s", diff)
+	}
+
+	var again bytes.Buffer
+	if err := Write(&again, CycloneDX, testBinaries(), testOptions); err != nil {
+		t.Fatal(err)
+	}
+	if again.String() != buf.String() {
+		t.Error("Write() is not deterministic")
+	}
+}
+
+func TestWrite_spdx(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	if err := Write(&buf, SPDX, testBinaries(), testOptions); err != nil {
+		t.Fatal(err)
+	}
+	var doc spdxDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, buf.String())
+	}
+
+	if doc.SPDXVersion != "SPDX-2.3" || doc.SPDXID != "SPDXRef-DOCUMENT" ||
+		!strings.HasPrefix(doc.DocumentNamespace, "https://spdx.org/spdxdocs/gup-sbom-") ||
+		doc.CreationInfo.Created != "2026-01-02T03:04:05Z" || doc.CreationInfo.Creators[0] != "Tool: gup-v1.0.0" {
+		t.Errorf("header = %+v", doc)
+	}
+
+	gotPkgs := []string{}
+	for _, p := range doc.Packages {
+		gotPkgs = append(gotPkgs, p.SPDXID+" "+p.PrimaryPackagePurpose+" "+p.VersionInfo)
+	}
+	wantPkgs := []string{
+		"SPDXRef-Application-1-gal APPLICATION (devel)",
+		"SPDXRef-Application-2-stringer APPLICATION v0.20.0",
+		"SPDXRef-Module-1-golang.org-x-net LIBRARY v0.17.0",
+		"SPDXRef-Module-2-golang.org-x-sys LIBRARY v0.1.0",
+		"SPDXRef-Module-3-example.com-mod LIBRARY v0.2.0",
+	}
+	if diff := cmp.Diff(wantPkgs, gotPkgs); diff != "" {
+		t.Errorf("packages mismatch (-want +got):\n%s", diff)
+	}
+	wantComment := "import path: golang.org/x/tools/cmd/stringer\nmodule sum: h1:tools=\ngo version: go1.22.4\n" +
+		"build: -trimpath=true\nbuild: CGO_ENABLED=0"
+	if got := doc.Packages[1].Comment; got != wantComment {
+		t.Errorf("comment = %q, want %q", got, wantComment)
+	}
+
+	wantRels := []spdxRelationship{
+		{SPDXElementID: "SPDXRef-DOCUMENT", RelationshipType: "DESCRIBES", RelatedSPDXElement: "SPDXRef-Application-1-gal"},
+		{SPDXElementID: "SPDXRef-Application-1-gal", RelationshipType: "DEPENDS_ON", RelatedSPDXElement: "SPDXRef-Module-1-golang.org-x-net"},
+		{SPDXElementID: "SPDXRef-Application-1-gal", RelationshipType: "DEPENDS_ON", RelatedSPDXElement: "SPDXRef-Module-2-golang.org-x-sys"},
+		{SPDXElementID: "SPDXRef-DOCUMENT", RelationshipType: "DESCRIBES", RelatedSPDXElement: "SPDXRef-Application-2-stringer"},
+		{SPDXElementID: "SPDXRef-Application-2-stringer", RelationshipType: "DEPENDS_ON", RelatedSPDXElement: "SPDXRef-Module-1-golang.org-x-net"},
+		{SPDXElementID: "SPDXRef-Application-2-stringer", RelationshipType: "DEPENDS_ON", RelatedSPDXElement: "SPDXRef-Module-3-example.com-mod"},
+	}
+	if diff := cmp.Diff(wantRels, doc.Relationships); diff != "" {
+		t.Errorf("relationships mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWrite_unsupportedFormat(t *testing.T) {
+	t.Parallel()
+
+	if err := Write(&bytes.Buffer{}, Format("swid"), testBinaries(), testOptions); err == nil {
+		t.Error("Write() accepts an unsupported format")
+	}
+}
@@ -0,0 +1,351 @@
+// Package vulndb implements a client for the Go vulnerability database,
+// which serves vulnerabilities in the OSV format.
+// https://go.dev/security/vuln/database
+package vulndb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-version"
+)
+
+// DefaultURL is the Go vulnerability database used when no database is configured.
+const DefaultURL = "https://vuln.go.dev"
+
+// Stdlib is the module path of the Go standard library in the database.
+const Stdlib = "stdlib"
+
+// maxResponseSize limits the size of database responses read into memory.
+// The module index is the largest file.
+const maxResponseSize = 64 << 20
+
+// ErrNotFound is returned when the database does not have the requested file.
+var ErrNotFound = errors.New("not found")
+
+// Config is the configuration of Client.
+type Config struct {
+	// URL is an http(s) or file URL, or a local directory, that holds the
+	// database. When empty, DefaultURL is used.
+	URL string
+	// HTTPClient is used for http(s) databases. When nil, a client with a timeout is used.
+	HTTPClient *http.Client
+}
+
+// Client reads the Go vulnerability database.
+type Client struct {
+	// base is the database URL, or dir is the local directory.
+	base       string
+	dir        string
+	httpClient *http.Client
+}
+
+// New returns a Client configured by cfg.
+func New(cfg Config) (*Client, error) {
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 60 * time.Second}
+	}
+	raw := strings.TrimSpace(cfg.URL)
+	if raw == "" {
+		raw = DefaultURL
+	}
+
+	c := &Client{httpClient: httpClient}
+	u, err := url.Parse(raw)
+	switch {
+	case err != nil || u.Scheme == "" || (runtime.GOOS == "windows" && len(u.Scheme) == 1):
+		// A path such as "./vulndb" or "C:\vulndb".
+		c.dir = filepath.Clean(raw)
+	case u.Scheme == "file":
+		filePath := u.Path
+		if runtime.GOOS == "windows" {
+			// file:///C:/vulndb has the path "/C:/vulndb".
+			filePath = strings.TrimPrefix(filePath, "/")
+		}
+		c.dir = filepath.FromSlash(filePath)
+	case u.Scheme == "http" || u.Scheme == "https":
+		c.base = strings.TrimSuffix(raw, "/")
+	default:
+		return nil, fmt.Errorf("invalid vulnerability database URL %s: unsupported scheme %q", raw, u.Scheme)
+	}
+	return c, nil
+}
+
+// ModuleVulns lists the vulnerabilities of a module in the index.
+type ModuleVulns struct {
+	// Path is the module path, or Stdlib.
+	Path  string      `json:"path"`
+	Vulns []IndexVuln `json:"vulns"`
+}
+
+// IndexVuln is a vulnerability in the module index.
+type IndexVuln struct {
+	ID       string    `json:"id"`
+	Modified time.Time `json:"modified"`
+	// Fixed is the highest version that fixes the vulnerability. Empty means
+	// that no version fixes it.
+	Fixed string `json:"fixed,omitempty"`
+}
+
+// Candidates returns the vulnerabilities that may affect the module at ver:
+// those without a fixed version, or fixed in a later version. The OSV entry
+// of a candidate decides whether ver is affected.
+func (m ModuleVulns) Candidates(ver string) []IndexVuln {
+	if m.Path == Stdlib {
+		ver = GoVersionToSemver(ver)
+	}
+	v, err := version.NewVersion(ver)
+	if err != nil {
+		return m.Vulns
+	}
+	candidates := []IndexVuln{}
+	for _, vuln := range m.Vulns {
+		if vuln.Fixed != "" {
+			if fixed, err := version.NewVersion(vuln.Fixed); err == nil && !v.LessThan(fixed) {
+				continue
+			}
+		}
+		candidates = append(candidates, vuln)
+	}
+	return candidates
+}
+
+// Modules returns the module index ("index/modules.json").
+func (c *Client) Modules(ctx context.Context) ([]ModuleVulns, error) {
+	raw, err := c.get(ctx, "index/modules.json")
+	if err != nil {
+		return nil, err
+	}
+	modules := []ModuleVulns{}
+	if err := json.Unmarshal(raw, &modules); err != nil {
+		return nil, fmt.Errorf("invalid vulnerability database index: %w", err)
+	}
+	return modules, nil
+}
+
+// Entry returns the vulnerability with id ("ID/<id>.json").
+func (c *Client) Entry(ctx context.Context, id string) (*Entry, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || id == "." || id == ".." {
+		return nil, fmt.Errorf("invalid vulnerability ID %q", id)
+	}
+	raw, err := c.get(ctx, "ID/"+id+".json")
+	if err != nil {
+		return nil, err
+	}
+	entry := &Entry{}
+	if err := json.Unmarshal(raw, entry); err != nil {
+		return nil, fmt.Errorf("invalid vulnerability %s: %w", id, err)
+	}
+	return entry, nil
+}
+
+// get reads name from the database.
+func (c *Client) get(ctx context.Context, name string) ([]byte, error) {
+	if c.dir != "" {
+		raw, err := os.ReadFile(filepath.Join(c.dir, filepath.FromSlash(name)))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("%s: %w", name, ErrNotFound)
+			}
+			return nil, err
+		}
+		return raw, nil
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	target := c.base + "/" + name
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() //nolint:errcheck // read-only body
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("can't read response of %s: %w", req.URL.Redacted(), err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return body, nil
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%s: %w", req.URL.Redacted(), ErrNotFound)
+	default:
+		return nil, fmt.Errorf("%s: unexpected status %s", req.URL.Redacted(), resp.Status)
+	}
+}
+
+// Entry is a vulnerability in the OSV format.
+type Entry struct {
+	ID       string     `json:"id"`
+	Aliases  []string   `json:"aliases,omitempty"`
+	Summary  string     `json:"summary,omitempty"`
+	Details  string     `json:"details,omitempty"`
+	Affected []Affected `json:"affected"`
+}
+
+// Affected is a module affected by a vulnerability.
+type Affected struct {
+	Module struct {
+		// Path is the module path, or Stdlib.
+		Path string `json:"name"`
+	} `json:"package"`
+	Ranges []Range `json:"ranges,omitempty"`
+}
+
+// Range is a list of introduced and fixed versions. The versions are
+// semantic versions without the "v" prefix.
+type Range struct {
+	Type   string  `json:"type"`
+	Events []Event `json:"events"`
+}
+
+// Event introduces or fixes a vulnerability at a version.
+type Event struct {
+	Introduced string `json:"introduced,omitempty"`
+	Fixed      string `json:"fixed,omitempty"`
+}
+
+// Affects reports whether the vulnerability affects modulePath at ver.
+// ver is a module version such as "v1.2.3", or a Go version such as
+// "go1.22.4" for Stdlib.
+func (e *Entry) Affects(modulePath, ver string) bool {
+	_, affected := e.fix(modulePath, ver)
+	return affected
+}
+
+// FixedVersion returns the lowest version that fixes the vulnerability
+// affecting modulePath at ver, in the format of ver. It returns "" if ver
+// is not affected or no version fixes it.
+func (e *Entry) FixedVersion(modulePath, ver string) string {
+	fixed, affected := e.fix(modulePath, ver)
+	if !affected || fixed == "" {
+		return ""
+	}
+	if modulePath == Stdlib {
+		return SemverToGoVersion(fixed)
+	}
+	return "v" + fixed
+}
+
+func (e *Entry) fix(modulePath, ver string) (string, bool) {
+	if modulePath == Stdlib {
+		ver = GoVersionToSemver(ver)
+	}
+	v, err := version.NewVersion(ver)
+	if err != nil {
+		return "", false
+	}
+	for _, a := range e.Affected {
+		if a.Module.Path != modulePath {
+			continue
+		}
+		if len(a.Ranges) == 0 {
+			// Every version is affected.
+			return "", true
+		}
+		for _, r := range a.Ranges {
+			if r.Type != "SEMVER" {
+				continue
+			}
+			if fixed, affected := r.fix(v); affected {
+				return fixed, true
+			}
+		}
+	}
+	return "", false
+}
+
+// fix walks the events in version order and reports whether v is in an
+// affected interval, and the fixed version that closes the interval.
+func (r Range) fix(v *version.Version) (string, bool) {
+	type event struct {
+		ver        *version.Version
+		raw        string
+		introduced bool
+	}
+	events := make([]event, 0, len(r.Events))
+	for _, e := range r.Events {
+		raw, introduced := e.Fixed, false
+		if e.Introduced != "" {
+			raw, introduced = e.Introduced, true
+		}
+		if raw == "0" {
+			raw = "0.0.0"
+		}
+		ev, err := version.NewVersion(raw)
+		if err != nil {
+			continue
+		}
+		events = append(events, event{ver: ev, raw: raw, introduced: introduced})
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].ver.LessThan(events[j].ver) })
+
+	affected := false
+	for _, e := range events {
+		if e.ver.GreaterThan(v) {
+			if affected && !e.introduced {
+				return e.raw, true
+			}
+			break
+		}
+		affected = e.introduced
+	}
+	return "", affected
+}
+
+// GoVersionToSemver converts a Go version such as "go1.22.4", "go1.22" or
+// "go1.21rc2" to a semantic version such as "v1.22.4". It returns "" if
+// goVer is not a Go version.
+func GoVersionToSemver(goVer string) string {
+	ver, ok := strings.CutPrefix(strings.TrimSpace(goVer), "go")
+	if !ok || ver == "" {
+		return ""
+	}
+	pre := ""
+	for _, tag := range []string{"rc", "beta"} {
+		if before, after, found := strings.Cut(ver, tag); found {
+			ver, pre = before, "-"+tag+"."+after
+			break
+		}
+	}
+	switch strings.Count(ver, ".") {
+	case 0:
+		ver += ".0.0"
+	case 1:
+		ver += ".0"
+	}
+	if _, err := version.NewSemver(ver + pre); err != nil {
+		return ""
+	}
+	return "v" + ver + pre
+}
+
+// SemverToGoVersion converts a semantic version such as "1.22.4" or
+// "1.21.0-rc.2" to a Go version such as "go1.22.4" or "go1.21rc2".
+func SemverToGoVersion(ver string) string {
+	ver = strings.TrimPrefix(ver, "v")
+	ver, pre, hasPre := strings.Cut(ver, "-")
+	if hasPre {
+		ver = strings.TrimSuffix(ver, ".0")
+		return "go" + ver + strings.ReplaceAll(pre, ".", "")
+	}
+	return "go" + ver
+}
@@ -0,0 +1,191 @@
+package vulndb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// testFiles is a database with a module vulnerability and a standard library vulnerability.
+var testFiles = map[string]string{ //nolint:gochecknoglobals // read-only test data
+	"index/modules.json": `[
+		{"path":"golang.org/x/net","vulns":[{"id":"GO-2023-0001","modified":"2023-10-11T00:00:00Z","fixed":"0.17.0"}]},
+		{"path":"stdlib","vulns":[{"id":"GO-2024-0002","modified":"2024-06-04T00:00:00Z","fixed":"1.22.4"}]}
+	]`,
+	"ID/GO-2023-0001.json": `{
+		"id":"GO-2023-0001","aliases":["CVE-2023-44487"],"summary":"HTTP/2 rapid reset",
+		"affected":[{"package":{"name":"golang.org/x/net"},
+			"ranges":[{"type":"SEMVER","events":[{"introduced":"0"},{"fixed":"0.17.0"}]}]}]
+	}`,
+	"ID/GO-2024-0002.json": `{
+		"id":"GO-2024-0002","summary":"Symlink handling in os",
+		"affected":[{"package":{"name":"stdlib"},
+			"ranges":[{"type":"SEMVER","events":[{"introduced":"1.21.0-0"},{"fixed":"1.21.11"},{"introduced":"1.22.0-0"},{"fixed":"1.22.4"}]}]}]
+	}`,
+}
+
+func writeTestDB(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, body := range testFiles {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := testFiles[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	dir := writeTestDB(t)
+
+	for name, dbURL := range map[string]string{
+		"http": srv.URL + "/",
+		"dir":  dir,
+		"file": "file://" + filepath.ToSlash(dir),
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			client, err := New(Config{URL: dbURL})
+			if err != nil {
+				t.Fatal(err)
+			}
+			modules, err := client.Modules(context.Background())
+			if err != nil {
+				t.Fatalf("Modules() error = %v", err)
+			}
+			if len(modules) != 2 || modules[0].Path != "golang.org/x/net" || modules[1].Vulns[0].Fixed != "1.22.4" {
+				t.Errorf("Modules() = %+v", modules)
+			}
+
+			entry, err := client.Entry(context.Background(), "GO-2023-0001")
+			if err != nil {
+				t.Fatalf("Entry() error = %v", err)
+			}
+			if entry.Summary != "HTTP/2 rapid reset" || entry.Aliases[0] != "CVE-2023-44487" {
+				t.Errorf("Entry() = %+v", entry)
+			}
+
+			if _, err := client.Entry(context.Background(), "GO-9999-9999"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Entry() error = %v, want ErrNotFound", err)
+			}
+			if _, err := client.Entry(context.Background(), "../index/modules"); err == nil {
+				t.Error("Entry() accepts an ID with a path separator")
+			}
+		})
+	}
+}
+
+func TestNew_unsupportedScheme(t *testing.T) {
+	t.Parallel()
+
+	if _, err := New(Config{URL: "ftp://example.com/vulndb"}); err == nil {
+		t.Error("New() accepts an ftp URL")
+	}
+}
+
+func TestModuleVulns_Candidates(t *testing.T) {
+	t.Parallel()
+
+	m := ModuleVulns{Path: "golang.org/x/net", Vulns: []IndexVuln{
+		{ID: "GO-1", Fixed: "0.17.0"},
+		{ID: "GO-2"},
+		{ID: "GO-3", Fixed: "0.10.0"},
+	}}
+	ids := func(vulns []IndexVuln) []string {
+		got := []string{}
+		for _, v := range vulns {
+			got = append(got, v.ID)
+		}
+		return got
+	}
+	if diff := cmp.Diff([]string{"GO-1", "GO-2"}, ids(m.Candidates("v0.15.0"))); diff != "" {
+		t.Errorf("Candidates() mismatch (-want +got):\n%s", diff)
+	}
+	stdlib := ModuleVulns{Path: Stdlib, Vulns: []IndexVuln{{ID: "GO-4", Fixed: "1.22.4"}}}
+	if got := ids(stdlib.Candidates("go1.22.4")); len(got) != 0 {
+		t.Errorf("Candidates(go1.22.4) = %v, want none", got)
+	}
+	if got := ids(stdlib.Candidates("go1.22.3")); len(got) != 1 {
+		t.Errorf("Candidates(go1.22.3) = %v, want GO-4", got)
+	}
+}
+
+func TestEntry_Affects(t *testing.T) {
+	t.Parallel()
+
+	net := &Entry{Affected: []Affected{{Ranges: []Range{{Type: "SEMVER", Events: []Event{{Introduced: "0"}, {Fixed: "0.17.0"}}}}}}}
+	net.Affected[0].Module.Path = "golang.org/x/net"
+	stdlib := &Entry{Affected: []Affected{{Ranges: []Range{{Type: "SEMVER", Events: []Event{
+		{Introduced: "1.22.0-0"}, {Fixed: "1.22.4"}, {Introduced: "1.21.0-0"}, {Fixed: "1.21.11"},
+	}}}}}}
+	stdlib.Affected[0].Module.Path = Stdlib
+
+	tests := []struct {
+		entry      *Entry
+		modulePath string
+		ver        string
+		affected   bool
+		fixed      string
+	}{
+		{entry: net, modulePath: "golang.org/x/net", ver: "v0.15.0", affected: true, fixed: "v0.17.0"},
+		{entry: net, modulePath: "golang.org/x/net", ver: "v0.17.0", affected: false},
+		{entry: net, modulePath: "golang.org/x/text", ver: "v0.1.0", affected: false},
+		{entry: stdlib, modulePath: Stdlib, ver: "go1.21.5", affected: true, fixed: "go1.21.11"},
+		{entry: stdlib, modulePath: Stdlib, ver: "go1.22rc1", affected: true, fixed: "go1.22.4"},
+		{entry: stdlib, modulePath: Stdlib, ver: "go1.22.4", affected: false},
+		{entry: stdlib, modulePath: Stdlib, ver: "go1.20.1", affected: false},
+	}
+	for _, tt := range tests {
+		if got := tt.entry.Affects(tt.modulePath, tt.ver); got != tt.affected {
+			t.Errorf("Affects(%s, %s) = %v, want %v", tt.modulePath, tt.ver, got, tt.affected)
+		}
+		if got := tt.entry.FixedVersion(tt.modulePath, tt.ver); got != tt.fixed {
+			t.Errorf("FixedVersion(%s, %s) = %q, want %q", tt.modulePath, tt.ver, got, tt.fixed)
+		}
+	}
+}
+
+func TestGoVersionToSemver(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"go1.22.4":  "v1.22.4",
+		"go1.22":    "v1.22.0",
+		"go1.21rc2": "v1.21.0-rc.2",
+		"devel":     "",
+		"unknown":   "",
+	}
+	for in, want := range tests {
+		if got := GoVersionToSemver(in); got != want {
+			t.Errorf("GoVersionToSemver(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if got := SemverToGoVersion("1.21.0-rc.2"); got != "go1.21rc2" {
+		t.Errorf("SemverToGoVersion() = %q, want go1.21rc2", got)
+	}
+	if got := SemverToGoVersion("1.22.4"); got != "go1.22.4" {
+		t.Errorf("SemverToGoVersion() = %q, want go1.22.4", got)
+	}
+}